@@ -0,0 +1,80 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Reducer combines the values collected from the copies of the regional hubs
+// +kubebuilder:validation:Enum=CountByValue;Sum;Min;Max;List
+type Reducer string
+
+const (
+	// CountByValue counts the occurrences of every value
+	CountByValue Reducer = "CountByValue"
+	// Sum adds the numeric values
+	Sum Reducer = "Sum"
+	// Min keeps the smallest numeric value
+	Min Reducer = "Min"
+	// Max keeps the largest numeric value
+	Max Reducer = "Max"
+	// List keeps the distinct values
+	List Reducer = "List"
+)
+
+// AggregationRuleSpec defines the resource rolled up and the fields collected from its copies
+type AggregationRuleSpec struct {
+	// Source is the resource whose copies reported by the regional hubs are rolled up to the global hub object
+	Source SourceResource `json:"source"`
+	// Fields are reduced to status.aggregations.<name> of the global hub object
+	// +kubebuilder:validation:MinItems=1
+	Fields []AggregationField `json:"fields"`
+}
+
+// SourceResource identifies the rolled up resource
+type SourceResource struct {
+	Group    string `json:"group,omitempty"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+}
+
+// GroupVersionResource returns the GroupVersionResource of the source
+func (s SourceResource) GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: s.Group, Version: s.Version, Resource: s.Resource}
+}
+
+// AggregationField defines a field collected from the copies and how the values are reduced
+type AggregationField struct {
+	// Name of the result in status.aggregations
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z][a-zA-Z0-9]*$`
+	Name string `json:"name"`
+	// Path is the dot separated path of the field, e.g. status.status.compliant. The lists along the path are
+	// expanded, so every item contributes a value, and a * segment expands the values of a map
+	Path string `json:"path"`
+	// Reducer combines the values of every regional hub and of all the regional hubs
+	Reducer Reducer `json:"reducer"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// AggregationRule is the Schema for the aggregationrules API
+type AggregationRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AggregationRuleSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AggregationRuleList contains a list of AggregationRule
+type AggregationRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AggregationRule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AggregationRule{}, &AggregationRuleList{})
+}
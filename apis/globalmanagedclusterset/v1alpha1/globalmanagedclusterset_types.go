@@ -0,0 +1,53 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GlobalManagedClusterSetSpec defines the clusters of the set and where it is bound on the regional hubs
+type GlobalManagedClusterSetSpec struct {
+	// ClusterSelector selects the ManagedClusters reported by the regional hubs by their labels, an empty selector
+	// selects all the clusters
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// BindingNamespaces are the namespaces the ManagedClusterSet is bound to on every regional hub
+	BindingNamespaces []string `json:"bindingNamespaces,omitempty"`
+}
+
+// GlobalManagedClusterSetStatus defines the clusters selected on every regional hub
+type GlobalManagedClusterSetStatus struct {
+	NumberOfClusters int32         `json:"numberOfClusters,omitempty"`
+	Hubs             []HubClusters `json:"hubs,omitempty"`
+}
+
+// HubClusters defines the clusters of the set on a regional hub
+type HubClusters struct {
+	Name     string   `json:"name"`
+	Clusters []string `json:"clusters,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// GlobalManagedClusterSet is the Schema for the globalmanagedclustersets API, it groups the ManagedClusters of
+// several regional hubs and is synced to every regional hub as a ManagedClusterSet with its clusters on the hub
+type GlobalManagedClusterSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GlobalManagedClusterSetSpec   `json:"spec,omitempty"`
+	Status GlobalManagedClusterSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GlobalManagedClusterSetList contains a list of GlobalManagedClusterSet
+type GlobalManagedClusterSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GlobalManagedClusterSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GlobalManagedClusterSet{}, &GlobalManagedClusterSetList{})
+}
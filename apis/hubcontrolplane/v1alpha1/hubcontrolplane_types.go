@@ -13,8 +13,26 @@ type HubControlPlaneSpec struct {
 type HubControlPlaneStatus struct {
 	Addons          []string              `json:"addons,omitempty"`
 	ManagedClusters ManagedClustersStatus `json:"managedClusters,omitempty"`
+	Drift           *DriftStatus          `json:"drift,omitempty"`
+	// HeartbeatTime is the last time the syncer of the regional hub reported it is alive
+	HeartbeatTime *metav1.Time `json:"heartbeatTime,omitempty"`
+	// Conditions are set by the Global Hub, Available is false once the heartbeat of the regional hub expires
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Propagation lists the global objects the syncer did not apply to the regional hub at their last generation, the
+	// objects missing from the reports taken at PropagationTime are applied
+	Propagation []PropagationReport `json:"propagation,omitempty"`
+	// PropagationTime is the last time the syncer of the regional hub took its propagation reports
+	PropagationTime *metav1.Time `json:"propagationTime,omitempty"`
+	// PropagationOmitted counts the reports left out of Propagation to bound the size of the status, the objects
+	// missing from the reports are unknown while it is set
+	PropagationOmitted int32 `json:"propagationOmitted,omitempty"`
 }
 
+// HubControlPlaneAvailable is the condition of the regional hubs sending their heartbeat
+const HubControlPlaneAvailable = "Available"
+
 // ManagedClustersStatus defines managed clusters with available, unavailable and unknown status
 type ManagedClustersStatus struct {
 	Available   []string `json:"available,omitempty"`
@@ -22,6 +40,38 @@ type ManagedClustersStatus struct {
 	Unknown     []string `json:"unknown,omitempty"`
 }
 
+// DriftStatus counts the changes made on the regional hub to the objects synced from the Global Hub since the syncer
+// started, the changes are reverted by the syncer
+type DriftStatus struct {
+	Detected          int64            `json:"detected,omitempty"`
+	Resources         map[string]int64 `json:"resources,omitempty"`
+	LastDetectionTime *metav1.Time     `json:"lastDetectionTime,omitempty"`
+}
+
+const (
+	// PropagationPending is the state of the global objects not applied to the regional hub at their generation yet
+	PropagationPending = "Pending"
+	// PropagationApplied is the state of the global objects applied to the regional hub
+	PropagationApplied = "Applied"
+	// PropagationFailed is the state of the global objects the regional hub failed to apply
+	PropagationFailed = "Failed"
+)
+
+// PropagationReport is the state of a global object the regional hub did not apply at its last generation
+type PropagationReport struct {
+	Group     string `json:"group,omitempty"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Generation is the generation of the global object last applied
+	Generation int64 `json:"generation,omitempty"`
+	// State is Failed, or Pending when the syncer has not applied the last generation of the global object yet
+	// +kubebuilder:validation:Enum=Pending;Applied;Failed
+	State string `json:"state"`
+	// Message is the error of the failed apply
+	Message string `json:"message,omitempty"`
+}
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster
@@ -6,9 +6,36 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftStatus) DeepCopyInto(out *DriftStatus) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastDetectionTime != nil {
+		in, out := &in.LastDetectionTime, &out.LastDetectionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftStatus.
+func (in *DriftStatus) DeepCopy() *DriftStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HubControlPlane) DeepCopyInto(out *HubControlPlane) {
 	*out = *in
@@ -92,6 +119,31 @@ func (in *HubControlPlaneStatus) DeepCopyInto(out *HubControlPlaneStatus) {
 		copy(*out, *in)
 	}
 	in.ManagedClusters.DeepCopyInto(&out.ManagedClusters)
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = new(DriftStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HeartbeatTime != nil {
+		in, out := &in.HeartbeatTime, &out.HeartbeatTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Propagation != nil {
+		in, out := &in.Propagation, &out.Propagation
+		*out = make([]PropagationReport, len(*in))
+		copy(*out, *in)
+	}
+	if in.PropagationTime != nil {
+		in, out := &in.PropagationTime, &out.PropagationTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubControlPlaneStatus.
@@ -133,3 +185,18 @@ func (in *ManagedClustersStatus) DeepCopy() *ManagedClustersStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PropagationReport) DeepCopyInto(out *PropagationReport) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PropagationReport.
+func (in *PropagationReport) DeepCopy() *PropagationReport {
+	if in == nil {
+		return nil
+	}
+	out := new(PropagationReport)
+	in.DeepCopyInto(out)
+	return out
+}
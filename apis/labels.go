@@ -0,0 +1,11 @@
+// Package apis holds the labels shared by the global hub, its apiserver and the syncers.
+package apis
+
+const (
+	// GlobalHubPolicyNamespaceLabel is the namespace a global hub resource originally comes from, the copies reported
+	// by the syncers carry a value different from their own namespace
+	GlobalHubPolicyNamespaceLabel = "global-hub.open-cluster-management.io/original-namespace"
+
+	// RegionalHubLabel is set on the managedclusters reported to the Global Hub, the value is the syncer name
+	RegionalHubLabel = "global-hub.open-cluster-management.io/regional-hub"
+)
@@ -0,0 +1,92 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NotificationEvent is a change notified by the rules
+// +kubebuilder:validation:Enum=PolicyNonCompliant;PolicyCompliant;HubUnavailable;HubAvailable
+type NotificationEvent string
+
+const (
+	// PolicyNonCompliant is notified when a global policy becomes non compliant on a regional hub
+	PolicyNonCompliant NotificationEvent = "PolicyNonCompliant"
+	// PolicyCompliant is notified when a global policy becomes compliant again on a regional hub
+	PolicyCompliant NotificationEvent = "PolicyCompliant"
+	// HubUnavailable is notified when a regional hub becomes unavailable
+	HubUnavailable NotificationEvent = "HubUnavailable"
+	// HubAvailable is notified when a regional hub becomes available again
+	HubAvailable NotificationEvent = "HubAvailable"
+)
+
+// NotificationRuleSpec defines the changes notified and the webhook they are delivered to
+type NotificationRuleSpec struct {
+	// Events are the changes notified
+	// +kubebuilder:validation:MinItems=1
+	Events []NotificationEvent `json:"events"`
+	// PolicySelector selects the global policies of the policy events by their labels, an empty selector selects all
+	// the global policies
+	PolicySelector metav1.LabelSelector `json:"policySelector,omitempty"`
+	// Hubs restricts the notifications to the regional hubs, the changes of all the regional hubs are notified when
+	// empty
+	Hubs []string `json:"hubs,omitempty"`
+	// Webhook receives the notifications
+	Webhook Webhook `json:"webhook"`
+	// DedupeWindow drops the notifications of an event already notified for the same policy and regional hub within
+	// the window, 5m by default
+	DedupeWindow *metav1.Duration `json:"dedupeWindow,omitempty"`
+	// MaxPerMinute is the maximum number of notifications delivered per minute, the others are dropped, 10 by default
+	// +kubebuilder:validation:Minimum=1
+	MaxPerMinute *int32 `json:"maxPerMinute,omitempty"`
+}
+
+// Webhook is a generic HTTP webhook, e.g. a Slack or a Teams incoming webhook
+type Webhook struct {
+	// URL receives the notifications in POST requests, it must be https and resolve to a public address
+	// +kubebuilder:validation:Pattern=`^https://`
+	URL string `json:"url"`
+	// Template is the Go template of the payload, executed with the notification: .Rule, .Event, .Namespace,
+	// .Policy, .Hub, .Message and .Time, the json function quotes a value. The payload is {"text": .Message} by
+	// default, which Slack and Teams accept
+	Template string `json:"template,omitempty"`
+	// ContentType of the payload, application/json by default
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// NotificationRuleStatus counts the notifications of the rule
+type NotificationRuleStatus struct {
+	Delivered int64 `json:"delivered,omitempty"`
+	Failed    int64 `json:"failed,omitempty"`
+	// Suppressed counts the notifications dropped by the dedupe window or the rate limit
+	Suppressed       int64        `json:"suppressed,omitempty"`
+	LastDeliveryTime *metav1.Time `json:"lastDeliveryTime,omitempty"`
+	// LastError is the error of the last notification which failed to be delivered
+	LastError string `json:"lastError,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// NotificationRule is the Schema for the notificationrules API, it delivers the compliance changes of the global
+// policies and the availability changes of the regional hubs to a webhook
+type NotificationRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NotificationRuleSpec   `json:"spec,omitempty"`
+	Status NotificationRuleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NotificationRuleList contains a list of NotificationRule
+type NotificationRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NotificationRule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NotificationRule{}, &NotificationRuleList{})
+}
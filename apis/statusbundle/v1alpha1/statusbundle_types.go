@@ -0,0 +1,51 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StatusBundleSpec defines the objects of a resource reported by a regional hub
+type StatusBundleSpec struct {
+	// Hub is the regional hub reporting the objects
+	Hub string `json:"hub"`
+	// Source is the resource of the reported objects
+	Source BundledResource `json:"source"`
+	// NumberOfObjects is the number of the reported objects
+	NumberOfObjects int32 `json:"numberOfObjects,omitempty"`
+	// Objects are all the reported objects, gzip compressed JSON
+	Objects []byte `json:"objects,omitempty"`
+}
+
+// BundledResource identifies the resource of the reported objects
+type BundledResource struct {
+	Group    string `json:"group,omitempty"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Hub",type=string,JSONPath=`.spec.hub`
+//+kubebuilder:printcolumn:name="Resource",type=string,JSONPath=`.spec.source.resource`
+//+kubebuilder:printcolumn:name="Objects",type=integer,JSONPath=`.spec.numberOfObjects`
+
+// StatusBundle is the Schema for the statusbundles API, it carries the objects of a resource reported by a regional
+// hub in a single object of the hub namespace, in place of one write per object
+type StatusBundle struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec StatusBundleSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// StatusBundleList contains a list of StatusBundle
+type StatusBundleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []StatusBundle `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&StatusBundle{}, &StatusBundleList{})
+}
@@ -0,0 +1,4 @@
+// openapi-gen only reads the package tags from doc.go.
+// +k8s:openapi-gen=true
+
+package v1alpha1
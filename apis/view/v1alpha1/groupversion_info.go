@@ -0,0 +1,21 @@
+// Package v1alpha1 contains API Schema definitions for the view v1alpha1 API group, the views are computed by the
+// global hub from its caches and are not stored
+//+kubebuilder:object:generate=true
+//+groupName=view.globalhub.open-cluster-management.io
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is group version used to register these objects
+	GroupVersion = schema.GroupVersion{Group: "view.globalhub.open-cluster-management.io", Version: "v1alpha1"}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types in this group-version to the given scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,48 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ComplianceChange is a change of the compliance state of a global policy on a regional hub or a managed cluster
+type ComplianceChange struct {
+	// Time is when the change was seen by the global hub
+	Time metav1.Time `json:"time"`
+	Hub  string      `json:"hub"`
+	// Cluster is empty for the compliance of the regional hub
+	Cluster string `json:"cluster,omitempty"`
+	// ComplianceState is Compliant, NonCompliant or Pending
+	ComplianceState string `json:"complianceState"`
+}
+
+//+kubebuilder:object:root=true
+
+// PolicyHistory is the compliance history of a global policy, oldest change first
+type PolicyHistory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Changes []ComplianceChange `json:"changes"`
+}
+
+//+kubebuilder:object:root=true
+
+// PolicyHistoryOptions selects the changes of the compliance history of a global policy
+type PolicyHistoryOptions struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Hub selects the changes on the regional hub and its managed clusters
+	Hub string `json:"hub,omitempty"`
+	// Cluster selects the changes on the managed cluster
+	Cluster string `json:"cluster,omitempty"`
+	// Since selects the changes from the time, RFC3339 formatted
+	Since *metav1.Time `json:"since,omitempty"`
+	// Until selects the changes before the time, RFC3339 formatted
+	Until *metav1.Time `json:"until,omitempty"`
+	// Limit is the maximum number of changes, the latest are returned
+	Limit int64 `json:"limit,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PolicyHistory{}, &PolicyHistoryOptions{})
+}
@@ -0,0 +1,66 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PolicyReport reports the compliance of every global policy
+	PolicyReport = "policies"
+	// HubReport reports the compliance of every global policy on every regional hub
+	HubReport = "hubs"
+	// ClusterReport reports the compliance of every global policy on every managed cluster
+	ClusterReport = "clusters"
+)
+
+const (
+	JSONReportFormat  = "json"
+	CSVReportFormat   = "csv"
+	JUnitReportFormat = "junit"
+)
+
+// ComplianceReportEntry is the compliance of a global policy, on a regional hub or a managed cluster depending on the
+// report
+type ComplianceReportEntry struct {
+	Namespace string `json:"namespace"`
+	Policy    string `json:"policy"`
+	Hub       string `json:"hub,omitempty"`
+	Cluster   string `json:"cluster,omitempty"`
+	// Standards, Categories and Controls are read from the annotations of the global policy
+	Standards  []string `json:"standards,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Controls   []string `json:"controls,omitempty"`
+	// ComplianceState is Compliant, NonCompliant or Pending
+	ComplianceState string `json:"complianceState"`
+	// Compliant, NonCompliant and Pending count the managed clusters of the policy and hub reports
+	Compliant    int32 `json:"compliant,omitempty"`
+	NonCompliant int32 `json:"nonCompliant,omitempty"`
+	Pending      int32 `json:"pending,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ComplianceReport is the fleet wide compliance of the global policies, named after the report: policies, hubs or
+// clusters
+type ComplianceReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// GeneratedAt is when the report was computed
+	GeneratedAt metav1.Time             `json:"generatedAt"`
+	Entries     []ComplianceReportEntry `json:"entries"`
+}
+
+//+kubebuilder:object:root=true
+
+// ComplianceReportOptions selects the format of a compliance report
+type ComplianceReportOptions struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Format is json, csv or junit, json by default
+	Format string `json:"format,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ComplianceReport{}, &ComplianceReportOptions{})
+}
@@ -0,0 +1,90 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FieldOperator is the operator of a field filter
+// +kubebuilder:validation:Enum=In;NotIn;Exists;DoesNotExist
+type FieldOperator string
+
+const (
+	// FieldOpIn matches when a value of the field is one of the values
+	FieldOpIn FieldOperator = "In"
+	// FieldOpNotIn matches when no value of the field is one of the values
+	FieldOpNotIn FieldOperator = "NotIn"
+	// FieldOpExists matches when the field has a value
+	FieldOpExists FieldOperator = "Exists"
+	// FieldOpDoesNotExist matches when the field has no value
+	FieldOpDoesNotExist FieldOperator = "DoesNotExist"
+)
+
+// FieldFilter matches the objects by the values of a field
+type FieldFilter struct {
+	// Path is the dot separated path of the field, e.g. spec.hubAcceptsClient. The lists along the path are expanded
+	// and a * segment expands the values of a map. The cluster segment holds the managed clusters of the object: the
+	// name and the available status of the managed clusters and the hubcontrolplanes, the name and the compliant
+	// state of the policies, e.g. cluster.compliant
+	Path string `json:"path"`
+	// Operator is In, NotIn, Exists or DoesNotExist
+	Operator FieldOperator `json:"operator"`
+	// Values are the values of In and NotIn
+	Values []string `json:"values,omitempty"`
+}
+
+// SearchQuery selects the objects of a kind synced from the regional hubs
+type SearchQuery struct {
+	// Kind is ManagedCluster, Policy or HubControlPlane
+	Kind string `json:"kind"`
+	// Namespace selects the policies copied from the global policies of the namespace
+	Namespace string `json:"namespace,omitempty"`
+	// Name selects the objects with the name
+	Name string `json:"name,omitempty"`
+	// LabelSelector selects the objects by labels, e.g. env=prod
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// Fields select the objects matching every filter
+	Fields []FieldFilter `json:"fields,omitempty"`
+}
+
+// SearchSpec defines the objects searched
+type SearchSpec struct {
+	// Query selects the objects of the results
+	Query SearchQuery `json:"query"`
+	// Hubs restricts the search to the regional hubs, all the regional hubs are searched when empty
+	Hubs []string `json:"hubs,omitempty"`
+	// Joins keep the objects with a managed cluster matched by every join on the same regional hub, the cluster
+	// segment of the field filters then holds that managed cluster only
+	Joins []SearchQuery `json:"joins,omitempty"`
+}
+
+// SearchResult is an object matching the search
+type SearchResult struct {
+	Kind      string            `json:"kind"`
+	Hub       string            `json:"hub"`
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	// Clusters are the managed clusters of the object matched by the joins
+	Clusters []string `json:"clusters,omitempty"`
+}
+
+// SearchStatus defines the results of the search
+type SearchStatus struct {
+	Results []SearchResult `json:"results"`
+}
+
+//+kubebuilder:object:root=true
+
+// Search searches the objects synced from the regional hubs, it is created to run the search and returned with the
+// results in its status, it is not stored
+type Search struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SearchSpec   `json:"spec"`
+	Status SearchStatus `json:"status,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Search{})
+}
@@ -0,0 +1,129 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HubStatus defines the observed state of a regional hub
+type HubStatus struct {
+	// Endpoint is the endpoint of the regional hub
+	Endpoint string `json:"endpoint,omitempty"`
+	// Addons are the addons installed on the regional hub
+	Addons []string `json:"addons,omitempty"`
+	// ManagedClusters counts the managed clusters of the regional hub
+	ManagedClusters ManagedClusterCounts `json:"managedClusters"`
+	// Policies counts the policies from the global hub on the regional hub
+	Policies PolicyCounts `json:"policies"`
+}
+
+// ManagedClusterCounts counts the managed clusters by availability
+type ManagedClusterCounts struct {
+	Total       int32 `json:"total"`
+	Available   int32 `json:"available"`
+	Unavailable int32 `json:"unavailable"`
+	Unknown     int32 `json:"unknown"`
+}
+
+// PolicyCounts counts the policies by compliance state
+type PolicyCounts struct {
+	Total        int32 `json:"total"`
+	Compliant    int32 `json:"compliant"`
+	NonCompliant int32 `json:"nonCompliant"`
+	Pending      int32 `json:"pending"`
+}
+
+//+kubebuilder:object:root=true
+
+// Hub is the view of a regional hub reporting to the global hub
+type Hub struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status HubStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HubList contains a list of Hub
+type HubList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Hub `json:"items"`
+}
+
+// ManagedCluster is the view of a managed cluster of a regional hub
+type ManagedCluster struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Available is the status of the available condition of the managed cluster, True, False or Unknown
+	Available metav1.ConditionStatus `json:"available"`
+}
+
+//+kubebuilder:object:root=true
+
+// HubManagedClusters are the managed clusters of a regional hub, it is the managedclusters subresource of the hubs
+type HubManagedClusters struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Items []ManagedCluster `json:"items"`
+}
+
+// FleetPolicyStatus defines the compliance of a global policy across the regional hubs
+type FleetPolicyStatus struct {
+	// ComplianceState is NonCompliant when a cluster of any regional hub is not compliant, Compliant when the clusters
+	// of all the regional hubs are compliant, and Pending otherwise
+	ComplianceState string `json:"complianceState"`
+	// Hubs are the compliance of the policy per regional hub
+	Hubs []HubCompliance `json:"hubs,omitempty"`
+}
+
+// HubCompliance counts the clusters of a regional hub by compliance state
+type HubCompliance struct {
+	Hub             string `json:"hub"`
+	ComplianceState string `json:"complianceState"`
+	Compliant       int32  `json:"compliant"`
+	NonCompliant    int32  `json:"nonCompliant"`
+	Pending         int32  `json:"pending"`
+}
+
+//+kubebuilder:object:root=true
+
+// FleetPolicy is the view of a global policy across the regional hubs
+type FleetPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status FleetPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// FleetPolicyList contains a list of FleetPolicy
+type FleetPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FleetPolicy `json:"items"`
+}
+
+// ClusterCompliance is the compliance state of a global policy on a managed cluster
+type ClusterCompliance struct {
+	Hub             string `json:"hub"`
+	Cluster         string `json:"cluster"`
+	ComplianceState string `json:"complianceState"`
+}
+
+//+kubebuilder:object:root=true
+
+// PolicyCompliance is the compliance of a global policy on every managed cluster of the regional hubs, it is the
+// compliance subresource of the policies
+type PolicyCompliance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Clusters []ClusterCompliance `json:"clusters"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Hub{}, &HubList{}, &HubManagedClusters{}, &FleetPolicy{}, &FleetPolicyList{}, &PolicyCompliance{})
+}
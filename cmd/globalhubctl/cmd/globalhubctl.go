@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type globalOptions struct {
+	Kubeconfig string
+}
+
+func NewGlobalHubCtlCommand() *cobra.Command {
+	options := &globalOptions{}
+	globalhubctlCommand := &cobra.Command{
+		Use:   "globalhubctl",
+		Short: "Operates the global hub apiserver",
+	}
+
+	globalhubctlCommand.PersistentFlags().StringVar(&options.Kubeconfig, "kubeconfig", options.Kubeconfig,
+		"Kubeconfig file of the global hub apiserver.")
+	globalhubctlCommand.AddCommand(newMigrateCommand(options))
+	globalhubctlCommand.AddCommand(newReportCommand(options))
+
+	return globalhubctlCommand
+}
+
+func (options *globalOptions) restConfig() (*rest.Config, error) {
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: options.Kubeconfig}, nil).ClientConfig()
+}
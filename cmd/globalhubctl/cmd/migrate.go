@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/util/retry"
+)
+
+const migrateChunkSize = 500
+
+func newMigrateCommand(options *globalOptions) *cobra.Command {
+	migrateCommand := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrates the stored resources",
+	}
+	migrateCommand.AddCommand(newMigrateEncryptionCommand(options))
+	return migrateCommand
+}
+
+func newMigrateEncryptionCommand(options *globalOptions) *cobra.Command {
+	resources := []string{"secrets"}
+	migrateEncryptionCommand := &cobra.Command{
+		Use:   "encryption",
+		Short: "Rewrites the stored resources with the first provider of the encryption provider config",
+		Long: "Rewrites the stored resources with the first provider of the encryption provider config. " +
+			"Run it after enabling encryption or rotating the key, the resources written before stay in their previous form otherwise.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := options.restConfig()
+			if err != nil {
+				return err
+			}
+			dynamicClient, err := dynamic.NewForConfig(config)
+			if err != nil {
+				return err
+			}
+			discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+			if err != nil {
+				return err
+			}
+			mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+			for _, resource := range resources {
+				gvr, err := mapper.ResourceFor(schema.ParseGroupResource(resource).WithVersion(""))
+				if err != nil {
+					return fmt.Errorf("failed to find the resource %s: %w", resource, err)
+				}
+				count, err := rewriteResources(cmd.Context(), dynamicClient, gvr)
+				if err != nil {
+					return fmt.Errorf("failed to migrate %s: %w", gvr.GroupResource(), err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %d rewritten\n", gvr.GroupResource(), count)
+			}
+			return nil
+		},
+	}
+
+	migrateEncryptionCommand.Flags().StringSliceVar(&resources, "resources", resources,
+		"Resources to rewrite, e.g. secrets,managedclusters.cluster.open-cluster-management.io")
+
+	return migrateEncryptionCommand
+}
+
+// rewriteResources updates every object of the resource without changing it. The apiserver writes the objects
+// read through a provider other than the first one again, so they end up stored with the current key.
+func rewriteResources(ctx context.Context, client dynamic.Interface, gvr schema.GroupVersionResource) (int, error) {
+	count := 0
+	listOptions := metav1.ListOptions{Limit: migrateChunkSize}
+	for {
+		list, err := client.Resource(gvr).List(ctx, listOptions)
+		if err != nil {
+			return count, err
+		}
+
+		for _, item := range list.Items {
+			namespace, name := item.GetNamespace(), item.GetName()
+			err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				obj, err := client.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				_, err = client.Resource(gvr).Namespace(namespace).Update(ctx, obj, metav1.UpdateOptions{})
+				return err
+			})
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if list.GetContinue() == "" {
+			return count, nil
+		}
+		listOptions.Continue = list.GetContinue()
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/discovery"
+
+	viewv1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/view/v1alpha1"
+)
+
+func newReportCommand(options *globalOptions) *cobra.Command {
+	reportCommand := &cobra.Command{
+		Use:   "report",
+		Short: "Reports on the fleet",
+	}
+	reportCommand.AddCommand(newReportComplianceCommand(options))
+	return reportCommand
+}
+
+func newReportComplianceCommand(options *globalOptions) *cobra.Command {
+	report := viewv1alpha1.ClusterReport
+	format := viewv1alpha1.CSVReportFormat
+	outputDir := ""
+	interval := time.Duration(0)
+	reportComplianceCommand := &cobra.Command{
+		Use:   "compliance",
+		Short: "Reports the compliance of the global policies on the regional hubs and the managed clusters",
+		Long: "Reports the compliance of the global policies, with their standards, categories and controls, per " +
+			"policy, per regional hub or per managed cluster. The report is computed by the global hub and printed, or " +
+			"written to the output directory, every interval when it is set.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			extension, ok := map[string]string{
+				viewv1alpha1.JSONReportFormat:  "json",
+				viewv1alpha1.CSVReportFormat:   "csv",
+				viewv1alpha1.JUnitReportFormat: "xml",
+			}[format]
+			if !ok {
+				return fmt.Errorf("--format must be json, csv or junit")
+			}
+			if interval > 0 && len(outputDir) == 0 {
+				return fmt.Errorf("--interval requires --output-dir")
+			}
+			config, err := options.restConfig()
+			if err != nil {
+				return err
+			}
+			discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+			if err != nil {
+				return err
+			}
+			getReport := func(ctx context.Context) ([]byte, error) {
+				return discoveryClient.RESTClient().Get().
+					AbsPath("/apis", viewv1alpha1.GroupVersion.Group, viewv1alpha1.GroupVersion.Version, "compliancereports", report).
+					Param("format", format).
+					DoRaw(ctx)
+			}
+
+			if len(outputDir) == 0 {
+				data, err := getReport(cmd.Context())
+				if err != nil {
+					return err
+				}
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+			if err := os.MkdirAll(outputDir, 0o755); err != nil {
+				return err
+			}
+			for {
+				data, err := getReport(cmd.Context())
+				if err == nil {
+					file := filepath.Join(outputDir, fmt.Sprintf("compliance-%s-%s.%s", report, time.Now().UTC().Format("20060102T150405Z"), extension))
+					if err = writeReport(file, data); err == nil {
+						fmt.Fprintf(cmd.OutOrStdout(), "wrote %s\n", file)
+					}
+				}
+				if interval == 0 {
+					return err
+				}
+				// a failed report is retried at the next interval
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "failed to write the compliance report: %v\n", err)
+				}
+				select {
+				case <-cmd.Context().Done():
+					return nil
+				case <-time.After(interval):
+				}
+			}
+		},
+	}
+
+	reportComplianceCommand.Flags().StringVar(&report, "report", report, "The entries of the report, 'policies', "+
+		"'hubs' or 'clusters' for one entry per policy, per policy and regional hub or per policy and managed cluster.")
+	reportComplianceCommand.Flags().StringVar(&format, "format", format, "The format of the report, json, csv or junit.")
+	reportComplianceCommand.Flags().StringVar(&outputDir, "output-dir", outputDir, "The directory to write the "+
+		"timestamped reports to, the report is printed if not set.")
+	reportComplianceCommand.Flags().DurationVar(&interval, "interval", interval, "Writes a report to the output "+
+		"directory every interval, e.g. 24h, until the command is stopped. A single report is written if not set.")
+
+	return reportComplianceCommand
+}
+
+// writeReport writes the report to a temporary file renamed once complete, so no partial report is collected.
+func writeReport(file string, data []byte) error {
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
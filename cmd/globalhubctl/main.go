@@ -0,0 +1,15 @@
+package main
+
+import (
+	"os"
+
+	"k8s.io/component-base/cli"
+
+	"github.com/clyang82/multicluster-global-hub-lite/cmd/globalhubctl/cmd"
+)
+
+func main() {
+	globalhubctlCommand := cmd.NewGlobalHubCtlCommand()
+	code := cli.Run(globalhubctlCommand)
+	os.Exit(code)
+}
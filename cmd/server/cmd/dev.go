@@ -0,0 +1,405 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/kubeapiserver/authorizer/modes"
+	netutils "k8s.io/utils/net"
+	"sigs.k8s.io/yaml"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver"
+	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver/options"
+	"github.com/clyang82/multicluster-global-hub-lite/server/etcd"
+	"github.com/clyang82/multicluster-global-hub-lite/syncer"
+)
+
+//go:embed manifests
+var regionalHubManifestsFS embed.FS
+
+const (
+	globalHubName = "global-hub"
+	adminUser     = "admin"
+)
+
+type devOptions struct {
+	Dir                string
+	GlobalHubPort      int
+	RegionalHubPort    int
+	RegionalHubName    string
+	EtcdPeerPort       int
+	EtcdClientPort     int
+	BrokerPort         int
+	GRPCSyncPort       int
+	StatusBundleWindow time.Duration
+}
+
+func newDevCommand() *cobra.Command {
+	o := &devOptions{
+		Dir:             ".global-hub-dev",
+		GlobalHubPort:   6443,
+		RegionalHubPort: 6444,
+		RegionalHubName: "regional-hub",
+		EtcdPeerPort:    2380,
+		EtcdClientPort:  2379,
+	}
+	devCommand := &cobra.Command{
+		Use:   "dev",
+		Short: "Runs the global hub apiserver, a regional hub apiserver and a syncer between them in one process",
+		Long: "Runs the global hub apiserver, a regional hub apiserver serving the OCM APIs and a syncer between them, " +
+			"on top of one embedded etcd. The state, the certificates and the kubeconfigs of both hubs are written to --dir.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDev(genericapiserver.SetupSignalContext(), o)
+		},
+	}
+
+	flags := devCommand.Flags()
+	flags.StringVar(&o.Dir, "dir", o.Dir, "Directory of the state, the certificates and the kubeconfigs.")
+	flags.IntVar(&o.GlobalHubPort, "global-hub-port", o.GlobalHubPort, "Secure port of the global hub apiserver.")
+	flags.IntVar(&o.RegionalHubPort, "regional-hub-port", o.RegionalHubPort, "Secure port of the regional hub apiserver.")
+	flags.StringVar(&o.RegionalHubName, "regional-hub-name", o.RegionalHubName, "Name of the regional hub, the syncer reports its status under this name.")
+	flags.IntVar(&o.EtcdPeerPort, "embedded-etcd-peer-port", o.EtcdPeerPort, "Port for embedded etcd peer")
+	flags.IntVar(&o.EtcdClientPort, "embedded-etcd-client-port", o.EtcdClientPort, "Port for embedded etcd client")
+	flags.IntVar(&o.BrokerPort, "broker-port", o.BrokerPort, "Port of an embedded NATS broker, the syncer exchanges the "+
+		"spec and the status with the global hub through it when set.")
+	flags.IntVar(&o.GRPCSyncPort, "grpc-sync-port", o.GRPCSyncPort, "Port of the gRPC sync service of the global hub, the "+
+		"syncer streams the spec and the status with the global hub through it when set.")
+	flags.DurationVar(&o.StatusBundleWindow, "status-bundle-window", o.StatusBundleWindow, "Period the syncer writes the "+
+		"status of a resource to the global hub in a single StatusBundle, the status is written per object when 0.")
+
+	return devCommand
+}
+
+func runDev(ctx context.Context, o *devOptions) error {
+	dir, token, etcdClientInfo, err := prepareLocalHubs(ctx, o.Dir, o.EtcdPeerPort, o.EtcdClientPort)
+	if err != nil {
+		return err
+	}
+
+	// both hubs share the embedded etcd, under different prefixes
+	errCh := make(chan error, 2)
+	hubs := []struct {
+		name   string
+		port   int
+		global bool
+	}{
+		{name: globalHubName, port: o.GlobalHubPort, global: true},
+		{name: o.RegionalHubName, port: o.RegionalHubPort},
+	}
+	// the sync service is served by the global hub
+	grpcAddress := ""
+	if o.GRPCSyncPort > 0 {
+		grpcAddress = fmt.Sprintf("127.0.0.1:%d", o.GRPCSyncPort)
+	}
+	configs := map[string]*rest.Config{}
+	for _, hub := range hubs {
+		hubGRPCAddress := ""
+		if hub.global {
+			hubGRPCAddress = grpcAddress
+		}
+		configs[hub.name], err = startLocalHub(ctx, dir, hub.name, hub.port, hub.global, hubGRPCAddress, token, etcdClientInfo, errCh)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, hub := range hubs {
+		if err := waitForReady(ctx, configs[hub.name]); err != nil {
+			return fmt.Errorf("the %s apiserver is not ready: %v", hub.name, err)
+		}
+	}
+	if err := installRegionalHubCRDs(ctx, configs[o.RegionalHubName]); err != nil {
+		return err
+	}
+	// the syncer reports the status of the regional hub to its namespace on the global hub
+	if err := ensureNamespace(ctx, configs[globalHubName], o.RegionalHubName); err != nil {
+		return err
+	}
+
+	var syncerBroker *syncer.BrokerConfig
+	if o.BrokerPort > 0 {
+		broker, brokerConfigs, err := startDevBroker(dir, o.BrokerPort, globalHubName, o.RegionalHubName)
+		if err != nil {
+			return err
+		}
+		defer broker.Shutdown()
+		if err := syncer.StartBrokerServer(ctx, configs[globalHubName], brokerConfigs[globalHubName]); err != nil {
+			return err
+		}
+		syncerBroker = brokerConfigs[o.RegionalHubName]
+	}
+
+	if err := syncer.StartSyncer(ctx, &syncer.SyncerConfig{
+		UpstreamConfig:     configs[globalHubName],
+		DownstreamConfig:   configs[o.RegionalHubName],
+		SyncerName:         o.RegionalHubName,
+		StatusQueueFile:    filepath.Join(dir, o.RegionalHubName+"-status-queue.db"),
+		Broker:             syncerBroker,
+		GRPCAddress:        grpcAddress,
+		StatusBundleWindow: o.StatusBundleWindow,
+	}, numSyncerThreads); err != nil {
+		return err
+	}
+
+	klog.Infof("The global hub is ready, export KUBECONFIG=%s", filepath.Join(dir, globalHubName+".kubeconfig"))
+	klog.Infof("The regional hub %s is ready, export KUBECONFIG=%s", o.RegionalHubName, filepath.Join(dir, o.RegionalHubName+".kubeconfig"))
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errCh:
+		return err
+	}
+}
+
+const numSyncerThreads = 2
+
+// prepareLocalHubs creates the directory of the local hubs with the admin token and the service account key, then starts
+// the embedded etcd shared by the hubs.
+func prepareLocalHubs(ctx context.Context, dir string, etcdPeerPort, etcdClientPort int) (string, string, etcd.ClientInfo, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", etcd.ClientInfo{}, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", etcd.ClientInfo{}, err
+	}
+
+	token, err := ensureAdminToken(filepath.Join(dir, "tokens.csv"))
+	if err != nil {
+		return "", "", etcd.ClientInfo{}, err
+	}
+	if err := ensureServiceAccountKey(filepath.Join(dir, "sa.key")); err != nil {
+		return "", "", etcd.ClientInfo{}, err
+	}
+	// like the deployment, the CRDs are served without the OpenAPI v3 documents
+	if err := utilfeature.DefaultMutableFeatureGate.Set("OpenAPIV3=false"); err != nil {
+		return "", "", etcd.ClientInfo{}, err
+	}
+
+	etcdClientInfo, err := etcd.Run(ctx, filepath.Join(dir, "etcd"), strconv.Itoa(etcdPeerPort), strconv.Itoa(etcdClientPort))
+	if err != nil {
+		return "", "", etcd.ClientInfo{}, err
+	}
+	return dir, token, etcdClientInfo, nil
+}
+
+// startLocalHub runs the apiserver of a hub until the context is done and returns the config of the admin, the
+// kubeconfig is written to the directory. Only the global hub runs the global hub controllers.
+func startLocalHub(ctx context.Context, dir, name string, port int, global bool, grpcSyncAddress, token string,
+	etcdClientInfo etcd.ClientInfo, errCh chan<- error) (*rest.Config, error) {
+	s := devServerRunOptions(dir, name, port, etcdClientInfo)
+	s.DisableGlobalHubControllers = !global
+	s.GRPCSyncAddress = grpcSyncAddress
+	completedOptions, err := apiserver.Complete(s)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		errCh <- fmt.Errorf("the %s apiserver stopped: %v", name, completedOptions.Run(ctx))
+	}()
+
+	kubeconfig := filepath.Join(dir, name+".kubeconfig")
+	if err := writeKubeconfig(kubeconfig, name, fmt.Sprintf("https://127.0.0.1:%d", port), token); err != nil {
+		return nil, err
+	}
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
+
+// devServerRunOptions returns the options of a hub listening on localhost, authenticating the admin with the token file.
+func devServerRunOptions(dir, name string, port int, etcdClientInfo etcd.ClientInfo) *options.ServerRunOptions {
+	s := options.NewServerRunOptions()
+
+	s.Etcd.StorageConfig.Prefix = "/" + name + options.DefaultEtcdPathPrefix
+	s.Etcd.StorageConfig.Transport.ServerList = etcdClientInfo.Endpoints
+	s.Etcd.StorageConfig.Transport.KeyFile = etcdClientInfo.KeyFile
+	s.Etcd.StorageConfig.Transport.CertFile = etcdClientInfo.CertFile
+	s.Etcd.StorageConfig.Transport.TrustedCAFile = etcdClientInfo.TrustedCAFile
+
+	s.SecureServing.BindAddress = netutils.ParseIPSloppy("127.0.0.1")
+	s.SecureServing.BindPort = port
+	s.SecureServing.ServerCert.CertDirectory = filepath.Join(dir, name)
+	s.GenericServerRunOptions.AdvertiseAddress = netutils.ParseIPSloppy("127.0.0.1")
+
+	s.Authentication.TokenFile.TokenFile = filepath.Join(dir, "tokens.csv")
+	s.Authentication.ServiceAccounts.Lookup = false
+	s.Authorization.Modes = []string{modes.ModeRBAC}
+	s.ServiceAccountSigningKeyFile = filepath.Join(dir, "sa.key")
+
+	return s
+}
+
+// ensureAdminToken returns the token of the admin, the token file is created on the first run.
+func ensureAdminToken(tokenFile string) (string, error) {
+	if b, err := os.ReadFile(tokenFile); err == nil {
+		var token string
+		if _, err := fmt.Sscanf(string(b), "%32s", &token); err != nil {
+			return "", fmt.Errorf("failed to read the token of %s: %v", tokenFile, err)
+		}
+		return token, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	line := fmt.Sprintf("%s,%s,%s,%s\n", token, adminUser, adminUser, "system:masters")
+	return token, os.WriteFile(tokenFile, []byte(line), 0600)
+}
+
+// ensureServiceAccountKey creates the key signing the service account tokens on the first run.
+func ensureServiceAccountKey(keyFile string) error {
+	if _, err := os.Stat(keyFile); err == nil || !os.IsNotExist(err) {
+		return err
+	}
+	key, err := keyutil.MakeEllipticPrivateKeyPEM()
+	if err != nil {
+		return err
+	}
+	return keyutil.WriteKey(keyFile, key)
+}
+
+// startDevBroker starts the embedded broker on a self-signed certificate, with a user per hub, and returns the
+// configs of the users. The certificate is created on the first run, the passwords on every run.
+func startDevBroker(dir string, port int, users ...string) (*natsserver.Server, map[string]*syncer.BrokerConfig, error) {
+	certFile, keyFile := filepath.Join(dir, "broker.crt"), filepath.Join(dir, "broker.key")
+	if _, err := os.Stat(certFile); os.IsNotExist(err) {
+		cert, key, err := certutil.GenerateSelfSignedCertKey("127.0.0.1", []net.IP{netutils.ParseIPSloppy("127.0.0.1")}, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := certutil.WriteCert(certFile, cert); err != nil {
+			return nil, nil, err
+		}
+		if err := keyutil.WriteKey(keyFile, key); err != nil {
+			return nil, nil, err
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	configs := map[string]*syncer.BrokerConfig{}
+	brokerUsers := []*natsserver.User{}
+	for _, user := range users {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		password := hex.EncodeToString(b)
+		passwordFile := filepath.Join(dir, "broker-"+user+".password")
+		if err := os.WriteFile(passwordFile, []byte(password), 0600); err != nil {
+			return nil, nil, err
+		}
+		brokerUsers = append(brokerUsers, syncer.BrokerUser(user, password))
+		configs[user] = &syncer.BrokerConfig{
+			URL:          fmt.Sprintf("tls://127.0.0.1:%d", port),
+			User:         user,
+			PasswordFile: passwordFile,
+			CAFile:       certFile,
+		}
+	}
+
+	broker, err := syncer.StartEmbeddedBroker("127.0.0.1", port,
+		&tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, brokerUsers)
+	if err != nil {
+		return nil, nil, err
+	}
+	return broker, configs, nil
+}
+
+func writeKubeconfig(path, name, server, token string) error {
+	config := clientcmdapi.NewConfig()
+	// the serving certificates are self-signed
+	config.Clusters[name] = &clientcmdapi.Cluster{Server: server, InsecureSkipTLSVerify: true}
+	config.AuthInfos[adminUser] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: adminUser}
+	config.CurrentContext = name
+	return clientcmd.WriteToFile(*config, path)
+}
+
+func waitForReady(ctx context.Context, config *rest.Config) error {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	return wait.PollImmediateUntil(time.Second, func() (bool, error) {
+		if _, err := client.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx); err != nil {
+			klog.V(4).Infof("Waiting for %s: %v", config.Host, err)
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+}
+
+// installRegionalHubCRDs installs the CRDs of the regional hub which are not served by the global hub apiserver.
+func installRegionalHubCRDs(ctx context.Context, config *rest.Config) error {
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	return fs.WalkDir(regionalHubManifestsFS, "manifests", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := regionalHubManifestsFS.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(b, &obj.Object); err != nil {
+			return err
+		}
+		_, err = dynamicClient.Resource(apiextensionsv1.SchemeGroupVersion.WithResource("customresourcedefinitions")).
+			Create(ctx, obj, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+		return nil
+	})
+}
+
+func ensureNamespace(ctx context.Context, config *rest.Config, name string) error {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	_, err = client.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
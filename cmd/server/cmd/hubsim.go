@@ -0,0 +1,456 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	"github.com/clyang82/multicluster-global-hub-lite/syncer"
+)
+
+var (
+	policyGVR         = schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}
+	managedClusterGVR = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}
+)
+
+type hubsimOptions struct {
+	Kubeconfig         string
+	Dir                string
+	Hubs               int
+	ClustersPerHub     int
+	Policies           int
+	Namespace          string
+	ChurnInterval      time.Duration
+	ChurnPolicies      int
+	Duration           time.Duration
+	Timeout            time.Duration
+	QPS                float32
+	Burst              int
+	BasePort           int
+	EtcdPeerPort       int
+	EtcdClientPort     int
+	Cleanup            bool
+	StatusBundleWindow time.Duration
+}
+
+func newHubsimCommand() *cobra.Command {
+	o := &hubsimOptions{
+		Hubs:           10,
+		ClustersPerHub: 10,
+		Policies:       100,
+		Namespace:      "hubsim",
+		ChurnInterval:  time.Second,
+		ChurnPolicies:  5,
+		Duration:       time.Minute,
+		Timeout:        5 * time.Minute,
+		QPS:            50,
+		Burst:          100,
+		BasePort:       7443,
+		EtcdPeerPort:   32380,
+		EtcdClientPort: 32379,
+		Cleanup:        true,
+	}
+	hubsimCommand := &cobra.Command{
+		Use:   "hubsim",
+		Short: "Simulates a fleet of regional hubs syncing with a global hub and reports the latencies",
+		Long: "Runs --hubs regional hub apiservers in process, each with --clusters-per-hub fake managed clusters and a " +
+			"syncer to the global hub of --kubeconfig. Creates --policies policies on the global hub, waits for them to be " +
+			"propagated to every regional hub, then changes the compliance of random policies on every regional hub during " +
+			"--duration. Reports the percentiles of the propagation and the status roll-up latencies, and the resource " +
+			"usage of the global hub apiserver and its storage scraped from its metrics.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.Kubeconfig == "" {
+				return fmt.Errorf("--kubeconfig is required")
+			}
+			if o.Hubs <= 0 || o.ClustersPerHub <= 0 || o.Policies <= 0 {
+				return fmt.Errorf("--hubs, --clusters-per-hub and --policies must be positive")
+			}
+			return runHubsim(genericapiserver.SetupSignalContext(), o, cmd.OutOrStdout())
+		},
+	}
+
+	flags := hubsimCommand.Flags()
+	flags.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig, "Kubeconfig file of the global hub apiserver.")
+	flags.StringVar(&o.Dir, "dir", o.Dir, "Directory of the regional hubs, a temporary directory removed after the run by default.")
+	flags.IntVar(&o.Hubs, "hubs", o.Hubs, "Number of simulated regional hubs.")
+	flags.IntVar(&o.ClustersPerHub, "clusters-per-hub", o.ClustersPerHub, "Number of fake managed clusters of every regional hub.")
+	flags.IntVar(&o.Policies, "policies", o.Policies, "Number of policies created on the global hub.")
+	flags.StringVar(&o.Namespace, "namespace", o.Namespace, "Namespace of the policies.")
+	flags.DurationVar(&o.ChurnInterval, "churn-interval", o.ChurnInterval, "Interval between the compliance changes.")
+	flags.IntVar(&o.ChurnPolicies, "churn-policies", o.ChurnPolicies, "Number of policies changing compliance on every regional hub per interval.")
+	flags.DurationVar(&o.Duration, "duration", o.Duration, "Duration of the compliance churn.")
+	flags.DurationVar(&o.Timeout, "timeout", o.Timeout, "Maximum time to wait for the propagation and the status roll-up.")
+	flags.Float32Var(&o.QPS, "qps", o.QPS, "QPS of the client creating the policies on the global hub.")
+	flags.IntVar(&o.Burst, "burst", o.Burst, "Burst of the client creating the policies on the global hub.")
+	flags.IntVar(&o.BasePort, "base-port", o.BasePort, "Secure port of the first regional hub apiserver, the next hubs use the next ports.")
+	flags.IntVar(&o.EtcdPeerPort, "embedded-etcd-peer-port", o.EtcdPeerPort, "Port for embedded etcd peer")
+	flags.IntVar(&o.EtcdClientPort, "embedded-etcd-client-port", o.EtcdClientPort, "Port for embedded etcd client")
+	flags.DurationVar(&o.StatusBundleWindow, "status-bundle-window", o.StatusBundleWindow, "Period the syncers write the status of a resource in a single StatusBundle, the status is written per object when 0.")
+	flags.BoolVar(&o.Cleanup, "cleanup", o.Cleanup, "Delete the policies and the namespaces of the regional hubs from the global hub after the run.")
+
+	return hubsimCommand
+}
+
+// simulatedHub is a regional hub apiserver running in process.
+type simulatedHub struct {
+	name     string
+	config   *rest.Config
+	client   dynamic.Interface
+	clusters []string
+}
+
+func runHubsim(ctx context.Context, o *hubsimOptions, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	globalConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: o.Kubeconfig}, nil).ClientConfig()
+	if err != nil {
+		return err
+	}
+	// the syncers keep the default rate limits of the syncer deployments
+	clientConfig := rest.CopyConfig(globalConfig)
+	clientConfig.QPS = o.QPS
+	clientConfig.Burst = o.Burst
+	globalClient, err := dynamic.NewForConfig(clientConfig)
+	if err != nil {
+		return err
+	}
+
+	dir := o.Dir
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", "hubsim"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+	}
+	_, token, etcdClientInfo, err := prepareLocalHubs(ctx, dir, o.EtcdPeerPort, o.EtcdClientPort)
+	if err != nil {
+		return err
+	}
+
+	// the regional hubs share the embedded etcd, under different prefixes
+	errCh := make(chan error, o.Hubs)
+	go func() {
+		select {
+		case err := <-errCh:
+			klog.Error(err)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	recorder := newLatencyRecorder()
+	hubs := make([]*simulatedHub, o.Hubs)
+	for i := range hubs {
+		name := fmt.Sprintf("sim-hub-%03d", i)
+		config, err := startLocalHub(ctx, dir, name, o.BasePort+i, false, "", token, etcdClientInfo, errCh)
+		if err != nil {
+			return err
+		}
+		hub := &simulatedHub{name: name, config: config}
+		if hub.client, err = dynamic.NewForConfig(config); err != nil {
+			return err
+		}
+		for j := 0; j < o.ClustersPerHub; j++ {
+			hub.clusters = append(hub.clusters, fmt.Sprintf("%s-cluster-%03d", name, j))
+		}
+		hubs[i] = hub
+	}
+
+	if err := ensureNamespace(ctx, globalConfig, o.Namespace); err != nil {
+		return err
+	}
+	for _, hub := range hubs {
+		if err := hub.prepare(ctx, o.Namespace); err != nil {
+			return fmt.Errorf("failed to prepare the regional hub %s: %v", hub.name, err)
+		}
+		// the syncer reports the status of the regional hub to its namespace on the global hub
+		if err := ensureNamespace(ctx, globalConfig, hub.name); err != nil {
+			return err
+		}
+		if err := hub.watchPolicies(ctx, o.Namespace, recorder); err != nil {
+			return err
+		}
+		if err := syncer.StartSyncer(ctx, &syncer.SyncerConfig{
+			UpstreamConfig:     globalConfig,
+			DownstreamConfig:   hub.config,
+			SyncerName:         hub.name,
+			StatusBundleWindow: o.StatusBundleWindow,
+		}, numSyncerThreads); err != nil {
+			return err
+		}
+	}
+	if err := watchRollUp(ctx, globalClient, hubs, recorder); err != nil {
+		return err
+	}
+	klog.Infof("%d regional hubs with %d managed clusters each are syncing with the global hub", o.Hubs, o.ClustersPerHub)
+
+	before, err := scrapeGlobalHubMetrics(ctx, globalConfig)
+	if err != nil {
+		return err
+	}
+
+	policies := make([]string, o.Policies)
+	for i := range policies {
+		policies[i] = fmt.Sprintf("sim-policy-%05d", i)
+		recorder.created(policies[i])
+		if err := createSimulatedPolicy(ctx, globalClient, o.Namespace, policies[i]); err != nil {
+			return err
+		}
+	}
+	klog.Infof("Created %d policies on the global hub", o.Policies)
+	if err := waitFor(ctx, o.Timeout, func() bool {
+		return recorder.propagatedCount() == o.Hubs*o.Policies
+	}); err != nil {
+		klog.Warningf("Stopped waiting for the propagation: %v", err)
+	}
+
+	churn(ctx, o, hubs, policies, recorder)
+	if err := waitFor(ctx, o.Timeout, func() bool {
+		return recorder.pendingCount() == 0
+	}); err != nil {
+		klog.Warningf("Stopped waiting for the status roll-up: %v", err)
+	}
+
+	after, err := scrapeGlobalHubMetrics(ctx, globalConfig)
+	if err != nil {
+		return err
+	}
+	report(out, o, recorder, before, after)
+
+	if o.Cleanup {
+		return cleanup(ctx, globalClient, o.Namespace, hubs)
+	}
+	return nil
+}
+
+// prepare waits for the OCM CRDs of the hub, then creates the namespace of the policies and the managed clusters.
+func (h *simulatedHub) prepare(ctx context.Context, namespace string) error {
+	if err := waitForReady(ctx, h.config); err != nil {
+		return err
+	}
+	if err := wait.PollImmediateUntil(time.Second, func() (bool, error) {
+		for _, gvr := range []schema.GroupVersionResource{policyGVR, managedClusterGVR} {
+			if _, err := h.client.Resource(gvr).List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+				klog.V(4).Infof("Waiting for %s on %s: %v", gvr.Resource, h.name, err)
+				return false, nil
+			}
+		}
+		return true, nil
+	}, ctx.Done()); err != nil {
+		return err
+	}
+	if err := installRegionalHubCRDs(ctx, h.config); err != nil {
+		return err
+	}
+	if err := ensureNamespace(ctx, h.config, namespace); err != nil {
+		return err
+	}
+
+	for _, name := range h.clusters {
+		cluster := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": managedClusterGVR.GroupVersion().String(),
+			"kind":       "ManagedCluster",
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+			"spec": map[string]interface{}{
+				"hubAcceptsClient": true,
+			},
+		}}
+		_, err := h.client.Resource(managedClusterGVR).Create(ctx, cluster, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchPolicies records the propagation latency of the policies created on the regional hub by the syncer.
+func (h *simulatedHub) watchPolicies(ctx context.Context, namespace string, recorder *latencyRecorder) error {
+	informerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(h.client, 0, namespace, nil)
+	informer := informerFactory.ForResource(policyGVR).Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if policy, ok := obj.(*unstructured.Unstructured); ok {
+				recorder.propagated(h.name, policy.GetName())
+			}
+		},
+	})
+	informerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to watch the policies of %s", h.name)
+	}
+	return nil
+}
+
+// setCompliance sets a random compliance state of the clusters in the status of the policy, like the policy framework
+// of the regional hub does.
+func (h *simulatedHub) setCompliance(ctx context.Context, namespace, name string, recorder *latencyRecorder) error {
+	status := []interface{}{}
+	for _, cluster := range h.clusters {
+		compliance := "Compliant"
+		if rand.Intn(2) == 0 {
+			compliance = "NonCompliant"
+		}
+		status = append(status, map[string]interface{}{
+			"clustername":      cluster,
+			"clusternamespace": cluster,
+			"compliant":        compliance,
+		})
+	}
+
+	start := time.Now()
+	var signature string
+	changed := false
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		policy, err := h.client.Resource(policyGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current := complianceSignature(policy)
+		if err := unstructured.SetNestedSlice(policy.Object, status, "status", "status"); err != nil {
+			return err
+		}
+		// the same compliance is not written, so there is nothing to roll up
+		if signature = complianceSignature(policy); signature == current {
+			return nil
+		}
+		_, err = h.client.Resource(policyGVR).Namespace(namespace).UpdateStatus(ctx, policy, metav1.UpdateOptions{})
+		changed = err == nil
+		return err
+	}); err != nil {
+		return err
+	}
+	if changed {
+		recorder.statusUpdated(h.name, name, signature, start)
+	}
+	return nil
+}
+
+// watchRollUp records the status roll-up latency of the policies reported by the syncers to the namespaces of the
+// regional hubs on the global hub.
+func watchRollUp(ctx context.Context, client dynamic.Interface, hubs []*simulatedHub, recorder *latencyRecorder) error {
+	hubNames := map[string]bool{}
+	for _, hub := range hubs {
+		hubNames[hub.name] = true
+	}
+	observe := func(obj interface{}) {
+		policy, ok := obj.(*unstructured.Unstructured)
+		if !ok || !hubNames[policy.GetNamespace()] {
+			return
+		}
+		recorder.rolledUp(policy.GetNamespace(), policy.GetName(), complianceSignature(policy))
+	}
+
+	informerFactory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	informer := informerFactory.ForResource(policyGVR).Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    observe,
+		UpdateFunc: func(_, obj interface{}) { observe(obj) },
+	})
+	informerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to watch the policies of the global hub")
+	}
+	return nil
+}
+
+// churn changes the compliance of random policies on every regional hub until the duration is over.
+func churn(ctx context.Context, o *hubsimOptions, hubs []*simulatedHub, policies []string, recorder *latencyRecorder) {
+	klog.Infof("Changing the compliance of %d policies per regional hub every %s for %s", o.ChurnPolicies, o.ChurnInterval, o.Duration)
+	ctx, cancel := context.WithTimeout(ctx, o.Duration)
+	defer cancel()
+	wait.JitterUntilWithContext(ctx, func(ctx context.Context) {
+		for _, hub := range hubs {
+			go func(hub *simulatedHub) {
+				for i := 0; i < o.ChurnPolicies; i++ {
+					policy := policies[rand.Intn(len(policies))]
+					if err := hub.setCompliance(ctx, o.Namespace, policy, recorder); err != nil && ctx.Err() == nil {
+						klog.Warningf("Failed to change the compliance of %s on %s: %v", policy, hub.name, err)
+					}
+				}
+			}(hub)
+		}
+	}, o.ChurnInterval, 0, true)
+}
+
+func createSimulatedPolicy(ctx context.Context, client dynamic.Interface, namespace, name string) error {
+	policy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": policyGVR.GroupVersion().String(),
+		"kind":       "Policy",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"spec": map[string]interface{}{
+			"disabled":          false,
+			"remediationAction": "inform",
+			"policy-templates":  []interface{}{},
+		},
+	}}
+	_, err := client.Resource(policyGVR).Namespace(namespace).Create(ctx, policy, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// cleanup deletes the policies and the status reported by the regional hubs from the global hub.
+func cleanup(ctx context.Context, client dynamic.Interface, namespace string, hubs []*simulatedHub) error {
+	if err := client.Resource(policyGVR).Namespace(namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{}); err != nil {
+		return err
+	}
+	for _, hub := range hubs {
+		err := client.Resource(schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}).Delete(ctx, hub.name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func waitFor(ctx context.Context, timeout time.Duration, condition func() bool) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return wait.PollImmediateUntil(time.Second, func() (bool, error) {
+		return condition(), nil
+	}, ctx.Done())
+}
+
+// complianceSignature identifies the compliance state of the clusters of a policy.
+func complianceSignature(policy *unstructured.Unstructured) string {
+	statuses, _, _ := unstructured.NestedSlice(policy.Object, "status", "status")
+	entries := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		if status, ok := status.(map[string]interface{}); ok {
+			cluster, _, _ := unstructured.NestedString(status, "clustername")
+			compliance, _, _ := unstructured.NestedString(status, "compliant")
+			entries = append(entries, cluster+"="+compliance)
+		}
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
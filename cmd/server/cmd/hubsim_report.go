@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// latencyRecorder records when the policies are created on the global hub and their compliance changes on the
+// regional hubs, then the latencies until the syncers propagate them.
+type latencyRecorder struct {
+	lock sync.Mutex
+
+	createdAt   map[string]time.Time
+	copies      map[string]bool
+	propagation []time.Duration
+
+	// pending are the compliance changes not rolled up yet, by regional hub and policy
+	pending       map[string]pendingStatus
+	statusUpdates int
+	superseded    int
+	rollUp        []time.Duration
+}
+
+type pendingStatus struct {
+	signature string
+	updatedAt time.Time
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{
+		createdAt: map[string]time.Time{},
+		copies:    map[string]bool{},
+		pending:   map[string]pendingStatus{},
+	}
+}
+
+func (r *latencyRecorder) created(policy string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.createdAt[policy] = time.Now()
+}
+
+func (r *latencyRecorder) propagated(hub, policy string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	createdAt, ok := r.createdAt[policy]
+	if !ok || r.copies[hub+"/"+policy] {
+		return
+	}
+	r.copies[hub+"/"+policy] = true
+	r.propagation = append(r.propagation, time.Since(createdAt))
+}
+
+func (r *latencyRecorder) propagatedCount() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.propagation)
+}
+
+// statusUpdated records a compliance change, a change not rolled up yet is superseded by the new one.
+func (r *latencyRecorder) statusUpdated(hub, policy, signature string, updatedAt time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.statusUpdates++
+	if _, ok := r.pending[hub+"/"+policy]; ok {
+		r.superseded++
+	}
+	r.pending[hub+"/"+policy] = pendingStatus{signature: signature, updatedAt: updatedAt}
+}
+
+func (r *latencyRecorder) rolledUp(hub, policy, signature string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	pending, ok := r.pending[hub+"/"+policy]
+	if !ok || pending.signature != signature {
+		return
+	}
+	delete(r.pending, hub+"/"+policy)
+	r.rollUp = append(r.rollUp, time.Since(pending.updatedAt))
+}
+
+func (r *latencyRecorder) pendingCount() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.pending)
+}
+
+// reportedMetrics are the metrics of the global hub apiserver in the report, summed over their labels.
+var reportedMetrics = []string{
+	"process_resident_memory_bytes",
+	"process_cpu_seconds_total",
+	"go_goroutines",
+	"apiserver_request_total",
+	"apiserver_storage_objects",
+	"etcd_db_total_size_in_bytes",
+}
+
+type globalHubMetrics struct {
+	scrapedAt time.Time
+	values    map[string]float64
+}
+
+func scrapeGlobalHubMetrics(ctx context.Context, config *rest.Config) (*globalHubMetrics, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	b, err := client.Discovery().RESTClient().Get().AbsPath("/metrics").DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape the metrics of the global hub: %v", err)
+	}
+	families, err := (&expfmt.TextParser{}).TextToMetricFamilies(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &globalHubMetrics{scrapedAt: time.Now(), values: map[string]float64{}}
+	for _, name := range reportedMetrics {
+		family, ok := families[name]
+		if !ok {
+			// e.g. the etcd metrics of a SQL datastore
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			switch {
+			case metric.GetGauge() != nil:
+				m.values[name] += metric.GetGauge().GetValue()
+			case metric.GetCounter() != nil:
+				m.values[name] += metric.GetCounter().GetValue()
+			case metric.GetUntyped() != nil:
+				m.values[name] += metric.GetUntyped().GetValue()
+			}
+		}
+	}
+	return m, nil
+}
+
+func report(out io.Writer, o *hubsimOptions, r *latencyRecorder, before, after *globalHubMetrics) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Regional hubs\t%d\t(%d managed clusters)\n", o.Hubs, o.Hubs*o.ClustersPerHub)
+	fmt.Fprintf(w, "Policies\t%d\n", o.Policies)
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "LATENCY\tCOUNT\tP50\tP90\tP99\tMAX\n")
+	fmt.Fprintf(w, "propagation\t%d/%d\t%s\n", len(r.propagation), o.Hubs*o.Policies, percentiles(r.propagation))
+	fmt.Fprintf(w, "status roll-up\t%d/%d\t%s\n", len(r.rollUp), r.statusUpdates-r.superseded, percentiles(r.rollUp))
+	if r.superseded > 0 {
+		fmt.Fprintf(w, "(%d compliance changes superseded before the roll-up)\n", r.superseded)
+	}
+	fmt.Fprintln(w)
+
+	elapsed := after.scrapedAt.Sub(before.scrapedAt).Seconds()
+	fmt.Fprintf(w, "GLOBAL HUB\tBEFORE\tAFTER\n")
+	if value, ok := after.values["process_resident_memory_bytes"]; ok {
+		fmt.Fprintf(w, "resident memory\t%s\t%s\n", mebibytes(before.values["process_resident_memory_bytes"]), mebibytes(value))
+	}
+	if value, ok := after.values["process_cpu_seconds_total"]; ok {
+		fmt.Fprintf(w, "cpu\t\t%.2f cores\n", (value-before.values["process_cpu_seconds_total"])/elapsed)
+	}
+	if value, ok := after.values["apiserver_request_total"]; ok {
+		fmt.Fprintf(w, "requests\t\t%.1f/s\n", (value-before.values["apiserver_request_total"])/elapsed)
+	}
+	if value, ok := after.values["go_goroutines"]; ok {
+		fmt.Fprintf(w, "goroutines\t%.0f\t%.0f\n", before.values["go_goroutines"], value)
+	}
+	if value, ok := after.values["apiserver_storage_objects"]; ok {
+		fmt.Fprintf(w, "storage objects\t%.0f\t%.0f\n", before.values["apiserver_storage_objects"], value)
+	}
+	if value, ok := after.values["etcd_db_total_size_in_bytes"]; ok {
+		fmt.Fprintf(w, "etcd database\t%s\t%s\n", mebibytes(before.values["etcd_db_total_size_in_bytes"]), mebibytes(value))
+	}
+	w.Flush()
+}
+
+func percentiles(latencies []time.Duration) string {
+	if len(latencies) == 0 {
+		return "-\t-\t-\t-"
+	}
+	sorted := append([]time.Duration{}, latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(p float64) time.Duration {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return sorted[i].Round(time.Millisecond)
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%s", at(0.5), at(0.9), at(0.99), sorted[len(sorted)-1].Round(time.Millisecond))
+}
+
+func mebibytes(b float64) string {
+	return fmt.Sprintf("%.1f MiB", b/(1<<20))
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	genericapiserver "k8s.io/apiserver/pkg/server"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver"
+	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver/options"
+)
+
+func NewServerCommand() *cobra.Command {
+	s := options.NewServerRunOptions()
+	serverCommand := &cobra.Command{
+		Use:   "global-hub-apiserver",
+		Short: "Serves the global hub APIs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := s.ValidateAPIProfileFlags(cmd.Flags()); err != nil {
+				return err
+			}
+			// set default options
+			completedOptions, err := apiserver.Complete(s)
+			if err != nil {
+				return err
+			}
+			// the service account options are only valid once completed
+			if err := s.Validate(args); err != nil {
+				return err
+			}
+
+			return completedOptions.Run(genericapiserver.SetupSignalContext())
+		},
+	}
+
+	s.AddFlags(serverCommand.Flags())
+	serverCommand.AddCommand(newDevCommand())
+	serverCommand.AddCommand(newHubsimCommand())
+
+	return serverCommand
+}
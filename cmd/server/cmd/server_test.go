@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver"
+	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver/options"
+)
+
+// serverArgs are the flags of the deployment of the global hub apiserver
+func serverArgs(dir, keyFile string) []string {
+	return []string{"--cert-dir=" + filepath.Join(dir, "certs"), "--bind-address=127.0.0.1",
+		"--service-account-signing-key-file=" + keyFile, "--service-account-lookup=false", "--authorization-mode=RBAC"}
+}
+
+func TestServerOptionsValidation(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "sa.key")
+	if err := ensureServiceAccountKey(keyFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// the options of the deployment are valid once completed
+	for _, args := range [][]string{{"--enable-embedded-etcd=true"}, {"--datastore-endpoint=sqlite://" + filepath.Join(dir, "state.db")}} {
+		s := options.NewServerRunOptions()
+		fs := pflag.NewFlagSet("global-hub-apiserver", pflag.ContinueOnError)
+		s.AddFlags(fs)
+		if err := fs.Parse(append(serverArgs(dir, keyFile), args...)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := apiserver.Complete(s); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Validate(nil); err != nil {
+			t.Errorf("%v: expected the options to be valid, got %v", args, err)
+		}
+	}
+}
+
+func TestServerCommandValidation(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "sa.key")
+	if err := ensureServiceAccountKey(keyFile); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"embedded etcd and datastore", []string{"--enable-embedded-etcd=true", "--datastore-endpoint=sqlite://" + filepath.Join(dir, "state.db")},
+			"--datastore-endpoint and --enable-embedded-etcd are mutually exclusive"},
+		{"local kms endpoint", []string{"--enable-embedded-etcd=true", "--local-kms-key-file=" + filepath.Join(dir, "kms.key"),
+			"--local-kms-endpoint=tcp://127.0.0.1:8080"}, "unix://"},
+		{"api profile", []string{"--enable-embedded-etcd=true", "--api-profile=small"}, "--api-profile must be"},
+		{"priority and fairness of the minimal profile", []string{"--enable-embedded-etcd=true", "--enable-priority-and-fairness=true"},
+			"--enable-priority-and-fairness requires --api-profile=full"},
+		{"external etcd", []string{}, "--etcd-servers must be specified"},
+	} {
+		command := NewServerCommand()
+		command.SilenceUsage = true
+		command.SilenceErrors = true
+		// the server is never started as the options are rejected
+		command.SetArgs(append(serverArgs(dir, keyFile), test.args...))
+		if err := command.Execute(); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected the options to be rejected with %q, got %v", test.name, test.expected, err)
+		}
+	}
+}
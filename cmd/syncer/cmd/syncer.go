@@ -46,10 +46,30 @@ func NewSyncerCommand() *cobra.Command {
 }
 
 func Run(options *synceroptions.Options, ctx context.Context) error {
-	globalhubConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: options.FromKubeconfig}, nil).ClientConfig()
-	if err != nil {
-		return err
+	var globalhubConfig *rest.Config
+	var broker *syncer.BrokerConfig
+	var grpcAddress string
+	var err error
+	if options.Transport == synceroptions.BrokerTransport {
+		broker = &syncer.BrokerConfig{
+			URL:          options.BrokerURL,
+			User:         options.BrokerUser,
+			PasswordFile: options.BrokerPasswordFile,
+			CAFile:       options.BrokerCAFile,
+		}
+		if len(broker.User) == 0 {
+			broker.User = options.PodNamespace
+		}
+	} else {
+		if options.Transport == synceroptions.GRPCTransport {
+			// the kubeconfig of the global hub provides the credentials of the streams
+			grpcAddress = options.GRPCAddress
+		}
+		globalhubConfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: options.FromKubeconfig}, nil).ClientConfig()
+		if err != nil {
+			return err
+		}
 	}
 
 	var toConfig *rest.Config
@@ -68,9 +88,13 @@ func Run(options *synceroptions.Options, ctx context.Context) error {
 	if err := syncer.StartSyncer(
 		ctx,
 		&syncer.SyncerConfig{
-			UpstreamConfig:   globalhubConfig,
-			DownstreamConfig: toConfig,
-			SyncerName:       options.PodNamespace,
+			UpstreamConfig:     globalhubConfig,
+			DownstreamConfig:   toConfig,
+			SyncerName:         options.PodNamespace,
+			StatusQueueFile:    options.StatusQueue,
+			Broker:             broker,
+			GRPCAddress:        grpcAddress,
+			StatusBundleWindow: options.StatusBundleWindow,
 		},
 		numThreads,
 	); err != nil {
@@ -2,24 +2,51 @@ package options
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/spf13/pflag"
 )
 
 type Options struct {
-	FromKubeconfig string
-	ToKubeconfig   string
-	PodNamespace   string
+	FromKubeconfig     string
+	ToKubeconfig       string
+	PodNamespace       string
+	StatusQueue        string
+	Transport          string
+	BrokerURL          string
+	BrokerUser         string
+	BrokerPasswordFile string
+	BrokerCAFile       string
+	GRPCAddress        string
+	StatusBundleWindow time.Duration
 }
 
+const (
+	// APIServerTransport watches the spec on the global hub apiserver and writes the status to it
+	APIServerTransport = "apiserver"
+	// BrokerTransport receives the spec from the global hub and sends the status to it through a NATS broker
+	BrokerTransport = "broker"
+	// GRPCTransport streams the spec from the sync service of the global hub and the status to it
+	GRPCTransport = "grpc"
+)
+
 func NewOptions() *Options {
-	return &Options{}
+	return &Options{Transport: APIServerTransport}
 }
 
 func (options *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&options.FromKubeconfig, "from-kubeconfig", options.FromKubeconfig, "Kubeconfig file for - from cluster.")
 	fs.StringVar(&options.ToKubeconfig, "to-kubeconfig", options.ToKubeconfig, "Kubeconfig file for - to cluster.")
 	fs.StringVar(&options.PodNamespace, "pod-namespace", "default", "The running namespace of the syncer pod")
+	fs.StringVar(&options.Transport, "transport", options.Transport, "How the spec and the status are exchanged with the global hub, 'apiserver', 'broker' or 'grpc'.")
+	fs.StringVar(&options.BrokerURL, "broker-url", options.BrokerURL, "The NATS broker of the broker transport, e.g. tls://broker:4222.")
+	fs.StringVar(&options.BrokerUser, "broker-user", options.BrokerUser, "The user of the syncer on the broker, only allowed to publish the status of its regional hub. Defaults to --pod-namespace, the name of the regional hub.")
+	fs.StringVar(&options.BrokerPasswordFile, "broker-password-file", options.BrokerPasswordFile, "The file of the password of --broker-user.")
+	fs.StringVar(&options.BrokerCAFile, "broker-ca-file", options.BrokerCAFile, "The CA verifying the certificate of the broker, the connection to the broker always uses TLS.")
+	fs.StringVar(&options.GRPCAddress, "grpc-address", options.GRPCAddress, "The sync service of the global hub of the grpc transport, e.g. global-hub:9443. The credentials are the ones of --from-kubeconfig.")
+	fs.StringVar(&options.StatusQueue, "status-queue", options.StatusQueue, "The file of the durable queue of the status updates to the global hub, the status updates are only queued in memory if not set.")
+	fs.DurationVar(&options.StatusBundleWindow, "status-bundle-window", options.StatusBundleWindow, "The period the status of a resource is written to the global hub in a single StatusBundle, e.g. 5s. The status is written per object when 0, the broker and grpc transports ignore it.")
 }
 
 func (options *Options) Complete() error {
@@ -27,8 +54,24 @@ func (options *Options) Complete() error {
 }
 
 func (options *Options) Validate() error {
-	if options.FromKubeconfig == "" {
-		return errors.New("--from-kubeconfig is required")
+	switch options.Transport {
+	case APIServerTransport:
+		if options.FromKubeconfig == "" {
+			return errors.New("--from-kubeconfig is required")
+		}
+	case BrokerTransport:
+		if options.BrokerURL == "" || options.BrokerPasswordFile == "" || options.BrokerCAFile == "" {
+			return errors.New("--broker-url, --broker-password-file and --broker-ca-file are required by the broker transport")
+		}
+	case GRPCTransport:
+		if options.FromKubeconfig == "" || options.GRPCAddress == "" {
+			return errors.New("--from-kubeconfig and --grpc-address are required by the grpc transport")
+		}
+	default:
+		return fmt.Errorf("--transport must be %s, %s or %s", APIServerTransport, BrokerTransport, GRPCTransport)
+	}
+	if options.StatusBundleWindow < 0 {
+		return errors.New("--status-bundle-window must not be negative")
 	}
 
 	// if options.ToKubeconfig == "" {
@@ -0,0 +1,377 @@
+package policyvalidation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+
+	"github.com/clyang82/multicluster-global-hub-lite/apis"
+)
+
+// PluginName indicates name of admission plugin.
+const PluginName = "GlobalHubPolicyValidation"
+
+// discoveryRefreshPeriod limits how often a reference to a resource not served refreshes the discovery
+const discoveryRefreshPeriod = 30 * time.Second
+
+var (
+	policyGVR           = policyv1.SchemeGroupVersion.WithResource("policies")
+	placementBindingGVR = policyv1.SchemeGroupVersion.WithResource("placementbindings")
+	placementRuleGVR    = schema.GroupVersionResource{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "placementrules"}
+	placementGVR        = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placements"}
+	policySetGVR        = schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1beta1", Resource: "policysets"}
+
+	// knownTemplateKinds are the "group/Kind" pairs the regional hubs know how to handle.
+	knownTemplateKinds = sets.NewString(
+		"policy.open-cluster-management.io/ConfigurationPolicy",
+		"policy.open-cluster-management.io/CertificatePolicy",
+		"policy.open-cluster-management.io/IamPolicy",
+		"templates.gatekeeper.sh/ConstraintTemplate",
+	)
+	// knownTemplateGroups accept any kind, e.g. the gatekeeper constraints are generated per template.
+	knownTemplateGroups = sets.NewString(
+		"constraints.gatekeeper.sh",
+	)
+
+	validComplianceTypes = sets.NewString("musthave", "mustnothave", "mustonlyhave")
+)
+
+// Register registers a plugin
+func Register(plugins *admission.Plugins) {
+	plugins.Register(PluginName, func(config io.Reader) (admission.Interface, error) {
+		configuration, err := LoadConfiguration(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewPolicyValidation(configuration), nil
+	})
+}
+
+// Plugin validates the governance resources created on the global hub before they are propagated
+// to the regional hubs.
+type Plugin struct {
+	*admission.Handler
+
+	strictness    Strictness
+	templateKinds sets.String
+	client        dynamic.Interface
+	mapper        meta.RESTMapper
+
+	lock sync.Mutex
+	// mapperReset is when the discovery was last refreshed to find a resource not served
+	mapperReset time.Time
+}
+
+var _ admission.ValidationInterface = &Plugin{}
+var _ WantsDynamicClient = &Plugin{}
+var _ WantsRESTMapper = &Plugin{}
+
+// NewPolicyValidation creates a new policy validation admission plugin
+func NewPolicyValidation(config *Configuration) *Plugin {
+	return &Plugin{
+		Handler:       admission.NewHandler(admission.Create, admission.Update),
+		strictness:    config.Strictness,
+		templateKinds: knownTemplateKinds.Union(sets.NewString(config.AdditionalTemplateKinds...)),
+	}
+}
+
+// SetDynamicClient sets the client used to look up the referenced objects.
+func (p *Plugin) SetDynamicClient(client dynamic.Interface) {
+	p.client = client
+}
+
+// SetRESTMapper sets the mapper used to find the served version of the referenced resources.
+func (p *Plugin) SetRESTMapper(mapper meta.RESTMapper) {
+	p.mapper = mapper
+}
+
+// ValidateInitialization ensures a dynamic client and a RESTMapper are set.
+func (p *Plugin) ValidateInitialization() error {
+	if p.client == nil {
+		return fmt.Errorf("%s requires a dynamic client", PluginName)
+	}
+	if p.mapper == nil {
+		return fmt.Errorf("%s requires a RESTMapper", PluginName)
+	}
+	return nil
+}
+
+// violation is a single problem found in the admitted object.
+type violation struct {
+	field   string
+	message string
+	// reference is true when the object itself is well formed but points to something missing.
+	reference bool
+}
+
+func (v violation) String() string {
+	return fmt.Sprintf("%s: %s", v.field, v.message)
+}
+
+// Validate makes sure the policies and placement bindings are well formed and consistent.
+func (p *Plugin) Validate(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	if len(a.GetSubresource()) != 0 {
+		return nil
+	}
+
+	gvr := a.GetResource()
+	if gvr != policyGVR && gvr != placementBindingGVR {
+		return nil
+	}
+
+	obj, ok := a.GetObject().(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	// the copies reported by the syncers reflect the regional hub state, leave them alone
+	if originalNamespace, ok := obj.GetLabels()[apis.GlobalHubPolicyNamespaceLabel]; ok && originalNamespace != obj.GetNamespace() {
+		return nil
+	}
+
+	var violations []violation
+	var err error
+	switch gvr {
+	case policyGVR:
+		violations, err = p.validatePolicy(obj)
+	case placementBindingGVR:
+		violations, err = p.validatePlacementBinding(ctx, obj)
+	}
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
+	return p.admit(ctx, a, violations)
+}
+
+// admit turns the violations into warnings or a rejection based on the configured strictness.
+func (p *Plugin) admit(ctx context.Context, a admission.Attributes, violations []violation) error {
+	var rejected []string
+	for _, v := range violations {
+		if p.strictness == StrictnessStrict || (p.strictness == StrictnessStandard && !v.reference) {
+			rejected = append(rejected, v.String())
+			continue
+		}
+		warning.AddWarning(ctx, "", fmt.Sprintf("%s %s/%s: %s", a.GetKind().Kind, a.GetNamespace(), a.GetName(), v))
+	}
+
+	if len(rejected) > 0 {
+		return admission.NewForbidden(a, fmt.Errorf("%s", strings.Join(rejected, "; ")))
+	}
+	return nil
+}
+
+func (p *Plugin) validatePolicy(obj *unstructured.Unstructured) ([]violation, error) {
+	policy := &policyv1.Policy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), policy); err != nil {
+		return []violation{{field: "spec", message: err.Error()}}, nil
+	}
+
+	var violations []violation
+	if len(policy.Spec.RemediationAction) > 0 && !validRemediationAction(string(policy.Spec.RemediationAction)) {
+		violations = append(violations, violation{
+			field:   "spec.remediationAction",
+			message: fmt.Sprintf("unsupported value %q, must be inform or enforce", policy.Spec.RemediationAction),
+		})
+	}
+
+	for i, template := range policy.Spec.PolicyTemplates {
+		field := fmt.Sprintf("spec.policy-templates[%d].objectDefinition", i)
+		if template == nil || len(template.ObjectDefinition.Raw) == 0 {
+			violations = append(violations, violation{field: field, message: "is required"})
+			continue
+		}
+
+		templateObj := &unstructured.Unstructured{}
+		if err := templateObj.UnmarshalJSON(template.ObjectDefinition.Raw); err != nil {
+			violations = append(violations, violation{field: field, message: err.Error()})
+			continue
+		}
+		violations = append(violations, p.validateTemplate(field, templateObj)...)
+	}
+
+	return violations, nil
+}
+
+func (p *Plugin) validateTemplate(field string, template *unstructured.Unstructured) []violation {
+	var violations []violation
+
+	gvk := template.GroupVersionKind()
+	if !p.templateKinds.Has(gvk.Group+"/"+gvk.Kind) && !knownTemplateGroups.Has(gvk.Group) {
+		violations = append(violations, violation{
+			field:   field + ".kind",
+			message: fmt.Sprintf("unknown policy template kind %q in group %q", gvk.Kind, gvk.Group),
+		})
+	}
+	if len(template.GetName()) == 0 {
+		violations = append(violations, violation{field: field + ".metadata.name", message: "is required"})
+	}
+
+	remediationAction, found, _ := unstructured.NestedString(template.Object, "spec", "remediationAction")
+	if found && !validRemediationAction(remediationAction) {
+		violations = append(violations, violation{
+			field:   field + ".spec.remediationAction",
+			message: fmt.Sprintf("unsupported value %q, must be inform or enforce", remediationAction),
+		})
+	}
+
+	if gvk.Group == policyv1.GroupVersion.Group && gvk.Kind == "ConfigurationPolicy" {
+		objectTemplates, _, _ := unstructured.NestedSlice(template.Object, "spec", "object-templates")
+		for i, t := range objectTemplates {
+			objectField := fmt.Sprintf("%s.spec.object-templates[%d]", field, i)
+			objectTemplate, ok := t.(map[string]interface{})
+			if !ok {
+				violations = append(violations, violation{field: objectField, message: "must be an object"})
+				continue
+			}
+			complianceType, _, _ := unstructured.NestedString(objectTemplate, "complianceType")
+			if !validComplianceTypes.Has(strings.ToLower(complianceType)) {
+				violations = append(violations, violation{
+					field:   objectField + ".complianceType",
+					message: fmt.Sprintf("unsupported value %q, must be one of %v", complianceType, validComplianceTypes.List()),
+				})
+			}
+			if _, found, _ := unstructured.NestedMap(objectTemplate, "objectDefinition"); !found {
+				violations = append(violations, violation{field: objectField + ".objectDefinition", message: "is required"})
+			}
+		}
+	}
+
+	return violations
+}
+
+func (p *Plugin) validatePlacementBinding(ctx context.Context, obj *unstructured.Unstructured) ([]violation, error) {
+	placementBinding := &policyv1.PlacementBinding{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), placementBinding); err != nil {
+		return []violation{{field: "placementRef", message: err.Error()}}, nil
+	}
+
+	var violations []violation
+	namespace := obj.GetNamespace()
+
+	ref := placementBinding.PlacementRef
+	var refGVR *schema.GroupVersionResource
+	switch {
+	case ref.APIGroup == placementRuleGVR.Group && ref.Kind == "PlacementRule":
+		refGVR = &placementRuleGVR
+	case ref.APIGroup == placementGVR.Group && ref.Kind == "Placement":
+		refGVR = &placementGVR
+	default:
+		violations = append(violations, violation{
+			field:   "placementRef",
+			message: fmt.Sprintf("unsupported kind %q in group %q, must be a PlacementRule or a Placement", ref.Kind, ref.APIGroup),
+		})
+	}
+	if len(ref.Name) == 0 {
+		violations = append(violations, violation{field: "placementRef.name", message: "is required"})
+	} else if refGVR != nil {
+		v, err := p.checkReference(ctx, *refGVR, namespace, ref.Name, "placementRef")
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, v...)
+	}
+
+	if len(placementBinding.Subjects) == 0 {
+		violations = append(violations, violation{field: "subjects", message: "at least one subject is required"})
+	}
+	for i, subject := range placementBinding.Subjects {
+		field := fmt.Sprintf("subjects[%d]", i)
+		var subjectGVR schema.GroupVersionResource
+		switch {
+		case subject.APIGroup == policyGVR.Group && subject.Kind == "Policy":
+			subjectGVR = policyGVR
+		case subject.APIGroup == policySetGVR.Group && subject.Kind == "PolicySet":
+			subjectGVR = policySetGVR
+		default:
+			violations = append(violations, violation{
+				field:   field,
+				message: fmt.Sprintf("unsupported kind %q in group %q, must be a Policy or a PolicySet", subject.Kind, subject.APIGroup),
+			})
+			continue
+		}
+		if len(subject.Name) == 0 {
+			violations = append(violations, violation{field: field + ".name", message: "is required"})
+			continue
+		}
+		v, err := p.checkReference(ctx, subjectGVR, namespace, subject.Name, field)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, v...)
+	}
+
+	return violations, nil
+}
+
+// checkReference reports a reference violation if the referenced object doesn't exist.
+// The reference is not checked when the global hub doesn't serve the resource, e.g. the PolicySets.
+func (p *Plugin) checkReference(ctx context.Context, gvr schema.GroupVersionResource, namespace, name, field string) ([]violation, error) {
+	// look the object up in whichever version of the resource is served
+	servedGVR, err := p.servedResource(gvr)
+	if meta.IsNoMatchError(err) {
+		klog.V(4).Infof("skip checking %s %s/%s, the resource is not served", gvr.Resource, namespace, name)
+		return nil, nil
+	}
+	if err != nil {
+		klog.Errorf("failed to find the served version of %s: %v", gvr.GroupResource(), err)
+		return nil, err
+	}
+
+	_, err = p.client.Resource(servedGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return []violation{{
+			field:     field,
+			message:   fmt.Sprintf("%s %s/%s does not exist", gvr.Resource, namespace, name),
+			reference: true,
+		}}, nil
+	}
+	if err != nil {
+		klog.Errorf("failed to get %s %s/%s: %v", gvr.Resource, namespace, name, err)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// servedResource returns the served version of the resource. The CRDs can be installed after the discovery is
+// cached, so a resource not served refreshes it, at most once per discoveryRefreshPeriod.
+func (p *Plugin) servedResource(gvr schema.GroupVersionResource) (schema.GroupVersionResource, error) {
+	servedGVR, err := p.mapper.ResourceFor(gvr.GroupResource().WithVersion(""))
+	mapper, ok := p.mapper.(meta.ResettableRESTMapper)
+	if !ok || !meta.IsNoMatchError(err) {
+		return servedGVR, err
+	}
+
+	p.lock.Lock()
+	refresh := time.Since(p.mapperReset) >= discoveryRefreshPeriod
+	if refresh {
+		p.mapperReset = time.Now()
+	}
+	p.lock.Unlock()
+	if !refresh {
+		return servedGVR, err
+	}
+	mapper.Reset()
+	return p.mapper.ResourceFor(gvr.GroupResource().WithVersion(""))
+}
+
+func validRemediationAction(action string) bool {
+	action = strings.ToLower(action)
+	return action == strings.ToLower(string(policyv1.Inform)) || action == strings.ToLower(string(policyv1.Enforce))
+}
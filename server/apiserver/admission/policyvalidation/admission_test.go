@@ -0,0 +1,256 @@
+package policyvalidation_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/client-go/dynamic/fake"
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+
+	"github.com/clyang82/multicluster-global-hub-lite/apis"
+	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver/admission/policyvalidation"
+)
+
+var (
+	policyGVR           = policyv1.SchemeGroupVersion.WithResource("policies")
+	placementBindingGVR = policyv1.SchemeGroupVersion.WithResource("placementbindings")
+	placementRuleGVR    = schema.GroupVersionResource{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "placementrules"}
+	// the placements are served in a version the plugin doesn't refer to, and the policysets aren't served
+	placementGVR = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1alpha1", Resource: "placements"}
+)
+
+func newPlugin(t *testing.T, strictness policyvalidation.Strictness, objects ...runtime.Object) *policyvalidation.Plugin {
+	plugin := policyvalidation.NewPolicyValidation(&policyvalidation.Configuration{Strictness: strictness})
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		policyGVR:        "PolicyList",
+		placementRuleGVR: "PlacementRuleList",
+		placementGVR:     "PlacementList",
+	}, objects...)
+	plugin.SetDynamicClient(client)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(policyGVR.GroupVersion().WithKind("Policy"), meta.RESTScopeNamespace)
+	mapper.Add(placementBindingGVR.GroupVersion().WithKind("PlacementBinding"), meta.RESTScopeNamespace)
+	mapper.Add(placementRuleGVR.GroupVersion().WithKind("PlacementRule"), meta.RESTScopeNamespace)
+	mapper.Add(placementGVR.GroupVersion().WithKind("Placement"), meta.RESTScopeNamespace)
+	plugin.SetRESTMapper(mapper)
+	if err := plugin.ValidateInitialization(); err != nil {
+		t.Fatal(err)
+	}
+	return plugin
+}
+
+func newObject(apiVersion, kind, namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	for k, v := range spec {
+		obj.Object[k] = v
+	}
+	return obj
+}
+
+func newPolicy(name string, remediationAction string, templateKind string) *unstructured.Unstructured {
+	return newObject("policy.open-cluster-management.io/v1", "Policy", "default", name, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"disabled":          false,
+			"remediationAction": remediationAction,
+			"policy-templates": []interface{}{
+				map[string]interface{}{
+					"objectDefinition": map[string]interface{}{
+						"apiVersion": "policy.open-cluster-management.io/v1",
+						"kind":       templateKind,
+						"metadata":   map[string]interface{}{"name": name + "-template"},
+						"spec": map[string]interface{}{
+							"remediationAction": "inform",
+							"object-templates": []interface{}{
+								map[string]interface{}{
+									"complianceType":   "musthave",
+									"objectDefinition": map[string]interface{}{"kind": "Namespace"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func newPlacementBinding(name, placementRule, policy string) *unstructured.Unstructured {
+	return newObject("policy.open-cluster-management.io/v1", "PlacementBinding", "default", name, map[string]interface{}{
+		"placementRef": map[string]interface{}{
+			"apiGroup": "apps.open-cluster-management.io",
+			"kind":     "PlacementRule",
+			"name":     placementRule,
+		},
+		"subjects": []interface{}{
+			map[string]interface{}{
+				"apiGroup": "policy.open-cluster-management.io",
+				"kind":     "Policy",
+				"name":     policy,
+			},
+		},
+	})
+}
+
+func newPlacementBindingOf(name string, placementRef map[string]interface{}, subjects ...map[string]interface{}) *unstructured.Unstructured {
+	binding := newObject("policy.open-cluster-management.io/v1", "PlacementBinding", "default", name, map[string]interface{}{
+		"placementRef": placementRef,
+	})
+	for _, subject := range subjects {
+		subjectList, _, _ := unstructured.NestedSlice(binding.Object, "subjects")
+		_ = unstructured.SetNestedSlice(binding.Object, append(subjectList, subject), "subjects")
+	}
+	return binding
+}
+
+func validate(plugin *policyvalidation.Plugin, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	attrs := admission.NewAttributesRecord(obj, nil, obj.GroupVersionKind(), obj.GetNamespace(), obj.GetName(),
+		gvr, "", admission.Create, nil, false, nil)
+	return plugin.Validate(context.TODO(), attrs, nil)
+}
+
+func TestValidatePolicy(t *testing.T) {
+	plugin := newPlugin(t, policyvalidation.StrictnessStandard)
+
+	if err := validate(plugin, policyGVR, newPolicy("valid", "inform", "ConfigurationPolicy")); err != nil {
+		t.Errorf("expected valid policy to be admitted: %v", err)
+	}
+
+	err := validate(plugin, policyGVR, newPolicy("bad-action", "remediate", "ConfigurationPolicy"))
+	if err == nil || !strings.Contains(err.Error(), "spec.remediationAction") {
+		t.Errorf("expected remediationAction to be rejected, got: %v", err)
+	}
+
+	err = validate(plugin, policyGVR, newPolicy("bad-kind", "enforce", "UnknownPolicy"))
+	if err == nil || !strings.Contains(err.Error(), "unknown policy template kind") {
+		t.Errorf("expected unknown template kind to be rejected, got: %v", err)
+	}
+
+	// the copies reported by the syncers are not validated
+	copied := newPolicy("bad-kind", "enforce", "UnknownPolicy")
+	copied.SetNamespace("hub1")
+	copied.SetLabels(map[string]string{apis.GlobalHubPolicyNamespaceLabel: "default"})
+	if err := validate(plugin, policyGVR, copied); err != nil {
+		t.Errorf("expected syncer copy to be admitted: %v", err)
+	}
+}
+
+func TestValidatePlacementBindingStrictness(t *testing.T) {
+	policy := newPolicy("policy1", "inform", "ConfigurationPolicy")
+	placementRule := newObject("apps.open-cluster-management.io/v1", "PlacementRule", "default", "rule1", nil)
+
+	cases := []struct {
+		name       string
+		strictness policyvalidation.Strictness
+		binding    *unstructured.Unstructured
+		rejected   bool
+	}{
+		{"existing references", policyvalidation.StrictnessStrict, newPlacementBinding("pb", "rule1", "policy1"), false},
+		{"missing reference in strict mode", policyvalidation.StrictnessStrict, newPlacementBinding("pb", "rule1", "missing"), true},
+		{"missing reference in standard mode", policyvalidation.StrictnessStandard, newPlacementBinding("pb", "missing", "policy1"), false},
+		{"missing reference in warn mode", policyvalidation.StrictnessWarn, newPlacementBinding("pb", "missing", "missing"), false},
+		{"empty placement name in standard mode", policyvalidation.StrictnessStandard, newPlacementBinding("pb", "", "policy1"), true},
+		{"empty placement name in warn mode", policyvalidation.StrictnessWarn, newPlacementBinding("pb", "", "policy1"), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			plugin := newPlugin(t, c.strictness, policy, placementRule)
+			err := validate(plugin, placementBindingGVR, c.binding)
+			if c.rejected && err == nil {
+				t.Errorf("expected placementbinding to be rejected")
+			}
+			if !c.rejected && err != nil {
+				t.Errorf("expected placementbinding to be admitted: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidatePlacementBindingReferences(t *testing.T) {
+	placement := newObject("cluster.open-cluster-management.io/v1alpha1", "Placement", "default", "placement1", nil)
+	plugin := newPlugin(t, policyvalidation.StrictnessStrict, placement)
+	placementRef := func(name string) map[string]interface{} {
+		return map[string]interface{}{"apiGroup": "cluster.open-cluster-management.io", "kind": "Placement", "name": name}
+	}
+	policySet := map[string]interface{}{"apiGroup": "policy.open-cluster-management.io", "kind": "PolicySet", "name": "set1"}
+
+	// the placement is found in the served version, the policysets are not checked
+	if err := validate(plugin, placementBindingGVR, newPlacementBindingOf("pb", placementRef("placement1"), policySet)); err != nil {
+		t.Errorf("expected placementbinding to be admitted: %v", err)
+	}
+	err := validate(plugin, placementBindingGVR, newPlacementBindingOf("pb", placementRef("missing"), policySet))
+	if err == nil || !strings.Contains(err.Error(), "placements default/missing does not exist") {
+		t.Errorf("expected the missing placement to be rejected, got: %v", err)
+	}
+}
+
+// installingMapper serves the placements once it is reset, like the discovery once their CRD is installed.
+type installingMapper struct {
+	meta.RESTMapper
+	resets int
+}
+
+func (m *installingMapper) Reset() {
+	m.resets++
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(placementGVR.GroupVersion().WithKind("Placement"), meta.RESTScopeNamespace)
+	m.RESTMapper = mapper
+}
+
+func TestValidatePlacementBindingInstalledResource(t *testing.T) {
+	plugin := newPlugin(t, policyvalidation.StrictnessStrict)
+	mapper := &installingMapper{RESTMapper: meta.NewDefaultRESTMapper(nil)}
+	plugin.SetRESTMapper(mapper)
+	placementRef := map[string]interface{}{"apiGroup": "cluster.open-cluster-management.io", "kind": "Placement", "name": "missing"}
+
+	// the discovery is refreshed to find the placements installed since it was cached
+	err := validate(plugin, placementBindingGVR, newPlacementBindingOf("pb", placementRef))
+	if err == nil || !strings.Contains(err.Error(), "placements default/missing does not exist") {
+		t.Errorf("expected the missing placement to be rejected, got: %v", err)
+	}
+	if mapper.resets != 1 {
+		t.Errorf("expected the discovery to be refreshed once, got %d", mapper.resets)
+	}
+	// the resources still not served don't refresh the discovery again right away
+	policySet := map[string]interface{}{"apiGroup": "policy.open-cluster-management.io", "kind": "PolicySet", "name": "set1"}
+	for i := 0; i < 2; i++ {
+		if err := validate(plugin, placementBindingGVR, newPlacementBindingOf("pb", placementRef, policySet)); err == nil {
+			t.Errorf("expected the missing placement to be rejected")
+		}
+	}
+	if mapper.resets != 1 {
+		t.Errorf("expected the discovery to be refreshed once, got %d", mapper.resets)
+	}
+}
+
+func TestLoadConfiguration(t *testing.T) {
+	config, err := policyvalidation.LoadConfiguration(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Strictness != policyvalidation.StrictnessStandard {
+		t.Errorf("expected default strictness %s, got %s", policyvalidation.StrictnessStandard, config.Strictness)
+	}
+
+	config, err = policyvalidation.LoadConfiguration(strings.NewReader("strictness: Strict\nadditionalTemplateKinds: [example.com/MyPolicy]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Strictness != policyvalidation.StrictnessStrict || len(config.AdditionalTemplateKinds) != 1 {
+		t.Errorf("unexpected configuration %+v", config)
+	}
+
+	if _, err := policyvalidation.LoadConfiguration(strings.NewReader("strictness: Loose\n")); err == nil {
+		t.Errorf("expected invalid strictness to be rejected")
+	}
+}
@@ -0,0 +1,65 @@
+package policyvalidation
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Strictness controls how violations found by the plugin are surfaced.
+type Strictness string
+
+const (
+	// StrictnessStrict rejects the request on any violation, including references
+	// to objects that do not exist yet.
+	StrictnessStrict Strictness = "Strict"
+	// StrictnessStandard rejects malformed content, but only warns about references
+	// to objects that do not exist yet, so that unordered applies keep working.
+	StrictnessStandard Strictness = "Standard"
+	// StrictnessWarn never rejects a request, every violation is returned as a warning.
+	StrictnessWarn Strictness = "Warn"
+)
+
+// Configuration is the plugin configuration passed through the admission config file, e.g.
+//
+//	plugins:
+//	- name: GlobalHubPolicyValidation
+//	  configuration:
+//	    strictness: Strict
+//	    additionalTemplateKinds:
+//	    - example.com/MyPolicy
+type Configuration struct {
+	// Strictness is one of Strict, Standard or Warn. Defaults to Standard.
+	Strictness Strictness `json:"strictness,omitempty"`
+	// AdditionalTemplateKinds lists extra "group/Kind" pairs accepted as policy templates.
+	AdditionalTemplateKinds []string `json:"additionalTemplateKinds,omitempty"`
+}
+
+// LoadConfiguration loads the provided configuration, falling back to the defaults if config is nil.
+func LoadConfiguration(config io.Reader) (*Configuration, error) {
+	configuration := &Configuration{}
+	if config != nil {
+		data, err := ioutil.ReadAll(config)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, configuration); err != nil {
+			return nil, fmt.Errorf("failed to decode %s configuration: %w", PluginName, err)
+		}
+	}
+
+	if configuration.Strictness == "" {
+		configuration.Strictness = StrictnessStandard
+	}
+
+	switch configuration.Strictness {
+	case StrictnessStrict, StrictnessStandard, StrictnessWarn:
+	default:
+		return nil, fmt.Errorf("invalid %s strictness %q, must be one of %s, %s or %s",
+			PluginName, configuration.Strictness, StrictnessStrict, StrictnessStandard, StrictnessWarn)
+	}
+
+	return configuration, nil
+}
@@ -0,0 +1,46 @@
+package policyvalidation
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/client-go/dynamic"
+)
+
+// WantsDynamicClient defines a function which sets a dynamic client for admission plugins that need
+// to look up custom resources served by the global hub.
+type WantsDynamicClient interface {
+	SetDynamicClient(dynamic.Interface)
+	admission.InitializationValidator
+}
+
+// WantsRESTMapper defines a function which sets a RESTMapper for admission plugins that need
+// to know which custom resources are served by the global hub.
+type WantsRESTMapper interface {
+	SetRESTMapper(meta.RESTMapper)
+	admission.InitializationValidator
+}
+
+type pluginInitializer struct {
+	dynamicClient dynamic.Interface
+	restMapper    meta.RESTMapper
+}
+
+// NewPluginInitializer creates an admission plugin initializer which injects the dynamic client
+// and the RESTMapper.
+func NewPluginInitializer(dynamicClient dynamic.Interface, restMapper meta.RESTMapper) admission.PluginInitializer {
+	return pluginInitializer{
+		dynamicClient: dynamicClient,
+		restMapper:    restMapper,
+	}
+}
+
+// Initialize checks the initialization interfaces implemented by a plugin
+// and provide the appropriate initialization data
+func (i pluginInitializer) Initialize(plugin admission.Interface) {
+	if wants, ok := plugin.(WantsDynamicClient); ok {
+		wants.SetDynamicClient(i.dynamicClient)
+	}
+	if wants, ok := plugin.(WantsRESTMapper); ok {
+		wants.SetRESTMapper(i.restMapper)
+	}
+}
@@ -1,6 +1,7 @@
 package apiserver
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -22,6 +23,8 @@ import (
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/client-go/dynamic"
 	kubeexternalinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	v1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
@@ -34,9 +37,13 @@ import (
 	"k8s.io/kube-aggregator/pkg/controllers/autoregister"
 	"k8s.io/kubernetes/pkg/controlplane/controller/crdregistration"
 
+	viewv1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/view/v1alpha1"
 	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver/options"
+	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver/views"
+	"github.com/clyang82/multicluster-global-hub-lite/server/compliancehistory"
 	"github.com/clyang82/multicluster-global-hub-lite/server/controllers/globalhubcontroller"
 	"github.com/clyang82/multicluster-global-hub-lite/server/controllers/kubecontroller"
+	"github.com/clyang82/multicluster-global-hub-lite/syncer"
 )
 
 func createAggregatorConfig(
@@ -55,6 +62,13 @@ func createAggregatorConfig(
 	// prevent generic API server from installing the OpenAPI handler. Aggregator server
 	// has its own customized OpenAPI handler.
 	genericConfig.SkipOpenAPIInstallation = true
+	// the views of the global hub are served by the aggregator
+	if genericConfig.OpenAPIConfig != nil {
+		genericConfig.OpenAPIConfig = views.OpenAPIConfig(genericConfig.OpenAPIConfig)
+	}
+	if genericConfig.OpenAPIV3Config != nil {
+		genericConfig.OpenAPIV3Config = views.OpenAPIConfig(genericConfig.OpenAPIV3Config)
+	}
 
 	if utilfeature.DefaultFeatureGate.Enabled(genericfeatures.StorageVersionAPI) &&
 		utilfeature.DefaultFeatureGate.Enabled(genericfeatures.APIServerIdentity) {
@@ -81,7 +95,11 @@ func createAggregatorConfig(
 	etcdOptions.StorageConfig.Paging = utilfeature.DefaultFeatureGate.Enabled(genericfeatures.APIListChunking)
 	etcdOptions.StorageConfig.Codec = aggregatorscheme.Codecs.LegacyCodec(v1.SchemeGroupVersion, v1beta1.SchemeGroupVersion)
 	etcdOptions.StorageConfig.EncodeVersioner = runtime.NewMultiGroupVersioner(v1.SchemeGroupVersion, schema.GroupKind{Group: v1beta1.GroupName})
-	genericConfig.RESTOptionsGetter = &genericoptions.SimpleRestOptionsFactory{Options: etcdOptions}
+	transformerOverrides, err := encryptionTransformerOverrides(commandOptions.Etcd)
+	if err != nil {
+		return nil, err
+	}
+	genericConfig.RESTOptionsGetter = &genericoptions.SimpleRestOptionsFactory{Options: etcdOptions, TransformerOverrides: transformerOverrides}
 
 	// override MergedResourceConfig with aggregator defaults and registry
 	if err := commandOptions.APIEnablement.ApplyTo(
@@ -91,10 +109,17 @@ func createAggregatorConfig(
 		return nil, err
 	}
 
+	// the availability controller of the aggregator watches the services and endpoints, which are not served by the
+	// minimal profile. The APIServices are all local then, give it informers which sync without the APIs.
+	aggregatorInformers := externalInformers
+	if commandOptions.APIProfile == options.MinimalAPIProfile {
+		aggregatorInformers = kubeexternalinformers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	}
+
 	aggregatorConfig := &aggregatorapiserver.Config{
 		GenericConfig: &genericapiserver.RecommendedConfig{
 			Config:                genericConfig,
-			SharedInformerFactory: externalInformers,
+			SharedInformerFactory: aggregatorInformers,
 		},
 		ExtraConfig: aggregatorapiserver.ExtraConfig{
 			ServiceResolver: serviceResolver,
@@ -108,7 +133,7 @@ func createAggregatorConfig(
 	return aggregatorConfig, nil
 }
 
-func createAggregatorServer(aggregatorConfig *aggregatorapiserver.Config, delegateAPIServer genericapiserver.DelegationTarget, apiExtensionInformers apiextensionsinformers.SharedInformerFactory, clientCert, clientKey string) (*aggregatorapiserver.APIAggregator, error) {
+func createAggregatorServer(aggregatorConfig *aggregatorapiserver.Config, delegateAPIServer genericapiserver.DelegationTarget, apiExtensionInformers apiextensionsinformers.SharedInformerFactory, clientCert, clientKey string, globalHubControllers bool, broker *options.Broker, grpcSyncAddress string, complianceHistory *options.ComplianceHistory) (*aggregatorapiserver.APIAggregator, error) {
 	aggregatorServer, err := aggregatorConfig.Complete().NewWithDelegate(delegateAPIServer)
 	if err != nil {
 		return nil, err
@@ -121,6 +146,36 @@ func createAggregatorServer(aggregatorConfig *aggregatorapiserver.Config, delega
 	}
 	autoRegistrationController := autoregister.NewAutoRegisterController(aggregatorServer.APIRegistrationInformers.Apiregistration().V1().APIServices(), apiRegistrationClient)
 	apiServices := apiServicesToRegister(delegateAPIServer, autoRegistrationController)
+
+	dynamicClient, err := dynamic.NewForConfig(aggregatorConfig.GenericConfig.LoopbackClientConfig)
+	if err != nil {
+		klog.Errorf("failed to create dynamic client: %v", err)
+	}
+
+	// the compliance changes of the global policies are recorded by the policy controller and served by the views
+	var history *compliancehistory.Store
+	if globalHubControllers {
+		history, err = compliancehistory.Open(context.TODO(), complianceHistory.File, complianceHistory.Retention, complianceHistory.MaxRecords)
+		if err != nil {
+			return nil, err
+		}
+		if err := aggregatorServer.GenericAPIServer.AddPostStartHook("global-hub-compliance-history", func(context genericapiserver.PostStartHookContext) error {
+			go history.Run(context.StopCh)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// serve the views of the regional hubs and the global policies from the aggregator
+	if globalHubControllers {
+		apiService, err := installViews(aggregatorServer.GenericAPIServer, dynamicClient, history)
+		if err != nil {
+			return nil, err
+		}
+		autoRegistrationController.AddAPIServiceToSyncOnStart(apiService)
+		apiServices = append(apiServices, apiService)
+	}
 	crdRegistrationController := crdregistration.NewCRDRegistrationController(
 		apiExtensionInformers.Apiextensions().V1().CustomResourceDefinitions(),
 		autoRegistrationController)
@@ -166,15 +221,10 @@ func createAggregatorServer(aggregatorConfig *aggregatorapiserver.Config, delega
 		return nil, err
 	}
 
-	dynamicClient, err := dynamic.NewForConfig(aggregatorConfig.GenericConfig.LoopbackClientConfig)
-	if err != nil {
-		klog.Errorf("failed to create dynamic client: %v", err)
-	}
-
-	// Add PostStartHook to install global hub crds
+	// Add PostStartHook to install global hub crds, the regional hubs only get the open cluster management ones
 	err = aggregatorServer.GenericAPIServer.AddPostStartHook("global-hub-crds", func(context genericapiserver.PostStartHookContext) error {
 		go func() {
-			if err := globalhubcontroller.InstallGlobalHubCRDs(dynamicClient); err != nil {
+			if err := globalhubcontroller.InstallGlobalHubCRDs(dynamicClient, globalHubControllers); err != nil {
 				klog.Errorf("failed to create global hub crds: %v", err)
 			}
 		}()
@@ -185,14 +235,78 @@ func createAggregatorServer(aggregatorConfig *aggregatorapiserver.Config, delega
 	}
 
 	// Add PostStartHook to install global hub controllers
-	if err := aggregatorServer.GenericAPIServer.AddPostStartHook("global-hub-controllers", func(context genericapiserver.PostStartHookContext) error {
-		globalhubcontroller.AddControllers(dynamicClient, context.StopCh)
+	if globalHubControllers {
+		if err := aggregatorServer.GenericAPIServer.AddPostStartHook("global-hub-controllers", func(context genericapiserver.PostStartHookContext) error {
+			kubeClient, err := kubernetes.NewForConfig(context.LoopbackClientConfig)
+			if err != nil {
+				return err
+			}
+			globalhubcontroller.AddControllers(dynamicClient, kubeClient, history, context.StopCh)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Add PostStartHook to exchange the spec and the status with the syncers through the broker
+	if globalHubControllers && len(broker.URL) > 0 {
+		brokerConfig := rest.CopyConfig(aggregatorConfig.GenericConfig.LoopbackClientConfig)
+		if err := aggregatorServer.GenericAPIServer.AddPostStartHook("global-hub-broker", func(hookContext genericapiserver.PostStartHookContext) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-hookContext.StopCh
+				cancel()
+			}()
+			return syncer.StartBrokerServer(ctx, brokerConfig, &syncer.BrokerConfig{
+				URL:          broker.URL,
+				User:         broker.User,
+				PasswordFile: broker.PasswordFile,
+				CAFile:       broker.CAFile,
+			})
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Add PostStartHook to stream the spec and the status with the syncers using the grpc transport
+	if globalHubControllers && len(grpcSyncAddress) > 0 {
+		syncConfig := rest.CopyConfig(aggregatorConfig.GenericConfig.LoopbackClientConfig)
+		tlsConfig, err := grpcSyncTLSConfig(&aggregatorConfig.GenericConfig.Config)
+		if err != nil {
+			return nil, err
+		}
+		auth := grpcSyncAuth(&aggregatorConfig.GenericConfig.Config)
+		if err := aggregatorServer.GenericAPIServer.AddPostStartHook("global-hub-grpc-sync", func(hookContext genericapiserver.PostStartHookContext) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-hookContext.StopCh
+				cancel()
+			}()
+			return syncer.StartGRPCServer(ctx, syncConfig, grpcSyncAddress, tlsConfig, auth)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return aggregatorServer, nil
+}
+
+// installViews installs the views in the aggregator, the informers of the views are started once the server is.
+func installViews(server *genericapiserver.GenericAPIServer, dynamicClient dynamic.Interface, history *compliancehistory.Store) (*v1.APIService, error) {
+	globalHubViews, err := views.NewViews(dynamicClient, history)
+	if err != nil {
+		return nil, err
+	}
+	if err := server.InstallAPIGroup(globalHubViews.APIGroupInfo()); err != nil {
+		return nil, err
+	}
+	if err := server.AddPostStartHook("global-hub-views", func(context genericapiserver.PostStartHookContext) error {
+		globalHubViews.Start(context.StopCh)
 		return nil
 	}); err != nil {
 		return nil, err
 	}
-
-	return aggregatorServer, nil
+	return makeAPIService(viewv1alpha1.GroupVersion), nil
 }
 
 func makeAPIService(gv schema.GroupVersion) *v1.APIService {
@@ -284,6 +398,7 @@ var apiVersionPriorities = map[schema.GroupVersion]priority{
 	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta1"}:  {group: 16100, version: 12},
 	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1alpha1"}: {group: 16100, version: 9},
 	{Group: "internal.apiserver.k8s.io", Version: "v1alpha1"}:    {group: 16000, version: 9},
+	viewv1alpha1.GroupVersion:                                    {group: 15900, version: 9},
 	// Append a new group to the end of the list if unsure.
 	// You can use min(existing group)-100 as the initial value for a group.
 	// Version can be set to 9 (to have space around) for a new group.
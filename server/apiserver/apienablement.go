@@ -83,3 +83,31 @@ func APIResourceConfigSource() *serverstorage.ResourceConfig {
 
 	return ret
 }
+
+var (
+	// minimalLegacyResources are the core resources served by the minimal API profile
+	minimalLegacyResources = []string{"namespaces", "secrets", "configmaps", "serviceaccounts", "events"}
+
+	// minimalAPIGroupVersions are the API groups served by the minimal API profile, besides the custom resources
+	minimalAPIGroupVersions = []schema.GroupVersion{
+		authenticationv1.SchemeGroupVersion,
+		authorizationapiv1.SchemeGroupVersion,
+		certificatesapiv1.SchemeGroupVersion,
+		coordinationapiv1.SchemeGroupVersion,
+		eventsv1.SchemeGroupVersion,
+		rbacv1.SchemeGroupVersion,
+	}
+)
+
+// MinimalAPIResourceConfigSource enables only the resources needed by the global hub, the workload, networking
+// and storage APIs which make no sense without nodes are left out.
+func MinimalAPIResourceConfigSource() *serverstorage.ResourceConfig {
+	ret := serverstorage.NewResourceConfig()
+
+	ret.EnableVersions(minimalAPIGroupVersions...)
+	for _, resource := range minimalLegacyResources {
+		ret.EnableResources(apiv1.SchemeGroupVersion.WithResource(resource))
+	}
+
+	return ret
+}
@@ -0,0 +1,44 @@
+package apiserver
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	genericregistry "k8s.io/apiserver/pkg/registry/generic"
+	genericoptions "k8s.io/apiserver/pkg/server/options"
+	"k8s.io/apiserver/pkg/server/options/encryptionconfig"
+	"k8s.io/apiserver/pkg/storage/value"
+)
+
+// encryptionTransformerOverrides loads the transformers of the --encryption-provider-config. The kube resources
+// get them through the storage factory, the apiextensions and aggregator servers need them passed explicitly.
+func encryptionTransformerOverrides(etcdOptions *genericoptions.EtcdOptions) (map[schema.GroupResource]value.Transformer, error) {
+	if etcdOptions.EncryptionProviderConfigFilepath == "" {
+		return nil, nil
+	}
+	return encryptionconfig.GetTransformerOverrides(etcdOptions.EncryptionProviderConfigFilepath)
+}
+
+// transformingRESTOptionsGetter applies the encryption transformers to the custom resources,
+// so the hub credentials stored in custom resources can be encrypted as well.
+type transformingRESTOptionsGetter struct {
+	delegate             genericregistry.RESTOptionsGetter
+	transformerOverrides map[schema.GroupResource]value.Transformer
+}
+
+func withTransformerOverrides(delegate genericregistry.RESTOptionsGetter,
+	transformerOverrides map[schema.GroupResource]value.Transformer) genericregistry.RESTOptionsGetter {
+	if len(transformerOverrides) == 0 {
+		return delegate
+	}
+	return &transformingRESTOptionsGetter{delegate: delegate, transformerOverrides: transformerOverrides}
+}
+
+func (t *transformingRESTOptionsGetter) GetRESTOptions(resource schema.GroupResource) (genericregistry.RESTOptions, error) {
+	ret, err := t.delegate.GetRESTOptions(resource)
+	if err != nil {
+		return ret, err
+	}
+	if transformer, ok := t.transformerOverrides[resource]; ok {
+		ret.StorageConfig.Transformer = transformer
+	}
+	return ret, nil
+}
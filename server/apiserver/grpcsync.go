@@ -0,0 +1,66 @@
+package apiserver
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+
+	"github.com/clyang82/multicluster-global-hub-lite/syncer"
+)
+
+// grpcSyncTLSConfig serves the sync service with the serving certificate of the apiserver, the client certificates
+// are requested to authenticate the syncers with them too.
+func grpcSyncTLSConfig(config *genericapiserver.Config) (*tls.Config, error) {
+	if config.SecureServing == nil || config.SecureServing.Cert == nil {
+		return nil, errors.New("the sync service requires the serving certificate of the apiserver")
+	}
+	provider := config.SecureServing.Cert
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequestClientCert,
+		// the serving certificate is rotated by the apiserver
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			certPEM, keyPEM := provider.CurrentCertKeyContent()
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				return nil, err
+			}
+			return &cert, nil
+		},
+	}, nil
+}
+
+// grpcSyncAuth authenticates the syncers like the apiserver does, and only lets them sync the regional hub whose
+// hubcontrolplane status they can update.
+func grpcSyncAuth(config *genericapiserver.Config) syncer.GRPCAuthFunc {
+	return func(req *http.Request, hub string) error {
+		resp, ok, err := config.Authentication.Authenticator.AuthenticateRequest(req)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("unauthorized")
+		}
+		decision, reason, err := config.Authorization.Authorizer.Authorize(req.Context(), authorizer.AttributesRecord{
+			User:            resp.User,
+			Verb:            "update",
+			APIGroup:        "cluster.open-cluster-management.io",
+			APIVersion:      "v1alpha1",
+			Resource:        "hubcontrolplanes",
+			Subresource:     "status",
+			Name:            hub,
+			ResourceRequest: true,
+		})
+		if err != nil {
+			return err
+		}
+		if decision != authorizer.DecisionAllow {
+			return fmt.Errorf("%s can not sync the regional hub %s: %s", resp.User.GetName(), hub, reason)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,212 @@
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/registry/rest"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/api/legacyscheme"
+	"k8s.io/kubernetes/pkg/controlplane"
+	"k8s.io/kubernetes/pkg/controlplane/controller/clusterauthenticationtrust"
+	authenticationrest "k8s.io/kubernetes/pkg/registry/authentication/rest"
+	authorizationrest "k8s.io/kubernetes/pkg/registry/authorization/rest"
+	certificatesrest "k8s.io/kubernetes/pkg/registry/certificates/rest"
+	coordinationrest "k8s.io/kubernetes/pkg/registry/coordination/rest"
+	configmapstore "k8s.io/kubernetes/pkg/registry/core/configmap/storage"
+	eventstore "k8s.io/kubernetes/pkg/registry/core/event/storage"
+	namespacestore "k8s.io/kubernetes/pkg/registry/core/namespace/storage"
+	podstore "k8s.io/kubernetes/pkg/registry/core/pod/storage"
+	secretstore "k8s.io/kubernetes/pkg/registry/core/secret/storage"
+	serviceaccountstore "k8s.io/kubernetes/pkg/registry/core/serviceaccount/storage"
+	eventsrest "k8s.io/kubernetes/pkg/registry/events/rest"
+	rbacrest "k8s.io/kubernetes/pkg/registry/rbac/rest"
+)
+
+// systemNamespaces are created by the bootstrap controller of a kube-apiserver, the minimal API server doesn't run it.
+var systemNamespaces = []string{metav1.NamespaceDefault, metav1.NamespaceSystem, metav1.NamespacePublic, corev1.NamespaceNodeLease}
+
+// CreateMinimalAPIServer creates an API server serving only the kube APIs of the minimal profile. Unlike the
+// controlplane instance, it doesn't build the pod, service and endpoint registries, the service IP allocators
+// and the kubelet client, so it needs none of the cluster networking configuration.
+func CreateMinimalAPIServer(c *controlplane.Config, delegateAPIServer genericapiserver.DelegationTarget) (*genericapiserver.GenericAPIServer, error) {
+	s, err := c.GenericConfig.Complete(c.ExtraConfig.VersionedInformers).New("kube-apiserver", delegateAPIServer)
+	if err != nil {
+		return nil, err
+	}
+
+	apiGroupInfo, err := newMinimalLegacyAPIGroupInfo(c)
+	if err != nil {
+		return nil, fmt.Errorf("error building core storage: %v", err)
+	}
+	if len(apiGroupInfo.VersionedResourcesStorageMap) > 0 {
+		if err := s.InstallLegacyAPIGroup(genericapiserver.DefaultLegacyAPIPrefix, apiGroupInfo); err != nil {
+			return nil, fmt.Errorf("error in registering group versions: %v", err)
+		}
+	}
+
+	m := &controlplane.Instance{
+		GenericAPIServer:          s,
+		ClusterAuthenticationInfo: c.ExtraConfig.ClusterAuthenticationInfo,
+	}
+	restStorageProviders := []controlplane.RESTStorageProvider{
+		authenticationrest.RESTStorageProvider{Authenticator: c.GenericConfig.Authentication.Authenticator, APIAudiences: c.GenericConfig.Authentication.APIAudiences},
+		authorizationrest.RESTStorageProvider{Authorizer: c.GenericConfig.Authorization.Authorizer, RuleResolver: c.GenericConfig.RuleResolver},
+		certificatesrest.RESTStorageProvider{},
+		coordinationrest.RESTStorageProvider{},
+		rbacrest.RESTStorageProvider{Authorizer: c.GenericConfig.Authorization.Authorizer},
+		eventsrest.RESTStorageProvider{TTL: c.ExtraConfig.EventTTL},
+	}
+	if err := m.InstallAPIs(c.ExtraConfig.APIResourceConfigSource, c.GenericConfig.RESTOptionsGetter, restStorageProviders...); err != nil {
+		return nil, err
+	}
+
+	s.AddPostStartHookOrDie("start-system-namespaces-controller", func(hookContext genericapiserver.PostStartHookContext) error {
+		client, err := kubernetes.NewForConfig(hookContext.LoopbackClientConfig)
+		if err != nil {
+			return err
+		}
+		go wait.Until(func() { ensureSystemNamespaces(client) }, time.Minute, hookContext.StopCh)
+		return nil
+	})
+
+	s.AddPostStartHookOrDie("start-cluster-authentication-info-controller", func(hookContext genericapiserver.PostStartHookContext) error {
+		client, err := kubernetes.NewForConfig(hookContext.LoopbackClientConfig)
+		if err != nil {
+			return err
+		}
+		runClusterAuthenticationTrustController(m.ClusterAuthenticationInfo, client, hookContext.StopCh)
+		return nil
+	})
+
+	return s, nil
+}
+
+// newMinimalLegacyAPIGroupInfo builds the storage of the enabled core resources of the minimal profile.
+func newMinimalLegacyAPIGroupInfo(c *controlplane.Config) (*genericapiserver.APIGroupInfo, error) {
+	apiGroupInfo := &genericapiserver.APIGroupInfo{
+		PrioritizedVersions:          legacyscheme.Scheme.PrioritizedVersionsForGroup(""),
+		VersionedResourcesStorageMap: map[string]map[string]rest.Storage{},
+		Scheme:                       legacyscheme.Scheme,
+		ParameterCodec:               legacyscheme.ParameterCodec,
+		NegotiatedSerializer:         legacyscheme.Codecs,
+	}
+	restOptionsGetter := c.GenericConfig.RESTOptionsGetter
+	enabled := func(resource string) bool {
+		return c.ExtraConfig.APIResourceConfigSource.ResourceEnabled(corev1.SchemeGroupVersion.WithResource(resource))
+	}
+
+	storage := map[string]rest.Storage{}
+	if enabled("namespaces") {
+		namespaceStorage, namespaceStatusStorage, namespaceFinalizeStorage, err := namespacestore.NewREST(restOptionsGetter)
+		if err != nil {
+			return nil, err
+		}
+		storage["namespaces"] = namespaceStorage
+		storage["namespaces/status"] = namespaceStatusStorage
+		storage["namespaces/finalize"] = namespaceFinalizeStorage
+	}
+
+	secretStorage, err := secretstore.NewREST(restOptionsGetter)
+	if err != nil {
+		return nil, err
+	}
+	if enabled("secrets") {
+		storage["secrets"] = secretStorage
+	}
+
+	if enabled("configmaps") {
+		configMapStorage, err := configmapstore.NewREST(restOptionsGetter)
+		if err != nil {
+			return nil, err
+		}
+		storage["configmaps"] = configMapStorage
+	}
+
+	if enabled("events") {
+		eventStorage, err := eventstore.NewREST(restOptionsGetter, uint64(c.ExtraConfig.EventTTL.Seconds()))
+		if err != nil {
+			return nil, err
+		}
+		storage["events"] = eventStorage
+	}
+
+	if enabled("serviceaccounts") {
+		var serviceAccountStorage *serviceaccountstore.REST
+		if c.ExtraConfig.ServiceAccountIssuer != nil {
+			// the pods are not served, their storage is only used to look up the objects tokens are bound to
+			podStorage, err := podstore.NewStorage(restOptionsGetter, nil, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			serviceAccountStorage, err = serviceaccountstore.NewREST(restOptionsGetter, c.ExtraConfig.ServiceAccountIssuer,
+				c.GenericConfig.Authentication.APIAudiences, c.ExtraConfig.ServiceAccountMaxExpiration,
+				podStorage.Pod.Store, secretStorage.Store, c.ExtraConfig.ExtendExpiration)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			serviceAccountStorage, err = serviceaccountstore.NewREST(restOptionsGetter, nil, nil, 0, nil, nil, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		storage["serviceaccounts"] = serviceAccountStorage
+		if serviceAccountStorage.Token != nil {
+			storage["serviceaccounts/token"] = serviceAccountStorage.Token
+		}
+	}
+
+	if len(storage) > 0 {
+		apiGroupInfo.VersionedResourcesStorageMap["v1"] = storage
+	}
+	return apiGroupInfo, nil
+}
+
+func ensureSystemNamespaces(client kubernetes.Interface) {
+	for _, name := range systemNamespaces {
+		_, err := client.CoreV1().Namespaces().Create(context.TODO(),
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, metav1.CreateOptions{})
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			klog.Errorf("Failed to create the system namespace %s: %v", name, err)
+		}
+	}
+}
+
+// runClusterAuthenticationTrustController publishes the client CAs to kube-system, like the kube-apiserver does,
+// so the aggregated API servers can authenticate the requests proxied to them.
+func runClusterAuthenticationTrustController(info clusterauthenticationtrust.ClusterAuthenticationInfo,
+	client kubernetes.Interface, stopCh <-chan struct{}) {
+	controller := clusterauthenticationtrust.NewClusterAuthenticationTrustController(info, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	for _, provider := range []dynamiccertificates.CAContentProvider{info.ClientCA, info.RequestHeaderCA} {
+		if provider == nil {
+			continue
+		}
+		provider.AddListener(controller)
+		if runner, ok := provider.(dynamiccertificates.ControllerRunner); ok {
+			// runonce to be sure that we have a value.
+			if err := runner.RunOnce(ctx); err != nil {
+				runtime.HandleError(err)
+			}
+			go runner.Run(ctx, 1)
+		}
+	}
+
+	go controller.Run(ctx, 1)
+}
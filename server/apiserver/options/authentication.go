@@ -34,6 +34,8 @@ import (
 	genericoptions "k8s.io/apiserver/pkg/server/options"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
 	openapicommon "k8s.io/kube-openapi/pkg/common"
@@ -85,8 +87,10 @@ type OIDCAuthenticationOptions struct {
 
 // ServiceAccountAuthenticationOptions contains service account authentication options for API Server
 type ServiceAccountAuthenticationOptions struct {
-	KeyFiles         []string
-	Lookup           bool
+	KeyFiles []string
+	Lookup   bool
+	// NoPodInformer looks the pods of the bound tokens up without an informer, for the API profiles which don't serve pods
+	NoPodInformer    bool
 	Issuers          []string
 	JWKSURI          string
 	MaxExpiration    time.Duration
@@ -484,11 +488,18 @@ func (o *BuiltInAuthenticationOptions) ApplyTo(authInfo *genericapiserver.Authen
 		authInfo.APIAudiences = authenticator.Audiences(o.ServiceAccounts.Issuers)
 	}
 
+	var podLister corev1listers.PodLister
+	if o.ServiceAccounts != nil && o.ServiceAccounts.NoPodInformer {
+		// an empty lister, the getter falls back to the client
+		podLister = corev1listers.NewPodLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}))
+	} else {
+		podLister = versionedInformer.Core().V1().Pods().Lister()
+	}
 	authenticatorConfig.ServiceAccountTokenGetter = serviceaccountcontroller.NewGetterFromClient(
 		extclient,
 		versionedInformer.Core().V1().Secrets().Lister(),
 		versionedInformer.Core().V1().ServiceAccounts().Lister(),
-		versionedInformer.Core().V1().Pods().Lister(),
+		podLister,
 	)
 
 	authenticatorConfig.BootstrapTokenAuthenticator = bootstrap.NewTokenAuthenticator(
@@ -0,0 +1,46 @@
+package options
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+// Broker configures the NATS broker the syncers using the broker transport exchange the spec and the status with.
+type Broker struct {
+	URL          string
+	User         string
+	PasswordFile string
+	CAFile       string
+}
+
+func NewBroker() *Broker {
+	return &Broker{User: "global-hub"}
+}
+
+func (b *Broker) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&b.URL, "broker-url", b.URL, "The NATS broker, e.g. tls://broker:4222, to send the spec "+
+		"to the syncers using the broker transport and to receive their status. The syncers only use the global hub "+
+		"apiserver if not set.")
+	fs.StringVar(&b.User, "broker-user", b.User, "The user of the global hub on the broker, allowed to publish the "+
+		"spec and to receive the status of all the regional hubs.")
+	fs.StringVar(&b.PasswordFile, "broker-password-file", b.PasswordFile, "The file of the password of --broker-user.")
+	fs.StringVar(&b.CAFile, "broker-ca-file", b.CAFile, "The CA verifying the certificate of the broker, the "+
+		"connection to the broker always uses TLS.")
+}
+
+func (b *Broker) Validate() []error {
+	var errs []error
+
+	if len(b.URL) == 0 {
+		return nil
+	}
+	if len(b.User) == 0 || len(b.PasswordFile) == 0 {
+		errs = append(errs, fmt.Errorf("--broker-user and --broker-password-file are required by --broker-url"))
+	}
+	if len(b.CAFile) == 0 {
+		errs = append(errs, fmt.Errorf("--broker-ca-file is required by --broker-url"))
+	}
+
+	return errs
+}
@@ -0,0 +1,45 @@
+package options
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// ComplianceHistory configures the history of the compliance changes of the global policies.
+type ComplianceHistory struct {
+	File       string
+	Retention  time.Duration
+	MaxRecords int
+}
+
+func NewComplianceHistory() *ComplianceHistory {
+	return &ComplianceHistory{
+		Retention:  7 * 24 * time.Hour,
+		MaxRecords: 100000,
+	}
+}
+
+func (c *ComplianceHistory) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&c.File, "compliance-history-file", c.File, "The sqlite database file, e.g. "+
+		"/var/lib/global-hub/compliance-history.db, keeping the compliance changes of the global policies on the "+
+		"regional hubs and the managed clusters. The history is kept in memory if not set.")
+	fs.DurationVar(&c.Retention, "compliance-history-retention", c.Retention, "How long the compliance changes are "+
+		"kept, the current compliance states are always kept. 0 keeps them forever.")
+	fs.IntVar(&c.MaxRecords, "compliance-history-max-records", c.MaxRecords, "The maximum number of compliance "+
+		"changes kept, the oldest are deleted first. 0 doesn't limit them.")
+}
+
+func (c *ComplianceHistory) Validate() []error {
+	var errs []error
+
+	if c.Retention < 0 {
+		errs = append(errs, fmt.Errorf("--compliance-history-retention must not be negative"))
+	}
+	if c.MaxRecords < 0 {
+		errs = append(errs, fmt.Errorf("--compliance-history-max-records must not be negative"))
+	}
+
+	return errs
+}
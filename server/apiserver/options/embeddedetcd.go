@@ -33,7 +33,7 @@ type EmbeddedEtcd struct {
 
 func NewEmbeddedEtcd() *EmbeddedEtcd {
 	return &EmbeddedEtcd{
-		Directory:  ".ocmconfig",
+		Directory:  "/etc/etcd-server",
 		PeerPort:   "2380",
 		ClientPort: "2379",
 	}
@@ -54,6 +54,9 @@ func (e *EmbeddedEtcd) Validate() []error {
 		if e.PeerPort == "" {
 			errs = append(errs, fmt.Errorf("--embedded-etcd-peer-port must be specified"))
 		}
+		if e.Directory == "" {
+			errs = append(errs, fmt.Errorf("--embedded-etcd-directory must be specified"))
+		}
 		if e.ClientPort == "" {
 			errs = append(errs, fmt.Errorf("--embedded-etcd-client-port must be specified"))
 		}
@@ -0,0 +1,38 @@
+package options
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// LocalKMS configures the KMS plugin started along with the apiserver, which encrypts the data encryption keys
+// with a key read from a local file. It is referenced by a kms provider of the --encryption-provider-config.
+type LocalKMS struct {
+	KeyFile  string
+	Endpoint string
+}
+
+func NewLocalKMS() *LocalKMS {
+	return &LocalKMS{
+		Endpoint: "unix:///var/run/global-hub/kms.sock",
+	}
+}
+
+func (k *LocalKMS) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&k.KeyFile, "local-kms-key-file", k.KeyFile, "File holding the base64 encoded key of the local KMS plugin, "+
+		"the plugin is started if set")
+	fs.StringVar(&k.Endpoint, "local-kms-endpoint", k.Endpoint, "Unix socket the local KMS plugin listens on, "+
+		"to be used as the endpoint of a kms provider in the --encryption-provider-config")
+}
+
+func (k *LocalKMS) Validate() []error {
+	var errs []error
+
+	if k.KeyFile != "" && !strings.HasPrefix(k.Endpoint, "unix://") {
+		errs = append(errs, fmt.Errorf("--local-kms-endpoint must start with unix://"))
+	}
+
+	return errs
+}
@@ -18,6 +18,7 @@ package options
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"time"
 
@@ -46,6 +47,9 @@ var DefaultServiceIPCIDR = net.IPNet{IP: netutils.ParseIPSloppy("10.0.0.0"), Mas
 // DefaultEtcdPathPrefix is the default key prefix of etcd for API Server
 const DefaultEtcdPathPrefix = "/registry"
 
+// DefaultServiceAccountIssuer is the default issuer of the service account tokens
+const DefaultServiceAccountIssuer = "https://kubernetes.default.svc"
+
 // ServerRunOptions runs a kubernetes api server.
 type ServerRunOptions struct {
 	GenericServerRunOptions *genericoptions.ServerRunOptions
@@ -89,10 +93,31 @@ type ServerRunOptions struct {
 
 	ShowHiddenMetricsForVersion string
 
-	EmbeddedEtcd  *EmbeddedEtcd
-	ClientKeyFile string
+	// APIProfile selects the kube APIs served next to the custom resources
+	APIProfile string
+
+	// DisableGlobalHubControllers serves the OCM APIs without reconciling them, e.g. for the regional hub of the dev mode
+	DisableGlobalHubControllers bool
+
+	// GRPCSyncAddress is the address of the sync service streaming the spec and the status with the syncers using
+	// the grpc transport
+	GRPCSyncAddress string
+
+	EmbeddedEtcd      *EmbeddedEtcd
+	Datastore         *Datastore
+	LocalKMS          *LocalKMS
+	ComplianceHistory *ComplianceHistory
+	Broker            *Broker
+	ClientKeyFile     string
 }
 
+const (
+	// FullAPIProfile serves all the kube APIs, it needs the cluster networking configuration of a kube-apiserver
+	FullAPIProfile = "full"
+	// MinimalAPIProfile serves only the kube APIs the global hub needs, without the workload and networking APIs
+	MinimalAPIProfile = "minimal"
+)
+
 // NewServerRunOptions creates a new ServerRunOptions object with default parameters
 func NewServerRunOptions() *ServerRunOptions {
 	s := ServerRunOptions{
@@ -111,36 +136,28 @@ func NewServerRunOptions() *ServerRunOptions {
 		Traces:                  genericoptions.NewTracingOptions(),
 
 		EventTTL:                          1 * time.Hour,
-		EndpointReconcilerType:            string(reconcilers.LeaseEndpointReconcilerType),
 		IdentityLeaseDurationSeconds:      3600,
 		IdentityLeaseRenewIntervalSeconds: 10,
-
-		// this is fake config, just to let server start
-		KubeletConfig: kubeletclient.KubeletClientConfig{
-			Port:         10250,
-			ReadOnlyPort: 10255,
-			PreferredAddressTypes: []string{
-				"Hostname",
-				"InternalDNS",
-				"InternalIP",
-				"ExternalDNS",
-				"ExternalIP",
-			},
-			HTTPTimeout: time.Duration(5) * time.Second,
-		},
+		APIProfile:                        MinimalAPIProfile,
 
 		EmbeddedEtcd: NewEmbeddedEtcd(),
+		Datastore:    NewDatastore(),
+		LocalKMS:     NewLocalKMS(),
+
+		ComplianceHistory: NewComplianceHistory(),
+		Broker:            NewBroker(),
 	}
 
 	// Overwrite the default for storage data format.
 	s.Etcd.DefaultStorageMediaType = "application/vnd.kubernetes.protobuf"
+	s.Authentication.ServiceAccounts.Issuers = []string{DefaultServiceAccountIssuer}
 
 	return &s
 }
 
 func (s *ServerRunOptions) Validate(args []string) error {
 	errs := []error{}
-	errs = append(errs, s.Etcd.Validate()...)
+	errs = append(errs, s.validateEtcd()...)
 	errs = append(errs, s.SecureServing.Validate()...)
 	errs = append(errs, s.Authentication.Validate()...)
 	errs = append(errs, s.Authorization.Validate()...)
@@ -150,9 +167,68 @@ func (s *ServerRunOptions) Validate(args []string) error {
 	errs = append(errs, validateTokenRequest(s)...)
 	errs = append(errs, s.Metrics.Validate()...)
 	errs = append(errs, s.EmbeddedEtcd.Validate()...)
+	errs = append(errs, s.Datastore.Validate(s.EmbeddedEtcd)...)
+	errs = append(errs, s.LocalKMS.Validate()...)
+	errs = append(errs, s.ComplianceHistory.Validate()...)
+	errs = append(errs, s.Broker.Validate()...)
+	if s.APIProfile != FullAPIProfile && s.APIProfile != MinimalAPIProfile {
+		errs = append(errs, fmt.Errorf("--api-profile must be %s or %s", FullAPIProfile, MinimalAPIProfile))
+	}
 	return utilerrors.NewAggregate(errs)
 }
 
+// ValidateAPIProfileFlags rejects the flags set explicitly which the selected profile turns off.
+func (s *ServerRunOptions) ValidateAPIProfileFlags(fs *pflag.FlagSet) error {
+	if s.APIProfile == MinimalAPIProfile && fs.Changed("enable-priority-and-fairness") && s.GenericServerRunOptions.EnablePriorityAndFairness {
+		return fmt.Errorf("--enable-priority-and-fairness requires --api-profile=%s", FullAPIProfile)
+	}
+	return nil
+}
+
+// CompleteAPIProfile sets the defaults of the selected profile. The minimal profile turns off the features relying
+// on APIs it doesn't serve, the full profile gets the kubelet and endpoint reconciler configuration it requires.
+func (s *ServerRunOptions) CompleteAPIProfile() error {
+	switch s.APIProfile {
+	case MinimalAPIProfile:
+		// the flowcontrol, admissionregistration, resourcequota and pod APIs are not served by the minimal profile
+		s.GenericServerRunOptions.EnablePriorityAndFairness = false
+		s.Admission.GenericAdmission.DefaultOffPlugins = s.Admission.GenericAdmission.DefaultOffPlugins.Union(MinimalProfileOffAdmissionPlugins())
+		s.Authentication.ServiceAccounts.NoPodInformer = true
+	case FullAPIProfile:
+		if s.EndpointReconcilerType == "" {
+			s.EndpointReconcilerType = string(reconcilers.LeaseEndpointReconcilerType)
+		}
+		// there are no nodes to connect to, the kubelet client only has to be valid
+		if s.KubeletConfig.Port == 0 {
+			s.KubeletConfig = kubeletclient.KubeletClientConfig{
+				Port:         10250,
+				ReadOnlyPort: 10255,
+				PreferredAddressTypes: []string{
+					"Hostname",
+					"InternalDNS",
+					"InternalIP",
+					"ExternalDNS",
+					"ExternalIP",
+				},
+				HTTPTimeout: time.Duration(5) * time.Second,
+			}
+		}
+	default:
+		return fmt.Errorf("--api-profile must be %s or %s", FullAPIProfile, MinimalAPIProfile)
+	}
+	return nil
+}
+
+// validateEtcd validates the etcd options, the servers of the embedded etcd and of the datastore are only set when they
+// start.
+func (s *ServerRunOptions) validateEtcd() []error {
+	etcd := *s.Etcd
+	if len(etcd.StorageConfig.Transport.ServerList) == 0 && (s.EmbeddedEtcd.Enabled || s.Datastore.Endpoint != "") {
+		etcd.StorageConfig.Transport.ServerList = []string{"embedded"}
+	}
+	return etcd.Validate()
+}
+
 func validateTokenRequest(options *ServerRunOptions) []error {
 	var errs []error
 
@@ -180,12 +256,27 @@ func (e *ServerRunOptions) AddFlags(fs *pflag.FlagSet) {
 	e.Authorization.AddFlags(fs)
 	e.Admission.AddFlags(fs)
 	e.GenericServerRunOptions.AddUniversalFlags(fs)
+	if flag := fs.Lookup("enable-priority-and-fairness"); flag != nil {
+		flag.Usage += " Only supported by --api-profile=full, the minimal profile doesn't serve the flowcontrol API."
+	}
 	e.EmbeddedEtcd.AddFlags(fs)
+	e.Datastore.AddFlags(fs)
+	e.LocalKMS.AddFlags(fs)
+	e.ComplianceHistory.AddFlags(fs)
+	e.Broker.AddFlags(fs)
 
 	fs.StringVar(&e.ClientKeyFile, "client-key-file", e.ClientKeyFile, "client cert key file")
+	fs.StringVar(&e.GRPCSyncAddress, "grpc-sync-address", e.GRPCSyncAddress, "The address, e.g. 0.0.0.0:9443, of the "+
+		"gRPC sync service streaming the spec to the syncers using the grpc transport and receiving their status. The "+
+		"streams resume from the last acknowledged generation when the syncers reconnect. Disabled if not set.")
+	fs.StringVar(&e.APIProfile, "api-profile", e.APIProfile, "The kube APIs to serve. "+
+		"'minimal' serves only namespaces, secrets, configmaps, serviceaccounts, events, RBAC, coordination and "+
+		"certificates next to the custom resources, 'full' serves all of them like a kube-apiserver. "+
+		"The minimal profile turns off the priority and fairness, --enable-priority-and-fairness requires 'full'.")
 	fs.StringVar(&e.ServiceAccountSigningKeyFile, "service-account-signing-key-file", e.ServiceAccountSigningKeyFile, ""+
 		"Path to the file that contains the current private key of the service account token issuer. The issuer will sign issued ID tokens with this private key.")
 	fs.StringVar(&e.ServiceClusterIPRanges, "service-cluster-ip-range", e.ServiceClusterIPRanges, ""+
 		"A CIDR notation IP range from which to assign service cluster IPs. This must not "+
-		"overlap with any IP ranges assigned to nodes or pods. Max of two dual-stack CIDRs is allowed.")
+		"overlap with any IP ranges assigned to nodes or pods. Max of two dual-stack CIDRs is allowed. "+
+		"Only used by the full API profile.")
 }
@@ -21,6 +21,8 @@ import (
 	"k8s.io/apiserver/pkg/admission/plugin/resourcequota"
 	mutatingwebhook "k8s.io/apiserver/pkg/admission/plugin/webhook/mutating"
 	validatingwebhook "k8s.io/apiserver/pkg/admission/plugin/webhook/validating"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver/admission/policyvalidation"
 )
 
 // AllOrderedPlugins is the list of all the plugins in order.
@@ -34,6 +36,7 @@ var AllOrderedPlugins = []string{
 	certapproval.PluginName,           // CertificateApproval
 	certsigning.PluginName,            // CertificateSigning
 	certsubjectrestriction.PluginName, // CertificateSubjectRestriction
+	policyvalidation.PluginName,       // GlobalHubPolicyValidation
 
 	// new admission plugins should generally be inserted above here
 	// webhook, resourcequota, and deny plugins must go at the end
@@ -55,6 +58,7 @@ func RegisterAllAdmissionPlugins(plugins *admission.Plugins) {
 	certapproval.Register(plugins)
 	certsigning.Register(plugins)
 	certsubjectrestriction.Register(plugins)
+	policyvalidation.Register(plugins)
 }
 
 // DefaultOffAdmissionPlugins get admission plugins off by default for kube-apiserver.
@@ -68,7 +72,18 @@ func DefaultOffAdmissionPlugins() sets.String {
 		certapproval.PluginName,           // CertificateApproval
 		certsigning.PluginName,            // CertificateSigning
 		certsubjectrestriction.PluginName, // CertificateSubjectRestriction
+		policyvalidation.PluginName,       // GlobalHubPolicyValidation
 	)
 
 	return sets.NewString(AllOrderedPlugins...).Difference(defaultOnPlugins)
 }
+
+// MinimalProfileOffAdmissionPlugins are the admission plugins relying on APIs the minimal API profile doesn't serve,
+// they are off by default with that profile.
+func MinimalProfileOffAdmissionPlugins() sets.String {
+	return sets.NewString(
+		mutatingwebhook.PluginName,   // MutatingAdmissionWebhook
+		validatingwebhook.PluginName, // ValidatingAdmissionWebhook
+		resourcequota.PluginName,     // ResourceQuota
+	)
+}
@@ -50,8 +50,12 @@ import (
 	"k8s.io/apiserver/pkg/util/notfoundhandler"
 	"k8s.io/apiserver/pkg/util/openapi"
 	"k8s.io/apiserver/pkg/util/webhook"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	clientgoinformers "k8s.io/client-go/informers"
 	clientgoclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/util/keyutil"
 	"k8s.io/component-base/logs"
 	_ "k8s.io/component-base/metrics/prometheus/workqueue" // for workqueue metric registration
@@ -72,7 +76,10 @@ import (
 	"k8s.io/kubernetes/pkg/serviceaccount"
 	netutils "k8s.io/utils/net"
 
+	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver/admission/policyvalidation"
 	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver/options"
+	"github.com/clyang82/multicluster-global-hub-lite/server/datastore"
+	"github.com/clyang82/multicluster-global-hub-lite/server/encryption"
 	"github.com/clyang82/multicluster-global-hub-lite/server/etcd"
 )
 
@@ -100,7 +107,16 @@ func CreateServerChain(completedOptions CompletedServerRunOptions) (*aggregatora
 		return nil, err
 	}
 
-	kubeAPIServer, err := CreateKubeAPIServer(kubeAPIServerConfig, apiExtensionsServer.GenericAPIServer)
+	var kubeAPIServer *genericapiserver.GenericAPIServer
+	if completedOptions.APIProfile == options.MinimalAPIProfile {
+		kubeAPIServer, err = CreateMinimalAPIServer(kubeAPIServerConfig, apiExtensionsServer.GenericAPIServer)
+	} else {
+		var instance *controlplane.Instance
+		instance, err = CreateKubeAPIServer(kubeAPIServerConfig, apiExtensionsServer.GenericAPIServer)
+		if instance != nil {
+			kubeAPIServer = instance.GenericAPIServer
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -111,9 +127,13 @@ func CreateServerChain(completedOptions CompletedServerRunOptions) (*aggregatora
 		return nil, err
 	}
 	aggregatorServer, err := createAggregatorServer(
-		aggregatorConfig, kubeAPIServer.GenericAPIServer, apiExtensionsServer.Informers,
+		aggregatorConfig, kubeAPIServer, apiExtensionsServer.Informers,
 		completedOptions.Authentication.ClientCert.ClientCA,
 		completedOptions.ClientKeyFile,
+		!completedOptions.DisableGlobalHubControllers,
+		completedOptions.Broker,
+		completedOptions.GRPCSyncAddress,
+		completedOptions.ComplianceHistory,
 	)
 	if err != nil {
 		// we don't need special handling for innerStopCh because the aggregator server doesn't create any go routines
@@ -281,7 +301,11 @@ func buildGenericConfig(
 	if lastErr = s.Features.ApplyTo(genericConfig); lastErr != nil {
 		return
 	}
-	if lastErr = s.APIEnablement.ApplyTo(genericConfig, APIResourceConfigSource(), legacyscheme.Scheme); lastErr != nil {
+	resourceConfig := APIResourceConfigSource()
+	if s.APIProfile == options.MinimalAPIProfile {
+		resourceConfig = MinimalAPIResourceConfigSource()
+	}
+	if lastErr = s.APIEnablement.ApplyTo(genericConfig, resourceConfig, legacyscheme.Scheme); lastErr != nil {
 		return
 	}
 	if lastErr = s.EgressSelector.ApplyTo(genericConfig); lastErr != nil {
@@ -370,12 +394,25 @@ func buildGenericConfig(
 		ExternalInformers:    versionedInformers,
 		LoopbackClientConfig: genericConfig.LoopbackClientConfig,
 	}
-	serviceResolver = buildServiceResolver(s.EnableAggregatorRouting, genericConfig.LoopbackClientConfig.Host, versionedInformers)
+	serviceResolver = buildServiceResolver(s.EnableAggregatorRouting, allocatesServiceIPs(s), genericConfig.LoopbackClientConfig.Host, versionedInformers)
 	pluginInitializers, admissionPostStartHook, err = admissionConfig.New(proxyTransport, genericConfig.EgressSelector, serviceResolver, genericConfig.TracerProvider)
 	if err != nil {
 		lastErr = fmt.Errorf("failed to create admission plugin initializer: %v", err)
 		return
 	}
+	// the global hub plugins look up custom resources, which the typed clientset can't serve
+	dynamicClient, err := dynamic.NewForConfig(kubeClientConfig)
+	if err != nil {
+		lastErr = fmt.Errorf("failed to create dynamic client: %v", err)
+		return
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(kubeClientConfig)
+	if err != nil {
+		lastErr = fmt.Errorf("failed to create discovery client: %v", err)
+		return
+	}
+	restMapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+	pluginInitializers = append(pluginInitializers, policyvalidation.NewPluginInitializer(dynamicClient, restMapper))
 
 	err = s.Admission.ApplyTo(
 		genericConfig,
@@ -432,7 +469,7 @@ func (c *CompletedServerRunOptions) Run(ctx context.Context) error {
 	// set etcd to embeddedetcd info
 	if c.EmbeddedEtcd != nil && c.EmbeddedEtcd.Enabled {
 
-		embeddedClientInfo, err := etcd.Run(ctx, c.EmbeddedEtcd.PeerPort, c.EmbeddedEtcd.ClientPort)
+		embeddedClientInfo, err := etcd.Run(ctx, c.EmbeddedEtcd.Directory, c.EmbeddedEtcd.PeerPort, c.EmbeddedEtcd.ClientPort)
 		if err != nil {
 			return err
 		}
@@ -443,6 +480,30 @@ func (c *CompletedServerRunOptions) Run(ctx context.Context) error {
 		c.ServerRunOptions.Etcd.StorageConfig.Transport.TrustedCAFile = embeddedClientInfo.TrustedCAFile
 	}
 
+	// set etcd to the etcd API served by the SQL datastore
+	if c.Datastore != nil && c.Datastore.Endpoint != "" {
+		datastoreClientInfo, err := datastore.Listen(ctx, c.Datastore.Endpoint)
+		if err != nil {
+			return err
+		}
+
+		c.ServerRunOptions.Etcd.StorageConfig.Transport.ServerList = datastoreClientInfo.Endpoints
+		c.ServerRunOptions.Etcd.StorageConfig.Transport.KeyFile = ""
+		c.ServerRunOptions.Etcd.StorageConfig.Transport.CertFile = ""
+		c.ServerRunOptions.Etcd.StorageConfig.Transport.TrustedCAFile = ""
+	}
+
+	// start the local KMS plugin referenced by the encryption provider config
+	if c.LocalKMS != nil && c.LocalKMS.KeyFile != "" {
+		service, err := encryption.NewLocalService(c.LocalKMS.KeyFile)
+		if err != nil {
+			return err
+		}
+		if err := encryption.ServeKMSPlugin(ctx, c.LocalKMS.Endpoint, service); err != nil {
+			return err
+		}
+	}
+
 	// to generate self-signed certificates
 	if err := c.ServerRunOptions.SecureServing.MaybeDefaultWithSelfSignedCerts("localhost", nil, []net.IP{netutils.ParseIPSloppy("127.0.0.1")}); err != nil {
 		return fmt.Errorf("error creating self-signed certificates: %v", err)
@@ -475,17 +536,26 @@ func Complete(s *options.ServerRunOptions) (CompletedServerRunOptions, error) {
 		return options, err
 	}
 
-	// process s.ServiceClusterIPRange from list to Primary and Secondary
-	// we process secondary only if provided by user
-	apiServerServiceIP, primaryServiceIPRange, secondaryServiceIPRange, err := getServiceIPAndRanges(s.ServiceClusterIPRanges)
-	if err != nil {
+	if err := s.CompleteAPIProfile(); err != nil {
 		return options, err
 	}
-	s.PrimaryServiceClusterIPRange = primaryServiceIPRange
-	s.SecondaryServiceClusterIPRange = secondaryServiceIPRange
-	s.APIServerServiceIP = apiServerServiceIP
 
-	if err := s.SecureServing.MaybeDefaultWithSelfSignedCerts(s.GenericServerRunOptions.AdvertiseAddress.String(), []string{"kubernetes.default.svc", "kubernetes.default", "kubernetes"}, []net.IP{apiServerServiceIP}); err != nil {
+	// only the full profile allocates service IPs, including the one of the kubernetes service
+	var alternateIPs []net.IP
+	if allocatesServiceIPs(s) {
+		// process s.ServiceClusterIPRange from list to Primary and Secondary
+		// we process secondary only if provided by user
+		apiServerServiceIP, primaryServiceIPRange, secondaryServiceIPRange, err := getServiceIPAndRanges(s.ServiceClusterIPRanges)
+		if err != nil {
+			return options, err
+		}
+		s.PrimaryServiceClusterIPRange = primaryServiceIPRange
+		s.SecondaryServiceClusterIPRange = secondaryServiceIPRange
+		s.APIServerServiceIP = apiServerServiceIP
+		alternateIPs = append(alternateIPs, apiServerServiceIP)
+	}
+
+	if err := s.SecureServing.MaybeDefaultWithSelfSignedCerts(s.GenericServerRunOptions.AdvertiseAddress.String(), []string{"kubernetes.default.svc", "kubernetes.default", "kubernetes"}, alternateIPs); err != nil {
 		return options, fmt.Errorf("error creating self-signed certificates: %v", err)
 	}
 
@@ -518,6 +588,9 @@ func Complete(s *options.ServerRunOptions) (CompletedServerRunOptions, error) {
 				klog.Warning("No TLS key provided, service account token authentication disabled")
 			}
 		}
+	} else if len(s.Authentication.ServiceAccounts.KeyFiles) == 0 {
+		// verify the issued tokens with the signing key
+		s.Authentication.ServiceAccounts.KeyFiles = []string{s.ServiceAccountSigningKeyFile}
 	}
 
 	if s.ServiceAccountSigningKeyFile != "" && len(s.Authentication.ServiceAccounts.Issuers) != 0 && s.Authentication.ServiceAccounts.Issuers[0] != "" {
@@ -580,9 +653,17 @@ func Complete(s *options.ServerRunOptions) (CompletedServerRunOptions, error) {
 	return options, nil
 }
 
-func buildServiceResolver(enabledAggregatorRouting bool, hostname string, informer clientgoinformers.SharedInformerFactory) webhook.ServiceResolver {
+// allocatesServiceIPs is true for the API profiles serving the services.
+func allocatesServiceIPs(s *options.ServerRunOptions) bool {
+	return s.APIProfile == options.FullAPIProfile
+}
+
+func buildServiceResolver(enabledAggregatorRouting, servesServices bool, hostname string, informer clientgoinformers.SharedInformerFactory) webhook.ServiceResolver {
 	var serviceResolver webhook.ServiceResolver
-	if enabledAggregatorRouting {
+	if !servesServices {
+		// don't start the service informers, they would never sync
+		serviceResolver = noServiceResolver{}
+	} else if enabledAggregatorRouting {
 		serviceResolver = aggregatorapiserver.NewEndpointServiceResolver(
 			informer.Core().V1().Services().Lister(),
 			informer.Core().V1().Endpoints().Lister(),
@@ -599,6 +680,13 @@ func buildServiceResolver(enabledAggregatorRouting bool, hostname string, inform
 	return serviceResolver
 }
 
+// noServiceResolver resolves no service, for the API profiles which don't serve services.
+type noServiceResolver struct{}
+
+func (noServiceResolver) ResolveEndpoint(namespace, name string, port int32) (*url.URL, error) {
+	return nil, fmt.Errorf("cannot resolve service %s/%s, services are not served", namespace, name)
+}
+
 func getServiceIPAndRanges(serviceClusterIPRanges string) (net.IP, net.IPNet, net.IPNet, error) {
 	serviceClusterIPRangeList := []string{}
 	if serviceClusterIPRanges != "" {
@@ -0,0 +1,37 @@
+package views
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	openapinamer "k8s.io/apiserver/pkg/endpoints/openapi"
+	openapicommon "k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+
+	viewv1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/view/v1alpha1"
+)
+
+// OpenAPIConfig returns a copy of the OpenAPI config of an apiserver with the definitions of the views, the field
+// manager of the searches needs them.
+func OpenAPIConfig(config *openapicommon.Config) *openapicommon.Config {
+	// the namer of the views ignores their internal version
+	versionedScheme := runtime.NewScheme()
+	utilruntime.Must(viewv1alpha1.AddToScheme(versionedScheme))
+	namer := openapinamer.NewDefinitionNamer(versionedScheme)
+
+	viewsConfig := *config
+	getDefinitions, getDefinitionName := config.GetDefinitions, config.GetDefinitionName
+	viewsConfig.GetDefinitions = func(ref openapicommon.ReferenceCallback) map[string]openapicommon.OpenAPIDefinition {
+		definitions := getDefinitions(ref)
+		for name, definition := range viewv1alpha1.GetOpenAPIDefinitions(ref) {
+			definitions[name] = definition
+		}
+		return definitions
+	}
+	viewsConfig.GetDefinitionName = func(name string) (string, spec.Extensions) {
+		if definitionName, extensions := namer.GetDefinitionName(name); len(extensions) > 0 {
+			return definitionName, extensions
+		}
+		return getDefinitionName(name)
+	}
+	return &viewsConfig
+}
@@ -0,0 +1,291 @@
+package views
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	viewv1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/view/v1alpha1"
+)
+
+// the annotations of the policies holding their standards, categories and controls, comma separated
+const (
+	standardsAnnotation  = "policy.open-cluster-management.io/standards"
+	categoriesAnnotation = "policy.open-cluster-management.io/categories"
+	controlsAnnotation   = "policy.open-cluster-management.io/controls"
+)
+
+// complianceReport computes the report of the compliance of every global policy.
+func (v *Views) complianceReport(report string) (*viewv1alpha1.ComplianceReport, error) {
+	objs, err := v.policies.Lister().List(everything)
+	if err != nil {
+		return nil, err
+	}
+	globalPolicies := []*unstructured.Unstructured{}
+	for _, obj := range objs {
+		if policy := obj.(*unstructured.Unstructured); isGlobalPolicy(policy) {
+			globalPolicies = append(globalPolicies, policy)
+		}
+	}
+	sort.Slice(globalPolicies, func(i, j int) bool {
+		if globalPolicies[i].GetNamespace() != globalPolicies[j].GetNamespace() {
+			return globalPolicies[i].GetNamespace() < globalPolicies[j].GetNamespace()
+		}
+		return globalPolicies[i].GetName() < globalPolicies[j].GetName()
+	})
+
+	complianceReport := &viewv1alpha1.ComplianceReport{
+		ObjectMeta:  metav1.ObjectMeta{Name: report},
+		GeneratedAt: metav1.Now(),
+		Entries:     []viewv1alpha1.ComplianceReportEntry{},
+	}
+	for _, policy := range globalPolicies {
+		fleetPolicy, copies, err := v.fleetPolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+		annotations := policy.GetAnnotations()
+		policyEntry := viewv1alpha1.ComplianceReportEntry{
+			Namespace:  policy.GetNamespace(),
+			Policy:     policy.GetName(),
+			Standards:  splitAnnotation(annotations[standardsAnnotation]),
+			Categories: splitAnnotation(annotations[categoriesAnnotation]),
+			Controls:   splitAnnotation(annotations[controlsAnnotation]),
+		}
+
+		switch report {
+		case viewv1alpha1.PolicyReport:
+			entry := policyEntry
+			entry.ComplianceState = fleetPolicy.Status.ComplianceState
+			if len(entry.ComplianceState) == 0 {
+				entry.ComplianceState = pending
+			}
+			for _, hub := range fleetPolicy.Status.Hubs {
+				entry.Compliant += hub.Compliant
+				entry.NonCompliant += hub.NonCompliant
+				entry.Pending += hub.Pending
+			}
+			complianceReport.Entries = append(complianceReport.Entries, entry)
+		case viewv1alpha1.HubReport:
+			for _, hub := range fleetPolicy.Status.Hubs {
+				entry := policyEntry
+				entry.Hub = hub.Hub
+				entry.ComplianceState = hub.ComplianceState
+				entry.Compliant = hub.Compliant
+				entry.NonCompliant = hub.NonCompliant
+				entry.Pending = hub.Pending
+				complianceReport.Entries = append(complianceReport.Entries, entry)
+			}
+		case viewv1alpha1.ClusterReport:
+			entries := []viewv1alpha1.ComplianceReportEntry{}
+			for _, policyCopy := range copies {
+				for _, cluster := range policyCopy.Status.Status {
+					if cluster == nil {
+						continue
+					}
+					entry := policyEntry
+					entry.Hub = policyCopy.Namespace
+					entry.Cluster = cluster.ClusterName
+					entry.ComplianceState = clusterCompliance(cluster)
+					entries = append(entries, entry)
+				}
+			}
+			sort.Slice(entries, func(i, j int) bool {
+				if entries[i].Hub != entries[j].Hub {
+					return entries[i].Hub < entries[j].Hub
+				}
+				return entries[i].Cluster < entries[j].Cluster
+			})
+			complianceReport.Entries = append(complianceReport.Entries, entries...)
+		}
+	}
+	return complianceReport, nil
+}
+
+func splitAnnotation(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// renderCSV writes a row per entry, the hub and the cluster columns are only in the reports they apply to, and the
+// counts of the managed clusters are not in the cluster report.
+func renderCSV(report *viewv1alpha1.ComplianceReport) ([]byte, error) {
+	header := []string{"namespace", "policy"}
+	switch report.Name {
+	case viewv1alpha1.HubReport:
+		header = append(header, "hub")
+	case viewv1alpha1.ClusterReport:
+		header = append(header, "hub", "cluster")
+	}
+	header = append(header, "standards", "categories", "controls", "complianceState")
+	if report.Name != viewv1alpha1.ClusterReport {
+		header = append(header, "compliant", "nonCompliant", "pending")
+	}
+
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for _, entry := range report.Entries {
+		record := []string{entry.Namespace, entry.Policy}
+		switch report.Name {
+		case viewv1alpha1.HubReport:
+			record = append(record, entry.Hub)
+		case viewv1alpha1.ClusterReport:
+			record = append(record, entry.Hub, entry.Cluster)
+		}
+		record = append(record, strings.Join(entry.Standards, ","), strings.Join(entry.Categories, ","),
+			strings.Join(entry.Controls, ","), entry.ComplianceState)
+		if report.Name != viewv1alpha1.ClusterReport {
+			record = append(record, strconv.Itoa(int(entry.Compliant)), strconv.Itoa(int(entry.NonCompliant)),
+				strconv.Itoa(int(entry.Pending)))
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// renderJUnit writes a test suite per global policy with a test case per entry, the non compliant entries fail and
+// the pending ones are skipped. The standards, categories and controls are the properties of the suites.
+func renderJUnit(report *viewv1alpha1.ComplianceReport) ([]byte, error) {
+	suites := junitTestSuites{Name: "compliance-" + report.Name}
+	timestamp := report.GeneratedAt.UTC().Format("2006-01-02T15:04:05")
+	for _, entry := range report.Entries {
+		name := entry.Namespace + "/" + entry.Policy
+		if len(suites.Suites) == 0 || suites.Suites[len(suites.Suites)-1].Name != name {
+			suite := junitTestSuite{Name: name, Timestamp: timestamp}
+			for _, property := range []struct {
+				name   string
+				values []string
+			}{{"standards", entry.Standards}, {"categories", entry.Categories}, {"controls", entry.Controls}} {
+				if len(property.values) > 0 {
+					suite.Properties = append(suite.Properties, junitProperty{Name: property.name, Value: strings.Join(property.values, ",")})
+				}
+			}
+			suites.Suites = append(suites.Suites, suite)
+		}
+		suite := &suites.Suites[len(suites.Suites)-1]
+
+		testCase := junitTestCase{Name: entry.Policy, ClassName: entry.Namespace + "." + entry.Policy}
+		switch {
+		case len(entry.Cluster) > 0:
+			testCase.Name = entry.Hub + "/" + entry.Cluster
+		case len(entry.Hub) > 0:
+			testCase.Name = entry.Hub
+		}
+		switch entry.ComplianceState {
+		case compliant:
+		case nonCompliant:
+			testCase.Failure = &junitMessage{Message: nonCompliant}
+			suite.Failures++
+			suites.Failures++
+		default:
+			testCase.Skipped = &junitMessage{Message: entry.ComplianceState}
+			suite.Skipped++
+			suites.Skipped++
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Tests++
+		suites.Tests++
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// reportStream streams a compliance report rendered as csv or junit instead of encoding it.
+type reportStream struct {
+	data        []byte
+	contentType string
+}
+
+var _ rest.ResourceStreamer = &reportStream{}
+
+func (s *reportStream) GetObjectKind() schema.ObjectKind {
+	return schema.EmptyObjectKind
+}
+
+func (s *reportStream) DeepCopyObject() runtime.Object {
+	return &reportStream{data: append([]byte(nil), s.data...), contentType: s.contentType}
+}
+
+func (s *reportStream) InputStream(ctx context.Context, apiVersion, acceptHeader string) (io.ReadCloser, bool, string, error) {
+	return io.NopCloser(bytes.NewReader(s.data)), false, s.contentType, nil
+}
+
+// renderReport returns the report in the format, as is for json.
+func renderReport(report *viewv1alpha1.ComplianceReport, format string) (runtime.Object, error) {
+	switch format {
+	case "", viewv1alpha1.JSONReportFormat:
+		return report, nil
+	case viewv1alpha1.CSVReportFormat:
+		data, err := renderCSV(report)
+		return &reportStream{data: data, contentType: "text/csv"}, err
+	case viewv1alpha1.JUnitReportFormat:
+		data, err := renderJUnit(report)
+		return &reportStream{data: data, contentType: "application/xml"}, err
+	}
+	return nil, fmt.Errorf("unsupported report format %q", format)
+}
@@ -0,0 +1,483 @@
+package views
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/sets"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	"github.com/clyang82/multicluster-global-hub-lite/apis"
+	viewv1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/view/v1alpha1"
+	"github.com/clyang82/multicluster-global-hub-lite/server/compliancehistory"
+)
+
+var everything = labels.Everything()
+
+// hubREST serves the regional hubs.
+type hubREST struct {
+	views *Views
+}
+
+var (
+	_ rest.Getter = &hubREST{}
+	_ rest.Lister = &hubREST{}
+	_ rest.Scoper = &hubREST{}
+)
+
+func (r *hubREST) New() runtime.Object {
+	return &viewv1alpha1.Hub{}
+}
+
+func (r *hubREST) NewList() runtime.Object {
+	return &viewv1alpha1.HubList{}
+}
+
+func (r *hubREST) NamespaceScoped() bool {
+	return false
+}
+
+func (r *hubREST) Get(ctx context.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+	if !r.views.synced() {
+		return nil, errNotSynced()
+	}
+	obj, err := r.views.hubs.Lister().Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil, apierrors.NewNotFound(viewv1alpha1.GroupVersion.WithResource("hubs").GroupResource(), name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.views.hub(obj)
+}
+
+func (r *hubREST) List(ctx context.Context, options *metainternalversion.ListOptions) (runtime.Object, error) {
+	if !r.views.synced() {
+		return nil, errNotSynced()
+	}
+	objs, err := r.views.hubs.Lister().List(labelSelector(options))
+	if err != nil {
+		return nil, err
+	}
+	list := &viewv1alpha1.HubList{Items: []viewv1alpha1.Hub{}}
+	for _, obj := range objs {
+		hub, err := r.views.hub(obj)
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, *hub)
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Name < list.Items[j].Name })
+	return list, nil
+}
+
+func (r *hubREST) ConvertToTable(ctx context.Context, object runtime.Object, tableOptions runtime.Object) (*metav1.Table, error) {
+	hubs := []viewv1alpha1.Hub{}
+	switch t := object.(type) {
+	case *viewv1alpha1.Hub:
+		hubs = append(hubs, *t)
+	case *viewv1alpha1.HubList:
+		hubs = t.Items
+	default:
+		return nil, fmt.Errorf("unexpected object %T", object)
+	}
+	table := &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Name", Type: "string", Format: "name"},
+			{Name: "Endpoint", Type: "string"},
+			{Name: "Available", Type: "string", Description: "The available managed clusters of the regional hub"},
+			{Name: "NonCompliant", Type: "integer", Description: "The policies not compliant on the regional hub"},
+			{Name: "Age", Type: "string"},
+		},
+	}
+	for i := range hubs {
+		hub := &hubs[i]
+		table.Rows = append(table.Rows, metav1.TableRow{
+			Cells: []interface{}{hub.Name, hub.Status.Endpoint,
+				fmt.Sprintf("%d/%d", hub.Status.ManagedClusters.Available, hub.Status.ManagedClusters.Total),
+				hub.Status.Policies.NonCompliant, age(hub.CreationTimestamp)},
+			Object: runtime.RawExtension{Object: hub},
+		})
+	}
+	return table, nil
+}
+
+// hubManagedClustersREST serves the managed clusters of a regional hub.
+type hubManagedClustersREST struct {
+	views *Views
+}
+
+var _ rest.Getter = &hubManagedClustersREST{}
+
+func (r *hubManagedClustersREST) New() runtime.Object {
+	return &viewv1alpha1.HubManagedClusters{}
+}
+
+func (r *hubManagedClustersREST) NamespaceScoped() bool {
+	return false
+}
+
+func (r *hubManagedClustersREST) Get(ctx context.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+	if !r.views.synced() {
+		return nil, errNotSynced()
+	}
+	obj, err := r.views.hubs.Lister().Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil, apierrors.NewNotFound(viewv1alpha1.GroupVersion.WithResource("hubs").GroupResource(), name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	hub := obj.(*unstructured.Unstructured)
+	clusters, err := r.views.managedClusters(name)
+	if err != nil {
+		return nil, err
+	}
+	return &viewv1alpha1.HubManagedClusters{
+		ObjectMeta: metav1.ObjectMeta{Name: hub.GetName(), UID: hub.GetUID(), CreationTimestamp: hub.GetCreationTimestamp()},
+		Items:      clusters,
+	}, nil
+}
+
+// fleetPolicyREST serves the compliance of the global policies across the regional hubs.
+type fleetPolicyREST struct {
+	views *Views
+}
+
+var (
+	_ rest.Getter = &fleetPolicyREST{}
+	_ rest.Lister = &fleetPolicyREST{}
+	_ rest.Scoper = &fleetPolicyREST{}
+)
+
+func (r *fleetPolicyREST) New() runtime.Object {
+	return &viewv1alpha1.FleetPolicy{}
+}
+
+func (r *fleetPolicyREST) NewList() runtime.Object {
+	return &viewv1alpha1.FleetPolicyList{}
+}
+
+func (r *fleetPolicyREST) NamespaceScoped() bool {
+	return true
+}
+
+func (r *fleetPolicyREST) Get(ctx context.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+	obj, err := r.views.globalPolicy(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	fleetPolicy, _, err := r.views.fleetPolicy(obj)
+	return fleetPolicy, err
+}
+
+func (r *fleetPolicyREST) List(ctx context.Context, options *metainternalversion.ListOptions) (runtime.Object, error) {
+	if !r.views.synced() {
+		return nil, errNotSynced()
+	}
+	objs, err := r.views.policies.Lister().ByNamespace(genericapirequest.NamespaceValue(ctx)).List(labelSelector(options))
+	if err != nil {
+		return nil, err
+	}
+	list := &viewv1alpha1.FleetPolicyList{Items: []viewv1alpha1.FleetPolicy{}}
+	for _, obj := range objs {
+		if !isGlobalPolicy(obj.(*unstructured.Unstructured)) {
+			continue
+		}
+		fleetPolicy, _, err := r.views.fleetPolicy(obj)
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, *fleetPolicy)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		if list.Items[i].Namespace != list.Items[j].Namespace {
+			return list.Items[i].Namespace < list.Items[j].Namespace
+		}
+		return list.Items[i].Name < list.Items[j].Name
+	})
+	return list, nil
+}
+
+func (r *fleetPolicyREST) ConvertToTable(ctx context.Context, object runtime.Object, tableOptions runtime.Object) (*metav1.Table, error) {
+	policies := []viewv1alpha1.FleetPolicy{}
+	switch t := object.(type) {
+	case *viewv1alpha1.FleetPolicy:
+		policies = append(policies, *t)
+	case *viewv1alpha1.FleetPolicyList:
+		policies = t.Items
+	default:
+		return nil, fmt.Errorf("unexpected object %T", object)
+	}
+	table := &metav1.Table{
+		ColumnDefinitions: []metav1.TableColumnDefinition{
+			{Name: "Name", Type: "string", Format: "name"},
+			{Name: "Compliance", Type: "string"},
+			{Name: "Hubs", Type: "integer", Description: "The regional hubs of the policy"},
+			{Name: "Age", Type: "string"},
+		},
+	}
+	for i := range policies {
+		policy := &policies[i]
+		table.Rows = append(table.Rows, metav1.TableRow{
+			Cells:  []interface{}{policy.Name, policy.Status.ComplianceState, len(policy.Status.Hubs), age(policy.CreationTimestamp)},
+			Object: runtime.RawExtension{Object: policy},
+		})
+	}
+	return table, nil
+}
+
+// policyComplianceREST serves the compliance of a global policy on every managed cluster.
+type policyComplianceREST struct {
+	views *Views
+}
+
+var _ rest.Getter = &policyComplianceREST{}
+
+func (r *policyComplianceREST) New() runtime.Object {
+	return &viewv1alpha1.PolicyCompliance{}
+}
+
+func (r *policyComplianceREST) NamespaceScoped() bool {
+	return true
+}
+
+func (r *policyComplianceREST) Get(ctx context.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+	obj, err := r.views.globalPolicy(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	fleetPolicy, copies, err := r.views.fleetPolicy(obj)
+	if err != nil {
+		return nil, err
+	}
+	compliance := &viewv1alpha1.PolicyCompliance{
+		ObjectMeta: fleetPolicy.ObjectMeta,
+		Clusters:   []viewv1alpha1.ClusterCompliance{},
+	}
+	for _, policy := range copies {
+		for _, cluster := range policy.Status.Status {
+			if cluster == nil {
+				continue
+			}
+			compliance.Clusters = append(compliance.Clusters, viewv1alpha1.ClusterCompliance{
+				Hub:             policy.Namespace,
+				Cluster:         cluster.ClusterName,
+				ComplianceState: clusterCompliance(cluster),
+			})
+		}
+	}
+	return compliance, nil
+}
+
+// policyHistoryREST serves the compliance changes of a global policy on the regional hubs and the managed clusters.
+type policyHistoryREST struct {
+	views *Views
+}
+
+var _ rest.GetterWithOptions = &policyHistoryREST{}
+
+func (r *policyHistoryREST) New() runtime.Object {
+	return &viewv1alpha1.PolicyHistory{}
+}
+
+func (r *policyHistoryREST) NamespaceScoped() bool {
+	return true
+}
+
+func (r *policyHistoryREST) NewGetOptions() (runtime.Object, bool, string) {
+	return &viewv1alpha1.PolicyHistoryOptions{}, false, ""
+}
+
+func (r *policyHistoryREST) Get(ctx context.Context, name string, options runtime.Object) (runtime.Object, error) {
+	historyOptions, ok := options.(*viewv1alpha1.PolicyHistoryOptions)
+	if !ok {
+		return nil, fmt.Errorf("invalid options object: %#v", options)
+	}
+	if historyOptions.Limit < 0 {
+		return nil, apierrors.NewBadRequest("limit must not be negative")
+	}
+	obj, err := r.views.globalPolicy(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	fleetPolicy, _, err := r.views.fleetPolicy(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	query := compliancehistory.Query{
+		Namespace: fleetPolicy.Namespace,
+		Policy:    fleetPolicy.Name,
+		Hub:       historyOptions.Hub,
+		Cluster:   historyOptions.Cluster,
+		Limit:     int(historyOptions.Limit),
+	}
+	if historyOptions.Since != nil {
+		query.Since = historyOptions.Since.Time
+	}
+	if historyOptions.Until != nil {
+		query.Until = historyOptions.Until.Time
+	}
+	records, err := r.views.history.Query(query)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	history := &viewv1alpha1.PolicyHistory{
+		ObjectMeta: fleetPolicy.ObjectMeta,
+		Changes:    []viewv1alpha1.ComplianceChange{},
+	}
+	for _, record := range records {
+		history.Changes = append(history.Changes, viewv1alpha1.ComplianceChange{
+			Time:            metav1.NewTime(record.Time),
+			Hub:             record.Hub,
+			Cluster:         record.Cluster,
+			ComplianceState: record.ComplianceState,
+		})
+	}
+	return history, nil
+}
+
+// complianceReportREST serves the fleet wide compliance reports of the global policies, in json, csv or junit.
+type complianceReportREST struct {
+	views *Views
+}
+
+var (
+	_ rest.GetterWithOptions = &complianceReportREST{}
+	_ rest.StorageMetadata   = &complianceReportREST{}
+)
+
+var (
+	complianceReports       = sets.NewString(viewv1alpha1.PolicyReport, viewv1alpha1.HubReport, viewv1alpha1.ClusterReport)
+	complianceReportFormats = sets.NewString(viewv1alpha1.JSONReportFormat, viewv1alpha1.CSVReportFormat, viewv1alpha1.JUnitReportFormat)
+)
+
+func (r *complianceReportREST) New() runtime.Object {
+	return &viewv1alpha1.ComplianceReport{}
+}
+
+func (r *complianceReportREST) NamespaceScoped() bool {
+	return false
+}
+
+func (r *complianceReportREST) NewGetOptions() (runtime.Object, bool, string) {
+	return &viewv1alpha1.ComplianceReportOptions{}, false, ""
+}
+
+func (r *complianceReportREST) ProducesMIMETypes(verb string) []string {
+	return []string{"text/csv", "application/xml"}
+}
+
+func (r *complianceReportREST) ProducesObject(verb string) interface{} {
+	return nil
+}
+
+func (r *complianceReportREST) Get(ctx context.Context, name string, options runtime.Object) (runtime.Object, error) {
+	reportOptions, ok := options.(*viewv1alpha1.ComplianceReportOptions)
+	if !ok {
+		return nil, fmt.Errorf("invalid options object: %#v", options)
+	}
+	if !complianceReports.Has(name) {
+		return nil, apierrors.NewNotFound(viewv1alpha1.GroupVersion.WithResource("compliancereports").GroupResource(), name)
+	}
+	if len(reportOptions.Format) > 0 && !complianceReportFormats.Has(reportOptions.Format) {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("unsupported format %q, must be one of %s", reportOptions.Format,
+			strings.Join(complianceReportFormats.List(), ", ")))
+	}
+	if !r.views.synced() {
+		return nil, errNotSynced()
+	}
+	report, err := r.views.complianceReport(name)
+	if err != nil {
+		return nil, err
+	}
+	return renderReport(report, reportOptions.Format)
+}
+
+// searchREST runs the searches, they are not stored.
+type searchREST struct {
+	views *Views
+}
+
+var _ rest.Creater = &searchREST{}
+
+func (r *searchREST) New() runtime.Object {
+	return &viewv1alpha1.Search{}
+}
+
+func (r *searchREST) NamespaceScoped() bool {
+	return false
+}
+
+func (r *searchREST) Create(ctx context.Context, obj runtime.Object, createValidation rest.ValidateObjectFunc, options *metav1.CreateOptions) (runtime.Object, error) {
+	search, ok := obj.(*viewv1alpha1.Search)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("not a Search: %#v", obj))
+	}
+	if createValidation != nil {
+		if err := createValidation(ctx, obj.DeepCopyObject()); err != nil {
+			return nil, err
+		}
+	}
+	if !r.views.synced() {
+		return nil, errNotSynced()
+	}
+	if err := r.views.search(search); err != nil {
+		return nil, err
+	}
+	// the search is not stored, no field is managed
+	search.ManagedFields = nil
+	return search, nil
+}
+
+// globalPolicy returns the global policy of the namespace of the request, the copies in the namespaces of the
+// regional hubs are not found.
+func (v *Views) globalPolicy(ctx context.Context, name string) (runtime.Object, error) {
+	if !v.synced() {
+		return nil, errNotSynced()
+	}
+	obj, err := v.policies.Lister().ByNamespace(genericapirequest.NamespaceValue(ctx)).Get(name)
+	if err == nil && !isGlobalPolicy(obj.(*unstructured.Unstructured)) {
+		err = apierrors.NewNotFound(policyGVR.GroupResource(), name)
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, apierrors.NewNotFound(viewv1alpha1.GroupVersion.WithResource("policies").GroupResource(), name)
+	}
+	return obj, err
+}
+
+// isGlobalPolicy returns false for the copies of the global policies in the namespaces of the regional hubs.
+func isGlobalPolicy(policy *unstructured.Unstructured) bool {
+	originalNamespace, ok := policy.GetLabels()[apis.GlobalHubPolicyNamespaceLabel]
+	return !ok || originalNamespace == policy.GetNamespace()
+}
+
+func labelSelector(options *metainternalversion.ListOptions) labels.Selector {
+	if options == nil || options.LabelSelector == nil {
+		return everything
+	}
+	return options.LabelSelector
+}
+
+func errNotSynced() error {
+	return apierrors.NewServiceUnavailable("the views of the global hub are not synced yet")
+}
+
+func age(timestamp metav1.Time) string {
+	if timestamp.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(timestamp.Time))
+}
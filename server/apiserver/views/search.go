@@ -0,0 +1,314 @@
+package views
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/clyang82/multicluster-global-hub-lite/apis"
+	viewv1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/view/v1alpha1"
+	"github.com/clyang82/multicluster-global-hub-lite/server/fieldpath"
+)
+
+const (
+	managedClusterKind  = "ManagedCluster"
+	policyKind          = "Policy"
+	hubControlPlaneKind = "HubControlPlane"
+
+	// clusterField holds the managed clusters of the searched objects
+	clusterField = "cluster"
+)
+
+var searchKinds = sets.NewString(managedClusterKind, policyKind, hubControlPlaneKind)
+
+// searchObject is an object synced from a regional hub with its managed clusters, each a map with the name of the
+// cluster and the state of the object on the cluster.
+type searchObject struct {
+	kind string
+	hub  string
+	// namespace is the namespace of the global policy of a policy
+	namespace string
+	obj       *unstructured.Unstructured
+	clusters  []interface{}
+}
+
+// searchQuery is a validated SearchQuery.
+type searchQuery struct {
+	viewv1alpha1.SearchQuery
+	selector labels.Selector
+}
+
+func newSearchQuery(query viewv1alpha1.SearchQuery, fldPath *field.Path) (*searchQuery, field.ErrorList) {
+	errs := field.ErrorList{}
+	if !searchKinds.Has(query.Kind) {
+		errs = append(errs, field.NotSupported(fldPath.Child("kind"), query.Kind, searchKinds.List()))
+	}
+	selector, err := labels.Parse(query.LabelSelector)
+	if err != nil {
+		errs = append(errs, field.Invalid(fldPath.Child("labelSelector"), query.LabelSelector, err.Error()))
+	}
+	for i, filter := range query.Fields {
+		filterPath := fldPath.Child("fields").Index(i)
+		if len(filter.Path) == 0 {
+			errs = append(errs, field.Required(filterPath.Child("path"), ""))
+		}
+		switch filter.Operator {
+		case viewv1alpha1.FieldOpIn, viewv1alpha1.FieldOpNotIn:
+			if len(filter.Values) == 0 {
+				errs = append(errs, field.Required(filterPath.Child("values"), "the values are required by "+string(filter.Operator)))
+			}
+		case viewv1alpha1.FieldOpExists, viewv1alpha1.FieldOpDoesNotExist:
+			if len(filter.Values) != 0 {
+				errs = append(errs, field.Forbidden(filterPath.Child("values"), "the values are forbidden by "+string(filter.Operator)))
+			}
+		default:
+			errs = append(errs, field.NotSupported(filterPath.Child("operator"), filter.Operator,
+				[]string{string(viewv1alpha1.FieldOpIn), string(viewv1alpha1.FieldOpNotIn), string(viewv1alpha1.FieldOpExists), string(viewv1alpha1.FieldOpDoesNotExist)}))
+		}
+	}
+	return &searchQuery{SearchQuery: query, selector: selector}, errs
+}
+
+// matches returns true when the object matches the query, the cluster field of the object holds the clusters.
+func (q *searchQuery) matches(o *searchObject, clusters []interface{}) bool {
+	if len(q.Name) > 0 && o.obj.GetName() != q.Name {
+		return false
+	}
+	if len(q.Namespace) > 0 && o.namespace != q.Namespace {
+		return false
+	}
+	if !q.selector.Matches(labels.Set(o.obj.GetLabels())) {
+		return false
+	}
+	if len(q.Fields) == 0 {
+		return true
+	}
+
+	content := make(map[string]interface{}, len(o.obj.Object)+1)
+	for key, value := range o.obj.Object {
+		content[key] = value
+	}
+	content[clusterField] = clusters
+	for _, filter := range q.Fields {
+		values := sets.NewString()
+		for _, value := range fieldpath.Values(content, strings.Split(filter.Path, ".")) {
+			values.Insert(fmt.Sprint(value))
+		}
+		var matched bool
+		switch filter.Operator {
+		case viewv1alpha1.FieldOpIn:
+			matched = values.HasAny(filter.Values...)
+		case viewv1alpha1.FieldOpNotIn:
+			matched = !values.HasAny(filter.Values...)
+		case viewv1alpha1.FieldOpExists:
+			matched = values.Len() > 0
+		case viewv1alpha1.FieldOpDoesNotExist:
+			matched = values.Len() == 0
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// search sets the results of the search to the objects matching the query, and when there are joins, with a managed
+// cluster matched by the query and every join on the same regional hub.
+func (v *Views) search(search *viewv1alpha1.Search) error {
+	spec := &search.Spec
+	specPath := field.NewPath("spec")
+	query, errs := newSearchQuery(spec.Query, specPath.Child("query"))
+	joins := []*searchQuery{}
+	for i, join := range spec.Joins {
+		joinQuery, joinErrs := newSearchQuery(join, specPath.Child("joins").Index(i))
+		errs = append(errs, joinErrs...)
+		joins = append(joins, joinQuery)
+	}
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(viewv1alpha1.GroupVersion.WithKind("Search").GroupKind(), search.Name, errs)
+	}
+	hubs := sets.NewString(spec.Hubs...)
+
+	// the clusters matched by every join, per regional hub
+	joined := make([]map[string]sets.String, len(joins))
+	for i, join := range joins {
+		joined[i] = map[string]sets.String{}
+		objects, err := v.searchObjects(join.Kind, hubs)
+		if err != nil {
+			return err
+		}
+		for _, o := range objects {
+			for _, cluster := range o.clusters {
+				if !join.matches(o, []interface{}{cluster}) {
+					continue
+				}
+				if _, ok := joined[i][o.hub]; !ok {
+					joined[i][o.hub] = sets.NewString()
+				}
+				joined[i][o.hub].Insert(clusterName(cluster))
+			}
+		}
+	}
+
+	objects, err := v.searchObjects(query.Kind, hubs)
+	if err != nil {
+		return err
+	}
+	results := []viewv1alpha1.SearchResult{}
+	for _, o := range objects {
+		result := viewv1alpha1.SearchResult{
+			Kind:      o.kind,
+			Hub:       o.hub,
+			Namespace: o.namespace,
+			Name:      o.obj.GetName(),
+			Labels:    o.obj.GetLabels(),
+		}
+		if len(joins) == 0 {
+			if query.matches(o, o.clusters) {
+				results = append(results, result)
+			}
+			continue
+		}
+
+		clusters := sets.NewString()
+		for _, cluster := range o.clusters {
+			name := clusterName(cluster)
+			if !query.matches(o, []interface{}{cluster}) {
+				continue
+			}
+			matched := true
+			for i := range joins {
+				if !joined[i][o.hub].Has(name) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				clusters.Insert(name)
+			}
+		}
+		if clusters.Len() > 0 {
+			result.Clusters = clusters.List()
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Hub != b.Hub {
+			return a.Hub < b.Hub
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	search.Status.Results = results
+	return nil
+}
+
+// searchObjects returns the objects of the kind synced from the regional hubs, from all of them when hubs is empty.
+func (v *Views) searchObjects(kind string, hubs sets.String) ([]*searchObject, error) {
+	objects := []*searchObject{}
+	switch kind {
+	case managedClusterKind:
+		objs, err := listByHub(v.clusters.Informer().GetIndexer(), hubIndex, hubs)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			cluster := obj.(*unstructured.Unstructured)
+			hub, ok := cluster.GetLabels()[apis.RegionalHubLabel]
+			if !ok {
+				continue
+			}
+			objects = append(objects, &searchObject{
+				kind: kind,
+				hub:  hub,
+				obj:  cluster,
+				clusters: []interface{}{map[string]interface{}{
+					"name":      cluster.GetName(),
+					"available": string(availability(cluster)),
+				}},
+			})
+		}
+	case policyKind:
+		// the copies of the policies are in the namespaces of the regional hubs
+		objs, err := listByHub(v.policies.Informer().GetIndexer(), cache.NamespaceIndex, hubs)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			policy := obj.(*unstructured.Unstructured)
+			if isGlobalPolicy(policy) {
+				continue
+			}
+			typedPolicy, err := toPolicy(policy)
+			if err != nil {
+				return nil, err
+			}
+			clusters := []interface{}{}
+			for _, cluster := range typedPolicy.Status.Status {
+				if cluster == nil {
+					continue
+				}
+				clusters = append(clusters, map[string]interface{}{
+					"name":      cluster.ClusterName,
+					"compliant": clusterCompliance(cluster),
+				})
+			}
+			objects = append(objects, &searchObject{
+				kind:      kind,
+				hub:       policy.GetNamespace(),
+				namespace: policy.GetLabels()[apis.GlobalHubPolicyNamespaceLabel],
+				obj:       policy,
+				clusters:  clusters,
+			})
+		}
+	case hubControlPlaneKind:
+		for _, obj := range v.hubs.Informer().GetIndexer().List() {
+			hub := obj.(*unstructured.Unstructured)
+			if hubs.Len() > 0 && !hubs.Has(hub.GetName()) {
+				continue
+			}
+			clusters := []interface{}{}
+			for _, status := range []struct{ field, available string }{
+				{"available", "True"}, {"unavailable", "False"}, {"unknown", "Unknown"},
+			} {
+				names, _, _ := unstructured.NestedStringSlice(hub.Object, "status", "managedClusters", status.field)
+				for _, name := range names {
+					clusters = append(clusters, map[string]interface{}{"name": name, "available": status.available})
+				}
+			}
+			objects = append(objects, &searchObject{kind: kind, hub: hub.GetName(), obj: hub, clusters: clusters})
+		}
+	}
+	return objects, nil
+}
+
+// listByHub returns the objects of the regional hubs from the index, all the objects when hubs is empty.
+func listByHub(indexer cache.Indexer, indexName string, hubs sets.String) ([]interface{}, error) {
+	if hubs.Len() == 0 {
+		return indexer.List(), nil
+	}
+	objs := []interface{}{}
+	for _, hub := range hubs.List() {
+		hubObjs, err := indexer.ByIndex(indexName, hub)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, hubObjs...)
+	}
+	return objs, nil
+}
+
+func clusterName(cluster interface{}) string {
+	name, _ := cluster.(map[string]interface{})["name"].(string)
+	return name
+}
@@ -0,0 +1,379 @@
+// Package views serves the read-only views of the regional hubs and the global policies, and the searches over the
+// managed clusters, the policies and the hubcontrolplanes, computed from the copies the syncers write in the
+// namespaces of the regional hubs, and the compliance reports of the global policies. The copies are read from
+// informer caches, nothing is stored. The compliance history of the global policies is read from the history recorded
+// by the policy controller.
+package views
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apiserver/pkg/registry/rest"
+	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+
+	"github.com/clyang82/multicluster-global-hub-lite/apis"
+	viewv1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/view/v1alpha1"
+	"github.com/clyang82/multicluster-global-hub-lite/server/compliancehistory"
+)
+
+const (
+	// hubIndex indexes the managed clusters by regional hub
+	hubIndex = "hub"
+	// globalPolicyIndex indexes the copies of the global policies in the namespaces of the regional hubs by the
+	// namespace and the name of the global policy
+	globalPolicyIndex = "global-policy"
+
+	compliant    = string(policyv1.Compliant)
+	nonCompliant = string(policyv1.NonCompliant)
+	pending      = "Pending"
+)
+
+var (
+	hubControlPlaneGVR = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1alpha1", Resource: "hubcontrolplanes"}
+	managedClusterGVR  = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}
+	policyGVR          = policyv1.SchemeGroupVersion.WithResource("policies")
+)
+
+var (
+	// Scheme registers the view types served by the global hub
+	Scheme = runtime.NewScheme()
+	Codecs = serializer.NewCodecFactory(Scheme)
+)
+
+func init() {
+	utilruntime.Must(viewv1alpha1.AddToScheme(Scheme))
+	// the views are only read, they are their own internal version
+	Scheme.AddKnownTypes(schema.GroupVersion{Group: viewv1alpha1.GroupVersion.Group, Version: runtime.APIVersionInternal},
+		&viewv1alpha1.Hub{}, &viewv1alpha1.HubList{}, &viewv1alpha1.HubManagedClusters{},
+		&viewv1alpha1.FleetPolicy{}, &viewv1alpha1.FleetPolicyList{}, &viewv1alpha1.PolicyCompliance{},
+		&viewv1alpha1.PolicyHistory{}, &viewv1alpha1.PolicyHistoryOptions{},
+		&viewv1alpha1.ComplianceReport{}, &viewv1alpha1.ComplianceReportOptions{})
+	utilruntime.Must(Scheme.AddConversionFunc((*url.Values)(nil), (*viewv1alpha1.PolicyHistoryOptions)(nil),
+		func(a, b interface{}, scope conversion.Scope) error {
+			return convertURLValuesToPolicyHistoryOptions(a.(*url.Values), b.(*viewv1alpha1.PolicyHistoryOptions))
+		}))
+	utilruntime.Must(Scheme.AddConversionFunc((*url.Values)(nil), (*viewv1alpha1.ComplianceReportOptions)(nil),
+		func(a, b interface{}, scope conversion.Scope) error {
+			b.(*viewv1alpha1.ComplianceReportOptions).Format = a.(*url.Values).Get("format")
+			return nil
+		}))
+	metav1.AddToGroupVersion(Scheme, schema.GroupVersion{Version: "v1"})
+	Scheme.AddUnversionedTypes(schema.GroupVersion{Group: "", Version: "v1"},
+		&metav1.Status{}, &metav1.APIVersions{}, &metav1.APIGroupList{}, &metav1.APIGroup{}, &metav1.APIResourceList{})
+}
+
+// convertURLValuesToPolicyHistoryOptions decodes the query parameters of the history of a global policy.
+func convertURLValuesToPolicyHistoryOptions(in *url.Values, out *viewv1alpha1.PolicyHistoryOptions) error {
+	out.Hub = in.Get("hub")
+	out.Cluster = in.Get("cluster")
+	for param, t := range map[string]**metav1.Time{"since": &out.Since, "until": &out.Until} {
+		if value := in.Get(param); len(value) > 0 {
+			*t = &metav1.Time{}
+			if err := (*t).UnmarshalQueryParameter(value); err != nil {
+				return apierrors.NewBadRequest(fmt.Sprintf("invalid %s %q: %v", param, value, err))
+			}
+		}
+	}
+	if value := in.Get("limit"); len(value) > 0 {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return apierrors.NewBadRequest(fmt.Sprintf("invalid limit %q: %v", value, err))
+		}
+		out.Limit = limit
+	}
+	return nil
+}
+
+// Views computes the views from the informers of the hubcontrolplanes, the managed clusters and the policies.
+type Views struct {
+	factory  dynamicinformer.DynamicSharedInformerFactory
+	hubs     informers.GenericInformer
+	clusters informers.GenericInformer
+	policies informers.GenericInformer
+	// history serves the compliance history of the global policies when it is set
+	history *compliancehistory.Store
+}
+
+func NewViews(client dynamic.Interface, history *compliancehistory.Store) (*Views, error) {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	v := &Views{
+		factory:  factory,
+		hubs:     factory.ForResource(hubControlPlaneGVR),
+		clusters: factory.ForResource(managedClusterGVR),
+		policies: factory.ForResource(policyGVR),
+		history:  history,
+	}
+	if err := v.clusters.Informer().AddIndexers(cache.Indexers{hubIndex: indexByHub}); err != nil {
+		return nil, err
+	}
+	if err := v.policies.Informer().AddIndexers(cache.Indexers{globalPolicyIndex: indexByGlobalPolicy}); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Start starts the informers, the views are unavailable until they are synced.
+func (v *Views) Start(stopCh <-chan struct{}) {
+	v.factory.Start(stopCh)
+}
+
+func (v *Views) synced() bool {
+	return v.hubs.Informer().HasSynced() && v.clusters.Informer().HasSynced() && v.policies.Informer().HasSynced()
+}
+
+// APIGroupInfo returns the views to install in an apiserver.
+func (v *Views) APIGroupInfo() *genericapiserver.APIGroupInfo {
+	apiGroupInfo := genericapiserver.NewDefaultAPIGroupInfo(viewv1alpha1.GroupVersion.Group, Scheme, runtime.NewParameterCodec(Scheme), Codecs)
+	storage := map[string]rest.Storage{
+		"hubs":                 &hubREST{views: v},
+		"hubs/managedclusters": &hubManagedClustersREST{views: v},
+		"policies":             &fleetPolicyREST{views: v},
+		"policies/compliance":  &policyComplianceREST{views: v},
+		"searches":             &searchREST{views: v},
+		"compliancereports":    &complianceReportREST{views: v},
+	}
+	if v.history != nil {
+		storage["policies/history"] = &policyHistoryREST{views: v}
+	}
+	apiGroupInfo.VersionedResourcesStorageMap[viewv1alpha1.GroupVersion.Version] = storage
+	return &apiGroupInfo
+}
+
+func indexByHub(obj interface{}) ([]string, error) {
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+	}
+	hub, ok := unObj.GetLabels()[apis.RegionalHubLabel]
+	if !ok {
+		return nil, nil
+	}
+	return []string{hub}, nil
+}
+
+func indexByGlobalPolicy(obj interface{}) ([]string, error) {
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+	}
+	originalNamespace, ok := unObj.GetLabels()[apis.GlobalHubPolicyNamespaceLabel]
+	if !ok || originalNamespace == unObj.GetNamespace() {
+		return nil, nil
+	}
+	return []string{originalNamespace + "/" + unObj.GetName()}, nil
+}
+
+// hub computes the view of a regional hub from its hubcontrolplane.
+func (v *Views) hub(obj runtime.Object) (*viewv1alpha1.Hub, error) {
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+	}
+	hub := &viewv1alpha1.Hub{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              unObj.GetName(),
+			UID:               unObj.GetUID(),
+			Labels:            unObj.GetLabels(),
+			CreationTimestamp: unObj.GetCreationTimestamp(),
+		},
+	}
+	hub.Status.Endpoint, _, _ = unstructured.NestedString(unObj.Object, "spec", "endpoint")
+	hub.Status.Addons, _, _ = unstructured.NestedStringSlice(unObj.Object, "status", "addons")
+
+	clusters, err := v.managedClusters(hub.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, cluster := range clusters {
+		hub.Status.ManagedClusters.Total++
+		switch cluster.Available {
+		case metav1.ConditionTrue:
+			hub.Status.ManagedClusters.Available++
+		case metav1.ConditionFalse:
+			hub.Status.ManagedClusters.Unavailable++
+		default:
+			hub.Status.ManagedClusters.Unknown++
+		}
+	}
+
+	policies, err := v.policies.Lister().ByNamespace(hub.Name).List(everything)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range policies {
+		if isGlobalPolicy(obj.(*unstructured.Unstructured)) {
+			continue
+		}
+		policy, err := toPolicy(obj)
+		if err != nil {
+			return nil, err
+		}
+		hub.Status.Policies.Total++
+		switch hubCompliance(hub.Name, policy).ComplianceState {
+		case compliant:
+			hub.Status.Policies.Compliant++
+		case nonCompliant:
+			hub.Status.Policies.NonCompliant++
+		default:
+			hub.Status.Policies.Pending++
+		}
+	}
+	return hub, nil
+}
+
+// managedClusters returns the managed clusters of the regional hub sorted by name.
+func (v *Views) managedClusters(hub string) ([]viewv1alpha1.ManagedCluster, error) {
+	objs, err := v.clusters.Informer().GetIndexer().ByIndex(hubIndex, hub)
+	if err != nil {
+		return nil, err
+	}
+	clusters := []viewv1alpha1.ManagedCluster{}
+	for _, obj := range objs {
+		unObj := obj.(*unstructured.Unstructured)
+		labels := map[string]string{}
+		for key, value := range unObj.GetLabels() {
+			if key != apis.RegionalHubLabel {
+				labels[key] = value
+			}
+		}
+		clusters = append(clusters, viewv1alpha1.ManagedCluster{
+			Name:      unObj.GetName(),
+			Labels:    labels,
+			Available: availability(unObj),
+		})
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+	return clusters, nil
+}
+
+// availability returns the status of the available condition of the managed cluster.
+func availability(cluster *unstructured.Unstructured) metav1.ConditionStatus {
+	conditions, _, _ := unstructured.NestedSlice(cluster.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != "ManagedClusterConditionAvailable" {
+			continue
+		}
+		switch status, _ := cond["status"].(string); status {
+		case string(metav1.ConditionTrue), string(metav1.ConditionFalse):
+			return metav1.ConditionStatus(status)
+		}
+	}
+	return metav1.ConditionUnknown
+}
+
+// fleetPolicy computes the compliance of the global policy from its copies in the namespaces of the regional hubs.
+func (v *Views) fleetPolicy(obj runtime.Object) (*viewv1alpha1.FleetPolicy, []*policyv1.Policy, error) {
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil, fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+	}
+	fleetPolicy := &viewv1alpha1.FleetPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              unObj.GetName(),
+			Namespace:         unObj.GetNamespace(),
+			UID:               unObj.GetUID(),
+			Labels:            unObj.GetLabels(),
+			CreationTimestamp: unObj.GetCreationTimestamp(),
+		},
+	}
+
+	objs, err := v.policies.Informer().GetIndexer().ByIndex(globalPolicyIndex, unObj.GetNamespace()+"/"+unObj.GetName())
+	if err != nil {
+		return nil, nil, err
+	}
+	copies := []*policyv1.Policy{}
+	for _, obj := range objs {
+		policy, err := toPolicy(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+		copies = append(copies, policy)
+	}
+	sort.Slice(copies, func(i, j int) bool { return copies[i].Namespace < copies[j].Namespace })
+
+	state := ""
+	for _, policy := range copies {
+		compliance := hubCompliance(policy.Namespace, policy)
+		fleetPolicy.Status.Hubs = append(fleetPolicy.Status.Hubs, compliance)
+		state = worstCompliance(state, compliance.ComplianceState)
+	}
+	if len(state) == 0 {
+		state = pending
+	}
+	fleetPolicy.Status.ComplianceState = state
+	return fleetPolicy, copies, nil
+}
+
+// hubCompliance counts the clusters of the regional hub by compliance state of the policy.
+func hubCompliance(hub string, policy *policyv1.Policy) viewv1alpha1.HubCompliance {
+	compliance := viewv1alpha1.HubCompliance{Hub: hub}
+	state := ""
+	for _, cluster := range policy.Status.Status {
+		if cluster == nil {
+			continue
+		}
+		switch string(cluster.ComplianceState) {
+		case compliant:
+			compliance.Compliant++
+		case nonCompliant:
+			compliance.NonCompliant++
+		default:
+			compliance.Pending++
+		}
+		state = worstCompliance(state, clusterCompliance(cluster))
+	}
+	if len(state) == 0 {
+		state = pending
+	}
+	compliance.ComplianceState = state
+	return compliance
+}
+
+func clusterCompliance(cluster *policyv1.CompliancePerClusterStatus) string {
+	switch state := string(cluster.ComplianceState); state {
+	case compliant, nonCompliant:
+		return state
+	}
+	return pending
+}
+
+// worstCompliance returns NonCompliant over Pending over Compliant, the state is empty before the first compliance.
+func worstCompliance(state, compliance string) string {
+	switch {
+	case len(state) == 0 || compliance == nonCompliant:
+		return compliance
+	case compliance == pending && state == compliant:
+		return pending
+	}
+	return state
+}
+
+func toPolicy(obj interface{}) (*policyv1.Policy, error) {
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+	}
+	policy := &policyv1.Policy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unObj.UnstructuredContent(), policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
@@ -0,0 +1,204 @@
+package views_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/rest"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/clyang82/multicluster-global-hub-lite/apis"
+	viewv1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/view/v1alpha1"
+	"github.com/clyang82/multicluster-global-hub-lite/server/apiserver/views"
+)
+
+func newPolicy(namespace, originalNamespace string, compliance map[string]string) *unstructured.Unstructured {
+	policy := &unstructured.Unstructured{}
+	policy.SetAPIVersion("policy.open-cluster-management.io/v1")
+	policy.SetKind("Policy")
+	policy.SetNamespace(namespace)
+	policy.SetName("policy1")
+	policy.SetLabels(map[string]string{apis.GlobalHubPolicyNamespaceLabel: originalNamespace})
+	status := []interface{}{}
+	for cluster, state := range compliance {
+		status = append(status, map[string]interface{}{"clustername": cluster, "clusternamespace": cluster, "compliant": state})
+	}
+	policy.Object["status"] = map[string]interface{}{"status": status}
+	return policy
+}
+
+func newManagedCluster(hub, name, env string) *unstructured.Unstructured {
+	cluster := &unstructured.Unstructured{}
+	cluster.SetAPIVersion("cluster.open-cluster-management.io/v1")
+	cluster.SetKind("ManagedCluster")
+	cluster.SetName(name)
+	cluster.SetLabels(map[string]string{apis.RegionalHubLabel: hub, "env": env})
+	return cluster
+}
+
+// startViews starts the views over the objects, the views are synced once the fleet policy is found.
+func startViews(t *testing.T, stopCh <-chan struct{}, objects ...runtime.Object) map[string]rest.Storage {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: "cluster.open-cluster-management.io", Version: "v1alpha1", Resource: "hubcontrolplanes"}: "HubControlPlaneList",
+		{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}:        "ManagedClusterList",
+		{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}:                "PolicyList",
+	}, objects...)
+	globalHubViews, err := views.NewViews(client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	globalHubViews.Start(stopCh)
+	return globalHubViews.APIGroupInfo().VersionedResourcesStorageMap[viewv1alpha1.GroupVersion.Version]
+}
+
+func TestFleetPolicy(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	storage := startViews(t, stopCh,
+		newPolicy("default", "default", nil),
+		newPolicy("hub1", "default", map[string]string{"cluster1": "Compliant"}),
+		newPolicy("hub2", "default", map[string]string{"cluster2": "Compliant", "cluster3": "NonCompliant"}),
+	)
+	ctx := genericapirequest.WithNamespace(context.TODO(), "default")
+	var obj runtime.Object
+	var err error
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		obj, err = storage["policies"].(rest.Getter).Get(ctx, "policy1", &metav1.GetOptions{})
+		return err == nil, nil
+	}); err != nil {
+		t.Fatalf("failed to get the fleet policy: %v", err)
+	}
+
+	fleetPolicy := obj.(*viewv1alpha1.FleetPolicy)
+	if fleetPolicy.Status.ComplianceState != "NonCompliant" {
+		t.Errorf("expected NonCompliant, got %s", fleetPolicy.Status.ComplianceState)
+	}
+	expected := []viewv1alpha1.HubCompliance{
+		{Hub: "hub1", ComplianceState: "Compliant", Compliant: 1},
+		{Hub: "hub2", ComplianceState: "NonCompliant", Compliant: 1, NonCompliant: 1},
+	}
+	if len(fleetPolicy.Status.Hubs) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, fleetPolicy.Status.Hubs)
+	}
+	for i := range expected {
+		if fleetPolicy.Status.Hubs[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], fleetPolicy.Status.Hubs[i])
+		}
+	}
+
+	// the copies in the namespaces of the regional hubs have no view
+	ctx = genericapirequest.WithNamespace(context.TODO(), "hub1")
+	if _, err := storage["policies"].(rest.Getter).Get(ctx, "policy1", &metav1.GetOptions{}); err == nil {
+		t.Errorf("expected the copy of the policy not to be found")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	storage := startViews(t, stopCh,
+		newPolicy("default", "default", nil),
+		newPolicy("hub1", "default", map[string]string{"cluster1": "NonCompliant", "cluster2": "NonCompliant", "cluster3": "Compliant"}),
+		newPolicy("hub2", "default", map[string]string{"cluster4": "NonCompliant"}),
+		newManagedCluster("hub1", "cluster1", "prod"),
+		newManagedCluster("hub1", "cluster2", "dev"),
+		newManagedCluster("hub1", "cluster3", "prod"),
+		newManagedCluster("hub2", "cluster4", "prod"),
+	)
+
+	// the prod clusters not compliant with the policy
+	search := &viewv1alpha1.Search{
+		Spec: viewv1alpha1.SearchSpec{
+			Query: viewv1alpha1.SearchQuery{Kind: "ManagedCluster", LabelSelector: "env=prod"},
+			Joins: []viewv1alpha1.SearchQuery{{
+				Kind:      "Policy",
+				Namespace: "default",
+				Name:      "policy1",
+				Fields:    []viewv1alpha1.FieldFilter{{Path: "cluster.compliant", Operator: viewv1alpha1.FieldOpIn, Values: []string{"NonCompliant"}}},
+			}},
+		},
+	}
+	var obj runtime.Object
+	var err error
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		obj, err = storage["searches"].(rest.Creater).Create(context.TODO(), search.DeepCopy(), nil, &metav1.CreateOptions{})
+		return err == nil, nil
+	}); err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	results := obj.(*viewv1alpha1.Search).Status.Results
+	if len(results) != 2 || results[0].Hub != "hub1" || results[0].Name != "cluster1" ||
+		results[1].Hub != "hub2" || results[1].Name != "cluster4" {
+		t.Errorf("expected cluster1 of hub1 and cluster4 of hub2, got %+v", results)
+	}
+
+	search.Spec.Query.Kind = "Unknown"
+	if _, err := storage["searches"].(rest.Creater).Create(context.TODO(), search.DeepCopy(), nil, &metav1.CreateOptions{}); !apierrors.IsInvalid(err) {
+		t.Errorf("expected an invalid search, got %v", err)
+	}
+}
+
+func TestComplianceReport(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	globalPolicy := newPolicy("default", "default", nil)
+	globalPolicy.SetAnnotations(map[string]string{
+		"policy.open-cluster-management.io/standards": "NIST SP 800-53",
+		"policy.open-cluster-management.io/controls":  "CM-2 Baseline Configuration, CM-6",
+	})
+	storage := startViews(t, stopCh,
+		globalPolicy,
+		newPolicy("hub1", "default", map[string]string{"cluster1": "NonCompliant"}),
+		newPolicy("hub2", "default", map[string]string{"cluster2": "Compliant"}),
+	)
+
+	var obj runtime.Object
+	var err error
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		obj, err = storage["compliancereports"].(rest.GetterWithOptions).Get(context.TODO(), "clusters",
+			&viewv1alpha1.ComplianceReportOptions{Format: "csv"})
+		return err == nil, nil
+	}); err != nil {
+		t.Fatalf("failed to get the report: %v", err)
+	}
+	stream, _, contentType, err := obj.(rest.ResourceStreamer).InputStream(context.TODO(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `namespace,policy,hub,cluster,standards,categories,controls,complianceState
+default,policy1,hub1,cluster1,NIST SP 800-53,,"CM-2 Baseline Configuration,CM-6",NonCompliant
+default,policy1,hub2,cluster2,NIST SP 800-53,,"CM-2 Baseline Configuration,CM-6",Compliant
+`
+	if contentType != "text/csv" || string(data) != expected {
+		t.Errorf("expected the csv report %q, got %s %q", expected, contentType, data)
+	}
+
+	obj, err = storage["compliancereports"].(rest.GetterWithOptions).Get(context.TODO(), "hubs",
+		&viewv1alpha1.ComplianceReportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := obj.(*viewv1alpha1.ComplianceReport)
+	if len(report.Entries) != 2 || report.Entries[0].Hub != "hub1" || report.Entries[0].ComplianceState != "NonCompliant" ||
+		len(report.Entries[0].Controls) != 2 {
+		t.Errorf("unexpected hub report %+v", report.Entries)
+	}
+
+	if _, err := storage["compliancereports"].(rest.GetterWithOptions).Get(context.TODO(), "hubs",
+		&viewv1alpha1.ComplianceReportOptions{Format: "yaml"}); !apierrors.IsBadRequest(err) {
+		t.Errorf("expected an unsupported format, got %v", err)
+	}
+}
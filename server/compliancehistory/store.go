@@ -0,0 +1,241 @@
+// Package compliancehistory keeps the changes of the compliance of the global policies on the regional hubs and the
+// managed clusters in a local sqlite database, bounded by a retention period and a maximum number of records.
+package compliancehistory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	_ "modernc.org/sqlite" // sqlite driver, pure Go
+)
+
+const tableName = "compliance_history"
+
+// pruneInterval is how often the records out of the retention period or over the maximum are deleted
+const pruneInterval = time.Minute
+
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS ` + tableName + ` (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		time INTEGER NOT NULL,
+		namespace TEXT NOT NULL,
+		policy TEXT NOT NULL,
+		hub TEXT NOT NULL,
+		cluster TEXT NOT NULL,
+		state TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS ` + tableName + `_policy_index ON ` + tableName + ` (namespace, policy, time)`,
+	`CREATE INDEX IF NOT EXISTS ` + tableName + `_time_index ON ` + tableName + ` (time)`,
+}
+
+// Record is the compliance state of a global policy on a regional hub or a managed cluster from a point in time.
+type Record struct {
+	Time      time.Time
+	Namespace string
+	Policy    string
+	Hub       string
+	// Cluster is empty for the compliance of the regional hub
+	Cluster         string
+	ComplianceState string
+}
+
+// Query selects the records of a global policy, on every regional hub and managed cluster when they are empty.
+type Query struct {
+	Namespace string
+	Policy    string
+	Hub       string
+	Cluster   string
+	// Since and Until bound the time of the records when they are set, Until is excluded
+	Since time.Time
+	Until time.Time
+	// Limit is the maximum number of records, the latest are returned, all of them when 0
+	Limit int
+}
+
+type key struct {
+	namespace, policy, hub, cluster string
+}
+
+// Store appends a record when the compliance state of a global policy changes on a regional hub or a managed cluster.
+type Store struct {
+	db         *sql.DB
+	retention  time.Duration
+	maxRecords int
+
+	lock sync.Mutex
+	// last is the last recorded state of every key, only the changes are recorded
+	last map[key]string
+}
+
+// Open opens the history in the sqlite database file, in memory when the file is empty. The records older than the
+// retention are deleted unless they are the current state, and the oldest records over maxRecords are deleted. A 0
+// retention or maxRecords doesn't bound the history.
+func Open(ctx context.Context, file string, retention time.Duration, maxRecords int) (*Store, error) {
+	dataSourceName := ":memory:"
+	if len(file) > 0 {
+		dataSourceName = file + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(30000)"
+	}
+	db, err := sql.Open("sqlite", dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	// an in memory database only lives in its connection
+	db.SetMaxOpenConns(1)
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create the compliance history schema: %w", err)
+		}
+	}
+
+	s := &Store{db: db, retention: retention, maxRecords: maxRecords, last: map[key]string{}}
+	rows, err := db.QueryContext(ctx, `SELECT namespace, policy, hub, cluster, state FROM `+tableName+` WHERE id IN (
+		SELECT MAX(id) FROM `+tableName+` GROUP BY namespace, policy, hub, cluster)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k key
+		var state string
+		if err := rows.Scan(&k.namespace, &k.policy, &k.hub, &k.cluster, &state); err != nil {
+			db.Close()
+			return nil, err
+		}
+		s.last[k] = state
+	}
+	if err := rows.Err(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run deletes the records out of the bounds of the history until the stop channel is closed, then closes the store.
+func (s *Store) Run(stopCh <-chan struct{}) {
+	wait.Until(func() {
+		if err := s.prune(time.Now()); err != nil {
+			klog.Errorf("Failed to prune the compliance history: %v", err)
+		}
+	}, pruneInterval, stopCh)
+	if err := s.db.Close(); err != nil {
+		klog.Errorf("Failed to close the compliance history: %v", err)
+	}
+}
+
+// Record appends the records whose compliance state changed since the last record of their policy, hub and cluster.
+func (s *Store) Record(records []Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	changed := []Record{}
+	for _, r := range records {
+		if state, ok := s.last[recordKey(r)]; ok && state == r.ComplianceState {
+			continue
+		}
+		changed = append(changed, r)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, r := range changed {
+		if _, err := tx.Exec(`INSERT INTO `+tableName+` (time, namespace, policy, hub, cluster, state) VALUES (?, ?, ?, ?, ?, ?)`,
+			r.Time.UnixNano(), r.Namespace, r.Policy, r.Hub, r.Cluster, r.ComplianceState); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, r := range changed {
+		s.last[recordKey(r)] = r.ComplianceState
+	}
+	return nil
+}
+
+// Query returns the records selected by the query, oldest first.
+func (s *Store) Query(q Query) ([]Record, error) {
+	conditions := []string{"namespace = ?", "policy = ?"}
+	args := []interface{}{q.Namespace, q.Policy}
+	if len(q.Hub) > 0 {
+		conditions = append(conditions, "hub = ?")
+		args = append(args, q.Hub)
+	}
+	if len(q.Cluster) > 0 {
+		conditions = append(conditions, "cluster = ?")
+		args = append(args, q.Cluster)
+	}
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "time >= ?")
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		conditions = append(conditions, "time < ?")
+		args = append(args, q.Until.UnixNano())
+	}
+	query := `SELECT time, namespace, policy, hub, cluster, state FROM ` + tableName + ` WHERE ` +
+		strings.Join(conditions, " AND ") + ` ORDER BY id DESC`
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	records := []Record{}
+	for rows.Next() {
+		var r Record
+		var nanos int64
+		if err := rows.Scan(&nanos, &r.Namespace, &r.Policy, &r.Hub, &r.Cluster, &r.ComplianceState); err != nil {
+			return nil, err
+		}
+		r.Time = time.Unix(0, nanos)
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// the latest were selected first
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// prune deletes the records older than the retention but the current states, then the oldest records over the
+// maximum number of records.
+func (s *Store) prune(now time.Time) error {
+	if s.retention > 0 {
+		if _, err := s.db.Exec(`DELETE FROM `+tableName+` WHERE time < ? AND id NOT IN (
+			SELECT MAX(id) FROM `+tableName+` GROUP BY namespace, policy, hub, cluster)`,
+			now.Add(-s.retention).UnixNano()); err != nil {
+			return err
+		}
+	}
+	if s.maxRecords > 0 {
+		if _, err := s.db.Exec(`DELETE FROM `+tableName+` WHERE id <= (
+			SELECT id FROM `+tableName+` ORDER BY id DESC LIMIT 1 OFFSET ?)`, s.maxRecords); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordKey(r Record) key {
+	return key{namespace: r.Namespace, policy: r.Policy, hub: r.Hub, cluster: r.Cluster}
+}
@@ -0,0 +1,73 @@
+package compliancehistory_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/compliancehistory"
+)
+
+func TestStore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.db")
+	store, err := compliancehistory.Open(context.TODO(), file, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open the history: %v", err)
+	}
+	start := time.Unix(1000, 0)
+	record := func(minutes int, hub, cluster, state string) compliancehistory.Record {
+		return compliancehistory.Record{
+			Time:            start.Add(time.Duration(minutes) * time.Minute),
+			Namespace:       "default",
+			Policy:          "policy1",
+			Hub:             hub,
+			Cluster:         cluster,
+			ComplianceState: state,
+		}
+	}
+	for _, records := range [][]compliancehistory.Record{
+		{record(0, "hub1", "", "Compliant"), record(0, "hub1", "cluster1", "Compliant"), record(0, "hub2", "", "Compliant")},
+		// only the changes are recorded
+		{record(1, "hub1", "", "Compliant"), record(1, "hub1", "cluster1", "Compliant"), record(1, "hub2", "", "NonCompliant")},
+	} {
+		if err := store.Record(records); err != nil {
+			t.Fatalf("failed to record: %v", err)
+		}
+	}
+
+	// the last states are restored when the history is reopened
+	store, err = compliancehistory.Open(context.TODO(), file, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to reopen the history: %v", err)
+	}
+	if err := store.Record([]compliancehistory.Record{record(2, "hub2", "", "NonCompliant"), record(3, "hub1", "", "NonCompliant")}); err != nil {
+		t.Fatalf("failed to record: %v", err)
+	}
+
+	records, err := store.Query(compliancehistory.Query{Namespace: "default", Policy: "policy1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 {
+		t.Errorf("expected 5 records, got %+v", records)
+	}
+
+	records, err = store.Query(compliancehistory.Query{
+		Namespace: "default", Policy: "policy1", Since: start.Add(time.Minute), Until: start.Add(10 * time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Hub != "hub2" || records[1].Hub != "hub1" || records[1].ComplianceState != "NonCompliant" {
+		t.Errorf("expected hub2 then hub1 to be NonCompliant, got %+v", records)
+	}
+
+	records, err = store.Query(compliancehistory.Query{Namespace: "default", Policy: "policy1", Hub: "hub1", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || !records[0].Time.Equal(start.Add(3*time.Minute)) {
+		t.Errorf("expected the latest record of hub1, got %+v", records)
+	}
+}
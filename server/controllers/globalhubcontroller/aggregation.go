@@ -0,0 +1,109 @@
+package globalhubcontroller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	aggregationrulev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/aggregationrule/v1alpha1"
+	"github.com/clyang82/multicluster-global-hub-lite/server/fieldpath"
+)
+
+// Aggregate reduces the fields of the copies reported by the regional hubs. Every field results in the reduction of
+// the values of each regional hub, keyed by the namespace of its copy, and the reduction of all the values:
+//
+//	<name>:
+//	  total: <reduced values of all the regional hubs>
+//	  hubs:
+//	    <regional hub>: <reduced values of the regional hub>
+func Aggregate(fields []aggregationrulev1alpha1.AggregationField, copies []*unstructured.Unstructured) map[string]interface{} {
+	aggregations := map[string]interface{}{}
+	for _, field := range fields {
+		path := strings.Split(field.Path, ".")
+		all := []interface{}{}
+		hubs := map[string]interface{}{}
+		for _, hubCopy := range copies {
+			values := fieldpath.Values(hubCopy.Object, path)
+			all = append(all, values...)
+			if reduced, ok := reduce(field.Reducer, values); ok {
+				hubs[hubCopy.GetNamespace()] = reduced
+			}
+		}
+
+		aggregation := map[string]interface{}{"hubs": hubs}
+		if reduced, ok := reduce(field.Reducer, all); ok {
+			aggregation["total"] = reduced
+		}
+		aggregations[field.Name] = aggregation
+	}
+	return aggregations
+}
+
+// reduce combines the values, it returns false when there is no result, e.g. the minimum of no values.
+func reduce(reducer aggregationrulev1alpha1.Reducer, values []interface{}) (interface{}, bool) {
+	switch reducer {
+	case aggregationrulev1alpha1.CountByValue:
+		counts := map[string]interface{}{}
+		for _, value := range values {
+			key := fmt.Sprint(value)
+			count, _ := counts[key].(int64)
+			counts[key] = count + 1
+		}
+		return counts, true
+	case aggregationrulev1alpha1.Sum:
+		integers := true
+		var intSum int64
+		var floatSum float64
+		for _, value := range values {
+			switch n := value.(type) {
+			case int64:
+				intSum += n
+				floatSum += float64(n)
+			case float64:
+				integers = false
+				floatSum += n
+			}
+		}
+		if integers {
+			return intSum, true
+		}
+		return floatSum, true
+	case aggregationrulev1alpha1.Min, aggregationrulev1alpha1.Max:
+		var result interface{}
+		var resultNumber float64
+		for _, value := range values {
+			var number float64
+			switch n := value.(type) {
+			case int64:
+				number = float64(n)
+			case float64:
+				number = n
+			default:
+				continue
+			}
+			if result == nil ||
+				(reducer == aggregationrulev1alpha1.Min && number < resultNumber) ||
+				(reducer == aggregationrulev1alpha1.Max && number > resultNumber) {
+				result, resultNumber = value, number
+			}
+		}
+		return result, result != nil
+	case aggregationrulev1alpha1.List:
+		seen := map[string]bool{}
+		list := []interface{}{}
+		for _, value := range values {
+			key := fmt.Sprint(value)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			list = append(list, runtime.DeepCopyJSONValue(value))
+		}
+		sort.Slice(list, func(i, j int) bool { return fmt.Sprint(list[i]) < fmt.Sprint(list[j]) })
+		return list, true
+	}
+	return nil, false
+}
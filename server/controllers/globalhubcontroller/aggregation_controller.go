@@ -0,0 +1,383 @@
+package globalhubcontroller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	aggregationrulev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/aggregationrule/v1alpha1"
+)
+
+// globalObjectIndex indexes the copies reported by the regional hubs by the key of their global hub object
+const globalObjectIndex = "global-object"
+
+var aggregationRuleGVR = aggregationrulev1alpha1.GroupVersion.WithResource("aggregationrules")
+
+// defaultAggregationRules are created when missing at startup, they can be changed but are recreated once deleted
+var defaultAggregationRules = []aggregationrulev1alpha1.AggregationRule{
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "subscription-status"},
+		Spec: aggregationrulev1alpha1.AggregationRuleSpec{
+			Source: aggregationrulev1alpha1.SourceResource{
+				Group:    "apps.open-cluster-management.io",
+				Version:  "v1",
+				Resource: "subscriptions",
+			},
+			Fields: []aggregationrulev1alpha1.AggregationField{
+				{Name: "phase", Path: "status.phase", Reducer: aggregationrulev1alpha1.CountByValue},
+				{Name: "packages", Path: "status.statuses.*.packages.*.phase", Reducer: aggregationrulev1alpha1.CountByValue},
+			},
+		},
+	},
+}
+
+// aggregationKey is a global hub object rolled up by an aggregation rule
+type aggregationKey struct {
+	rule      string
+	namespace string
+	name      string
+}
+
+// aggregationSource watches the objects of a resource referenced by the aggregation rules
+type aggregationSource struct {
+	informer cache.SharedIndexInformer
+	stopCh   chan struct{}
+}
+
+// AggregationController rolls up the fields of the copies reported by the syncers to status.aggregations of their
+// global hub object, as declared by the aggregation rules. The copies are the objects labeled with the namespace of
+// their global hub object, in the namespace of their regional hub.
+type AggregationController struct {
+	stopCh       <-chan struct{}
+	client       dynamic.Interface
+	ruleInformer cache.SharedIndexInformer
+	queue        workqueue.RateLimitingInterface
+
+	lock    sync.Mutex
+	sources map[schema.GroupVersionResource]*aggregationSource
+}
+
+func NewAggregationController(stopChannel <-chan struct{}, client dynamic.Interface,
+	informerFactory dynamicinformer.DynamicSharedInformerFactory) *AggregationController {
+	c := &AggregationController{
+		stopCh:       stopChannel,
+		client:       client,
+		ruleInformer: informerFactory.ForResource(aggregationRuleGVR).Informer(),
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "aggregation-controller"),
+		sources:      map[schema.GroupVersionResource]*aggregationSource{},
+	}
+
+	c.ruleInformer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				c.ruleChanged(obj)
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				c.ruleChanged(obj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				c.stopUnusedSources()
+			},
+		},
+	)
+	return c
+}
+
+func (c *AggregationController) Run(numThreads int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	go c.ruleInformer.Run(c.stopCh)
+	if !cache.WaitForCacheSync(c.stopCh, c.ruleInformer.HasSynced) {
+		klog.Info("Timed out waiting for caches to sync")
+		return
+	}
+
+	if err := c.createDefaultRules(); err != nil {
+		klog.Errorf("failed to create the default aggregation rules: %v", err)
+	}
+
+	klog.Infof("Starting aggregation controller")
+	defer klog.Infof("Shutting down aggregation controller")
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(c.startWorker, time.Second, c.stopCh)
+	}
+
+	<-c.stopCh
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for gvr, source := range c.sources {
+		close(source.stopCh)
+		delete(c.sources, gvr)
+	}
+}
+
+// createDefaultRules creates the default aggregation rules which do not exist.
+func (c *AggregationController) createDefaultRules() error {
+	for _, rule := range defaultAggregationRules {
+		if _, exists, err := c.ruleInformer.GetStore().GetByKey(rule.Name); err != nil || exists {
+			continue
+		}
+		rule.SetGroupVersionKind(aggregationrulev1alpha1.GroupVersion.WithKind("AggregationRule"))
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&rule)
+		if err != nil {
+			return err
+		}
+		_, err = c.client.Resource(aggregationRuleGVR).Create(context.TODO(),
+			&unstructured.Unstructured{Object: content}, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+		klog.Infof("Created the default aggregation rule %s", rule.Name)
+	}
+	return nil
+}
+
+// ruleChanged watches the source of the rule and enqueues the global hub objects of the source.
+func (c *AggregationController) ruleChanged(obj interface{}) {
+	rule, err := toAggregationRule(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	source := c.ensureSource(rule.Spec.Source.GroupVersionResource())
+	c.stopUnusedSources()
+
+	// the objects not synced yet are enqueued by the source informer
+	for _, item := range source.informer.GetStore().List() {
+		if obj, ok := item.(*unstructured.Unstructured); ok && isGlobalHubObject(obj) {
+			c.queue.Add(aggregationKey{rule: rule.Name, namespace: obj.GetNamespace(), name: obj.GetName()})
+		}
+	}
+}
+
+// sourceChanged enqueues the global hub object of the changed object for every rule of its resource.
+func (c *AggregationController) sourceChanged(gvr schema.GroupVersionResource, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	namespace, ok := unObj.GetLabels()[GlobalHubPolicyNamespaceLabel]
+	if !ok {
+		return
+	}
+
+	for _, item := range c.ruleInformer.GetStore().List() {
+		rule, err := toAggregationRule(item)
+		if err != nil {
+			utilruntime.HandleError(err)
+			continue
+		}
+		if rule.Spec.Source.GroupVersionResource() == gvr {
+			c.queue.Add(aggregationKey{rule: rule.Name, namespace: namespace, name: unObj.GetName()})
+		}
+	}
+}
+
+func (c *AggregationController) ensureSource(gvr schema.GroupVersionResource) *aggregationSource {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if source, ok := c.sources[gvr]; ok {
+		return source
+	}
+
+	// only the global hub objects and their copies are labeled with the original namespace
+	informer := dynamicinformer.NewFilteredDynamicInformer(c.client, gvr, metav1.NamespaceAll, 10*time.Hour,
+		cache.Indexers{globalObjectIndex: globalObjectIndexFunc},
+		func(o *metav1.ListOptions) {
+			o.LabelSelector = GlobalHubPolicyNamespaceLabel
+		}).Informer()
+	informer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				c.sourceChanged(gvr, obj)
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				c.sourceChanged(gvr, obj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				c.sourceChanged(gvr, obj)
+			},
+		},
+	)
+
+	source := &aggregationSource{informer: informer, stopCh: make(chan struct{})}
+	go informer.Run(source.stopCh)
+	c.sources[gvr] = source
+	klog.Infof("Watching %s for the aggregation rules", gvr)
+	return source
+}
+
+// stopUnusedSources stops watching the resources no rule refers to anymore.
+func (c *AggregationController) stopUnusedSources() {
+	used := map[schema.GroupVersionResource]bool{}
+	for _, item := range c.ruleInformer.GetStore().List() {
+		if rule, err := toAggregationRule(item); err == nil {
+			used[rule.Spec.Source.GroupVersionResource()] = true
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for gvr, source := range c.sources {
+		if !used[gvr] {
+			close(source.stopCh)
+			delete(c.sources, gvr)
+			klog.Infof("Stopped watching %s for the aggregation rules", gvr)
+		}
+	}
+}
+
+func (c *AggregationController) startWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *AggregationController) processNextWorkItem() bool {
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(aggregationKey)
+	defer c.queue.Done(key)
+	if err := c.process(key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("aggregation controller failed to sync %+v, err: %w", key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *AggregationController) process(key aggregationKey) error {
+	item, exists, err := c.ruleInformer.GetStore().GetByKey(key.rule)
+	if err != nil || !exists {
+		return err
+	}
+	rule, err := toAggregationRule(item)
+	if err != nil {
+		return err
+	}
+	gvr := rule.Spec.Source.GroupVersionResource()
+
+	c.lock.Lock()
+	source, ok := c.sources[gvr]
+	c.lock.Unlock()
+	if !ok {
+		return nil
+	}
+	if !source.informer.HasSynced() {
+		return fmt.Errorf("the informer of %s is not synced", gvr)
+	}
+
+	objectKey := key.name
+	if key.namespace != "" {
+		objectKey = key.namespace + "/" + key.name
+	}
+	if _, exists, err := source.informer.GetStore().GetByKey(objectKey); err != nil || !exists {
+		return err
+	}
+	items, err := source.informer.GetIndexer().ByIndex(globalObjectIndex, objectKey)
+	if err != nil {
+		return err
+	}
+	copies := []*unstructured.Unstructured{}
+	for _, item := range items {
+		if hubCopy, ok := item.(*unstructured.Unstructured); ok {
+			copies = append(copies, hubCopy)
+		}
+	}
+
+	aggregations := Aggregate(rule.Spec.Fields, copies)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return c.updateAggregations(gvr, key, aggregations)
+	})
+}
+
+// updateAggregations sets the aggregations of the rule in the status of the global hub object, the aggregations of
+// the other rules are kept.
+func (c *AggregationController) updateAggregations(gvr schema.GroupVersionResource, key aggregationKey,
+	aggregations map[string]interface{}) error {
+	globalObj, err := c.client.Resource(gvr).Namespace(key.namespace).Get(context.TODO(), key.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	existing, _, err := unstructured.NestedMap(globalObj.Object, "status", "aggregations")
+	if err != nil {
+		return err
+	}
+	updated := runtime.DeepCopyJSON(existing)
+	if updated == nil {
+		updated = map[string]interface{}{}
+	}
+	for name, aggregation := range aggregations {
+		updated[name] = aggregation
+	}
+	if equality.Semantic.DeepEqual(existing, updated) {
+		return nil
+	}
+
+	if err := unstructured.SetNestedMap(globalObj.Object, updated, "status", "aggregations"); err != nil {
+		return err
+	}
+	if _, err := c.client.Resource(gvr).Namespace(key.namespace).UpdateStatus(context.TODO(), globalObj, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	klog.V(2).Infof("updated the aggregations of %s %s/%s by rule %s", gvr.Resource, key.namespace, key.name, key.rule)
+	return nil
+}
+
+// isGlobalHubObject returns true for the objects created on the global hub, their label is their own namespace.
+func isGlobalHubObject(obj *unstructured.Unstructured) bool {
+	namespace, ok := obj.GetLabels()[GlobalHubPolicyNamespaceLabel]
+	return ok && namespace == obj.GetNamespace()
+}
+
+func globalObjectIndexFunc(obj interface{}) ([]string, error) {
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil
+	}
+	namespace, ok := unObj.GetLabels()[GlobalHubPolicyNamespaceLabel]
+	if !ok || namespace == unObj.GetNamespace() {
+		return nil, nil
+	}
+	if namespace == "" {
+		return []string{unObj.GetName()}, nil
+	}
+	return []string{namespace + "/" + unObj.GetName()}, nil
+}
+
+func toAggregationRule(obj interface{}) (*aggregationrulev1alpha1.AggregationRule, error) {
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+	}
+	rule := &aggregationrulev1alpha1.AggregationRule{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unObj.UnstructuredContent(), rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
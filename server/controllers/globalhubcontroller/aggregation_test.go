@@ -0,0 +1,106 @@
+package globalhubcontroller_test
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	aggregationrulev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/aggregationrule/v1alpha1"
+	"github.com/clyang82/multicluster-global-hub-lite/server/controllers/globalhubcontroller"
+)
+
+func hubCopy(hub string, status map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":      "test",
+			"namespace": hub,
+		},
+		"status": status,
+	}}
+}
+
+func TestAggregate(t *testing.T) {
+	copies := []*unstructured.Unstructured{
+		hubCopy("hub1", map[string]interface{}{
+			"replicas": int64(2),
+			"phase":    "Subscribed",
+			"status": []interface{}{
+				map[string]interface{}{"clustername": "cluster1", "compliant": "Compliant"},
+				map[string]interface{}{"clustername": "cluster2", "compliant": "NonCompliant"},
+			},
+			"statuses": map[string]interface{}{
+				"cluster1": map[string]interface{}{"packages": map[string]interface{}{
+					"nginx":   map[string]interface{}{"phase": "Deployed"},
+					"mongodb": map[string]interface{}{"phase": "Failed"},
+				}},
+				"cluster2": map[string]interface{}{"packages": map[string]interface{}{
+					"nginx": map[string]interface{}{"phase": "Deployed"},
+				}},
+			},
+		}),
+		hubCopy("hub2", map[string]interface{}{
+			"replicas": int64(5),
+			"phase":    "Failed",
+			"status": []interface{}{
+				map[string]interface{}{"clustername": "cluster3", "compliant": "Compliant"},
+			},
+		}),
+		hubCopy("hub3", map[string]interface{}{}),
+	}
+	fields := []aggregationrulev1alpha1.AggregationField{
+		{Name: "compliance", Path: "status.status.compliant", Reducer: aggregationrulev1alpha1.CountByValue},
+		{Name: "replicas", Path: "status.replicas", Reducer: aggregationrulev1alpha1.Sum},
+		{Name: "minReplicas", Path: "status.replicas", Reducer: aggregationrulev1alpha1.Min},
+		{Name: "maxReplicas", Path: "status.replicas", Reducer: aggregationrulev1alpha1.Max},
+		{Name: "phases", Path: "status.phase", Reducer: aggregationrulev1alpha1.List},
+		{Name: "packages", Path: "status.statuses.*.packages.*.phase", Reducer: aggregationrulev1alpha1.CountByValue},
+	}
+
+	expected := map[string]interface{}{
+		"compliance": map[string]interface{}{
+			"total": map[string]interface{}{"Compliant": int64(2), "NonCompliant": int64(1)},
+			"hubs": map[string]interface{}{
+				"hub1": map[string]interface{}{"Compliant": int64(1), "NonCompliant": int64(1)},
+				"hub2": map[string]interface{}{"Compliant": int64(1)},
+				"hub3": map[string]interface{}{},
+			},
+		},
+		"replicas": map[string]interface{}{
+			"total": int64(7),
+			"hubs":  map[string]interface{}{"hub1": int64(2), "hub2": int64(5), "hub3": int64(0)},
+		},
+		// the regional hubs without value have no minimum and maximum
+		"minReplicas": map[string]interface{}{
+			"total": int64(2),
+			"hubs":  map[string]interface{}{"hub1": int64(2), "hub2": int64(5)},
+		},
+		"maxReplicas": map[string]interface{}{
+			"total": int64(5),
+			"hubs":  map[string]interface{}{"hub1": int64(2), "hub2": int64(5)},
+		},
+		"phases": map[string]interface{}{
+			"total": []interface{}{"Failed", "Subscribed"},
+			"hubs": map[string]interface{}{
+				"hub1": []interface{}{"Subscribed"},
+				"hub2": []interface{}{"Failed"},
+				"hub3": []interface{}{},
+			},
+		},
+		"packages": map[string]interface{}{
+			"total": map[string]interface{}{"Deployed": int64(2), "Failed": int64(1)},
+			"hubs": map[string]interface{}{
+				"hub1": map[string]interface{}{"Deployed": int64(2), "Failed": int64(1)},
+				"hub2": map[string]interface{}{},
+				"hub3": map[string]interface{}{},
+			},
+		},
+	}
+
+	aggregations := globalhubcontroller.Aggregate(fields, copies)
+	for name, aggregation := range expected {
+		if !reflect.DeepEqual(aggregations[name], aggregation) {
+			t.Errorf("expected %s to be %v, but got %v", name, aggregation, aggregations[name])
+		}
+	}
+}
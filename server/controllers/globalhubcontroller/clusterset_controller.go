@@ -0,0 +1,221 @@
+package globalhubcontroller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	clustersetv1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/globalmanagedclusterset/v1alpha1"
+)
+
+// RegionalHubLabel is set by the syncers on the reported managedclusters, the value is the regional hub
+const RegionalHubLabel = "global-hub.open-cluster-management.io/regional-hub"
+
+var (
+	globalManagedClusterSetGVR = clustersetv1alpha1.GroupVersion.WithResource("globalmanagedclustersets")
+	managedClusterGVR          = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}
+)
+
+// ClusterSetController computes the members of the global managedclustersets from the labels of the managedclusters
+// reported by the regional hubs, the members are grouped by regional hub in the status of the set.
+type ClusterSetController struct {
+	stopCh          <-chan struct{}
+	client          dynamic.Interface
+	setInformer     cache.SharedIndexInformer
+	clusterInformer cache.SharedIndexInformer
+	queue           workqueue.RateLimitingInterface
+}
+
+func NewClusterSetController(stopChannel <-chan struct{}, client dynamic.Interface,
+	informerFactory dynamicinformer.DynamicSharedInformerFactory) *ClusterSetController {
+	c := &ClusterSetController{
+		stopCh:          stopChannel,
+		client:          client,
+		setInformer:     informerFactory.ForResource(globalManagedClusterSetGVR).Informer(),
+		clusterInformer: informerFactory.ForResource(managedClusterGVR).Informer(),
+		queue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "clusterset-controller"),
+	}
+
+	c.setInformer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				c.enqueue(obj)
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				c.enqueue(obj)
+			},
+		},
+	)
+	// any cluster change may change the members of every set
+	c.clusterInformer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				c.enqueueAll()
+			},
+			UpdateFunc: func(old, obj interface{}) {
+				oldCluster, oldOk := old.(*unstructured.Unstructured)
+				cluster, ok := obj.(*unstructured.Unstructured)
+				if oldOk && ok && equality.Semantic.DeepEqual(oldCluster.GetLabels(), cluster.GetLabels()) {
+					return
+				}
+				c.enqueueAll()
+			},
+			DeleteFunc: func(obj interface{}) {
+				c.enqueueAll()
+			},
+		},
+	)
+	return c
+}
+
+func (c *ClusterSetController) Run(numThreads int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	go c.setInformer.Run(c.stopCh)
+	go c.clusterInformer.Run(c.stopCh)
+	if !cache.WaitForCacheSync(c.stopCh, c.setInformer.HasSynced, c.clusterInformer.HasSynced) {
+		klog.Info("Timed out waiting for caches to sync")
+		return
+	}
+
+	klog.Infof("Starting clusterset controller")
+	defer klog.Infof("Shutting down clusterset controller")
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(c.startWorker, time.Second, c.stopCh)
+	}
+
+	<-c.stopCh
+}
+
+func (c *ClusterSetController) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *ClusterSetController) enqueueAll() {
+	for _, key := range c.setInformer.GetStore().ListKeys() {
+		c.queue.Add(key)
+	}
+}
+
+func (c *ClusterSetController) startWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *ClusterSetController) processNextWorkItem() bool {
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+	defer c.queue.Done(key)
+	if err := c.process(key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("clusterset controller failed to sync %q, err: %w", key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *ClusterSetController) process(key string) error {
+	item, exists, err := c.setInformer.GetStore().GetByKey(key)
+	if err != nil || !exists {
+		return err
+	}
+	set, err := toGlobalManagedClusterSet(item)
+	if err != nil {
+		return err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&set.Spec.ClusterSelector)
+	if err != nil {
+		klog.Errorf("invalid cluster selector of the globalmanagedclusterset %s: %v", set.Name, err)
+		return nil
+	}
+
+	status := ClusterSetMembers(selector, c.clusterInformer.GetStore().List())
+	if equality.Semantic.DeepEqual(set.Status, status) {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return c.updateStatus(set.Name, status)
+	})
+}
+
+func (c *ClusterSetController) updateStatus(name string, status clustersetv1alpha1.GlobalManagedClusterSetStatus) error {
+	globalObj, err := c.client.Resource(globalManagedClusterSetGVR).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	statusMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return err
+	}
+	globalObj.Object["status"] = statusMap
+	if _, err := c.client.Resource(globalManagedClusterSetGVR).UpdateStatus(context.TODO(), globalObj, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	klog.Infof("updated the globalmanagedclusterset %s with %d clusters", name, status.NumberOfClusters)
+	return nil
+}
+
+// ClusterSetMembers returns the clusters selected on every regional hub, the clusters not reported by a regional hub
+// are ignored.
+func ClusterSetMembers(selector labels.Selector, clusters []interface{}) clustersetv1alpha1.GlobalManagedClusterSetStatus {
+	clustersByHub := map[string][]string{}
+	for _, item := range clusters {
+		cluster, ok := item.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		hub, ok := cluster.GetLabels()[RegionalHubLabel]
+		if !ok || !selector.Matches(labels.Set(cluster.GetLabels())) {
+			continue
+		}
+		clustersByHub[hub] = append(clustersByHub[hub], cluster.GetName())
+	}
+
+	status := clustersetv1alpha1.GlobalManagedClusterSetStatus{}
+	for hub, hubClusters := range clustersByHub {
+		sort.Strings(hubClusters)
+		status.NumberOfClusters += int32(len(hubClusters))
+		status.Hubs = append(status.Hubs, clustersetv1alpha1.HubClusters{Name: hub, Clusters: hubClusters})
+	}
+	sort.Slice(status.Hubs, func(i, j int) bool { return status.Hubs[i].Name < status.Hubs[j].Name })
+	return status
+}
+
+func toGlobalManagedClusterSet(obj interface{}) (*clustersetv1alpha1.GlobalManagedClusterSet, error) {
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+	}
+	set := &clustersetv1alpha1.GlobalManagedClusterSet{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unObj.UnstructuredContent(), set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
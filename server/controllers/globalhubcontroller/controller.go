@@ -8,7 +8,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/compliancehistory"
 )
 
 type IController interface {
@@ -18,24 +21,44 @@ type IController interface {
 	ReconcileFunc() func(stopCh <-chan struct{}, obj interface{}) error
 }
 
-func AddControllers(dynamicClient dynamic.Interface, stopChan <-chan struct{}) {
+// AddControllers starts the global hub controllers, the compliance changes of the global policies are recorded in the
+// history when it is set.
+func AddControllers(dynamicClient dynamic.Interface, kubeClient kubernetes.Interface, history *compliancehistory.Store,
+	stopChan <-chan struct{}) {
+	recorder := NewEventRecorder(kubeClient, stopChan)
 	informerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, 10*time.Hour, metav1.NamespaceAll,
 		func(o *metav1.ListOptions) {
 			o.LabelSelector = fmt.Sprintf("!%s", "multicluster-global-hub.open-cluster-management.io/local-resource")
 		})
 
 	controllers := []IController{
-		NewPolicyController(dynamicClient),
+		NewPolicyController(dynamicClient, history),
 		NewPlacementBindingController(dynamicClient),
 		NewPlacementRuleController(dynamicClient),
+		NewLabelingController(dynamicClient, "placement-controller",
+			schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placements"}),
+		NewPlacementDecisionController(dynamicClient),
+		NewLabelingController(dynamicClient, "subscription-controller",
+			schema.GroupVersionResource{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "subscriptions"}),
+		NewLabelingController(dynamicClient, "channel-controller",
+			schema.GroupVersionResource{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "channels"}),
+		NewLabelingController(dynamicClient, "application-controller",
+			schema.GroupVersionResource{Group: "app.k8s.io", Version: "v1beta1", Resource: "applications"}),
+		NewStatusBundleController(dynamicClient),
 	}
 
 	genericControllers := []IGenericController{}
 	for _, c := range controllers {
-		genericControllers = append(genericControllers, NewGenericController(stopChan, dynamicClient, informerFactory, c))
+		genericControllers = append(genericControllers, NewGenericController(stopChan, dynamicClient, informerFactory, c, recorder))
 	}
 
 	for _, c := range genericControllers {
 		go c.Run(1)
 	}
+
+	go NewAggregationController(stopChan, dynamicClient, informerFactory).Run(1)
+	go NewClusterSetController(stopChan, dynamicClient, informerFactory).Run(1)
+	go NewHubAvailabilityController(stopChan, dynamicClient, informerFactory, recorder).Run(1)
+	go NewNotificationController(stopChan, dynamicClient, informerFactory).Run(1)
+	go NewPropagationController(stopChan, dynamicClient, informerFactory).Run(1)
 }
@@ -0,0 +1,38 @@
+package globalhubcontroller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// StatusAggregatedReason is recorded on the global objects when the status reported by a regional hub is rolled up
+	StatusAggregatedReason = "StatusAggregated"
+	// HubUnavailableReason is recorded on the hubcontrolplanes when their regional hub becomes unavailable
+	HubUnavailableReason = "HubUnavailable"
+	// HubAvailableReason is recorded on the hubcontrolplanes when their regional hub becomes available again
+	HubAvailableReason = "HubAvailable"
+
+	controllerEventComponent = "global-hub-controllers"
+)
+
+// IEventRecordingController is implemented by the controllers recording events on the global objects, the
+// GenericController sets their recorder.
+type IEventRecordingController interface {
+	SetEventRecorder(recorder record.EventRecorder)
+}
+
+// NewEventRecorder returns the recorder of the events of the global hub controllers, the events are recorded until
+// stopChan is closed.
+func NewEventRecorder(kubeClient kubernetes.Interface, stopChan <-chan struct{}) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	go func() {
+		<-stopChan
+		broadcaster.Shutdown()
+	}()
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerEventComponent})
+}
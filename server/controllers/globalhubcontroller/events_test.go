@@ -0,0 +1,64 @@
+package globalhubcontroller_test
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/controllers/globalhubcontroller"
+)
+
+func TestStatusAggregatedEvents(t *testing.T) {
+	newPolicy := func(namespace string, states ...string) *unstructured.Unstructured {
+		policy := &unstructured.Unstructured{}
+		policy.SetAPIVersion("policy.open-cluster-management.io/v1")
+		policy.SetKind("Policy")
+		policy.SetNamespace(namespace)
+		policy.SetName("policy1")
+		policy.SetLabels(map[string]string{globalhubcontroller.GlobalHubPolicyNamespaceLabel: "default"})
+		clusters := []interface{}{}
+		for i, state := range states {
+			clusters = append(clusters, map[string]interface{}{
+				"clustername":      fmt.Sprintf("cluster%d", i+1),
+				"clusternamespace": fmt.Sprintf("cluster%d", i+1),
+				"compliant":        state,
+			})
+		}
+		if len(states) > 0 {
+			policy.Object["status"] = map[string]interface{}{"status": clusters}
+		}
+		return policy
+	}
+
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), newPolicy("default"))
+	recorder := record.NewFakeRecorder(10)
+	controller := globalhubcontroller.NewPolicyController(client, nil)
+	controller.(globalhubcontroller.IEventRecordingController).SetEventRecorder(recorder)
+	reconcile := controller.ReconcileFunc()
+
+	for _, test := range []struct {
+		name   string
+		policy *unstructured.Unstructured
+		event  bool
+	}{
+		{"first status of hub1", newPolicy("hub1", "Compliant", "NonCompliant"), true},
+		{"same compliance of hub1", newPolicy("hub1", "NonCompliant", "Compliant"), false},
+		{"compliance of hub1 changed", newPolicy("hub1", "Compliant", "Compliant"), true},
+		{"first status of hub2", newPolicy("hub2", "Compliant"), true},
+		{"same compliance of hub2", newPolicy("hub2", "Compliant"), false},
+	} {
+		if err := reconcile(nil, test.policy); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if recorded := len(recorder.Events) > 0; recorded != test.event {
+			t.Errorf("%s: expected an event %v, got %v", test.name, test.event, recorded)
+		}
+		for len(recorder.Events) > 0 {
+			<-recorder.Events
+		}
+	}
+}
@@ -2,6 +2,7 @@ package globalhubcontroller
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -10,11 +11,18 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// IDeletingController is implemented by the controllers reconciling the deletions, the GenericController calls their
+// DeleteFunc with the last state of the deleted objects.
+type IDeletingController interface {
+	DeleteFunc() func(stopCh <-chan struct{}, obj interface{}) error
+}
+
 type IGenericController interface {
 	// Start starts N worker processes processing work items.
 	Run(numThreads int)
@@ -29,9 +37,20 @@ type GenericController struct {
 	createInstance func() client.Object
 	client         dynamic.Interface
 	reconcile      func(stopCh <-chan struct{}, obj interface{}) error
+
+	reconcileDelete func(stopCh <-chan struct{}, obj interface{}) error
+	lock            sync.Mutex
+	// deleted is the last state of the deleted objects not reconciled yet, keyed by namespace/name
+	deleted map[string]interface{}
 }
 
-func NewGenericController(stopChannel <-chan struct{}, client dynamic.Interface, informerFactory dynamicinformer.DynamicSharedInformerFactory, controller IController) *GenericController {
+// NewGenericController returns a controller reconciling the objects of the resource of the controller, the recorder is
+// set to the controllers recording events.
+func NewGenericController(stopChannel <-chan struct{}, client dynamic.Interface, informerFactory dynamicinformer.DynamicSharedInformerFactory,
+	controller IController, recorder record.EventRecorder) *GenericController {
+	if recordingController, ok := controller.(IEventRecordingController); ok && recorder != nil {
+		recordingController.SetEventRecorder(recorder)
+	}
 	c := &GenericController{
 		stopCh:         stopChannel,
 		name:           controller.GetName(),
@@ -41,6 +60,10 @@ func NewGenericController(stopChannel <-chan struct{}, client dynamic.Interface,
 		queue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controller.GetName()),
 		createInstance: controller.CreateInstanceFunc(),
 		reconcile:      controller.ReconcileFunc(),
+		deleted:        map[string]interface{}{},
+	}
+	if deletingController, ok := controller.(IDeletingController); ok {
+		c.reconcileDelete = deletingController.DeleteFunc()
 	}
 
 	c.informer.AddEventHandler(
@@ -52,7 +75,7 @@ func NewGenericController(stopChannel <-chan struct{}, client dynamic.Interface,
 				c.enqueue(obj)
 			},
 			DeleteFunc: func(obj interface{}) {
-				c.enqueue(obj)
+				c.enqueueDeleted(obj)
 			},
 		},
 	)
@@ -69,6 +92,24 @@ func (c *GenericController) enqueue(obj interface{}) {
 	c.queue.Add(key)
 }
 
+// enqueueDeleted enqueues a deleted resource, its last state is kept when the controller reconciles the deletions.
+func (c *GenericController) enqueueDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	if c.reconcileDelete != nil {
+		c.lock.Lock()
+		c.deleted[key] = obj
+		c.lock.Unlock()
+	}
+	c.queue.Add(key)
+}
+
 func (c *GenericController) Run(numThreads int) {
 	defer utilruntime.HandleCrash()
 	defer c.queue.ShutDown()
@@ -128,17 +169,40 @@ func (c *GenericController) process(key string) error {
 
 	item, exist, err := c.informer.GetStore().Get(instance)
 	if !exist {
-		klog.Warningf("cann't get object: %s/%s", namespace, name)
-		return nil
+		return c.processDeleted(key)
 	}
 	if err != nil {
 		klog.Errorf("get object(%s/%s) error %v", namespace, name, err)
 		return nil
 	}
+	// the object is created again, its deletion doesn't need to be reconciled anymore
+	c.lock.Lock()
+	delete(c.deleted, key)
+	c.lock.Unlock()
 
-	if err != c.reconcile(c.stopCh, item) {
+	if err := c.reconcile(c.stopCh, item); err != nil {
 		klog.Errorf("reconcile object(%s/%s) error %v", namespace, name, err)
 		return err
 	}
 	return nil
 }
+
+// processDeleted reconciles the last state of a deleted object, it is kept until the deletion is reconciled.
+func (c *GenericController) processDeleted(key string) error {
+	c.lock.Lock()
+	item, ok := c.deleted[key]
+	c.lock.Unlock()
+	if !ok {
+		klog.Warningf("cann't get object: %s", key)
+		return nil
+	}
+
+	if err := c.reconcileDelete(c.stopCh, item); err != nil {
+		klog.Errorf("reconcile deleted object(%s) error %v", key, err)
+		return err
+	}
+	c.lock.Lock()
+	delete(c.deleted, key)
+	c.lock.Unlock()
+	return nil
+}
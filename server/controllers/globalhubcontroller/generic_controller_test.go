@@ -0,0 +1,85 @@
+package globalhubcontroller_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/controllers/globalhubcontroller"
+)
+
+// failingController fails to reconcile the first objects.
+type failingController struct {
+	gvr schema.GroupVersionResource
+
+	lock     sync.Mutex
+	failures int
+	calls    int
+}
+
+func (c *failingController) GetName() string {
+	return "failing-controller"
+}
+
+func (c *failingController) GetGVR() schema.GroupVersionResource {
+	return c.gvr
+}
+
+func (c *failingController) CreateInstanceFunc() func() runtimeclient.Object {
+	return func() runtimeclient.Object {
+		return &unstructured.Unstructured{}
+	}
+}
+
+func (c *failingController) ReconcileFunc() func(stopCh <-chan struct{}, obj interface{}) error {
+	return func(stopCh <-chan struct{}, obj interface{}) error {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		c.calls++
+		if c.calls <= c.failures {
+			return errors.New("conflict")
+		}
+		return nil
+	}
+}
+
+func (c *failingController) getCalls() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.calls
+}
+
+func TestGenericControllerRequeuesFailures(t *testing.T) {
+	policyGVR := schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}
+	policy := &unstructured.Unstructured{}
+	policy.SetAPIVersion("policy.open-cluster-management.io/v1")
+	policy.SetKind("Policy")
+	policy.SetNamespace("default")
+	policy.SetName("policy1")
+	dynamicClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{policyGVR: "PolicyList"}, policy)
+	informerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	controller := &failingController{gvr: policyGVR, failures: 2}
+	go globalhubcontroller.NewGenericController(stopCh, dynamicClient, informerFactory, controller, nil).Run(1)
+
+	// the object is reconciled again until it succeeds
+	deadline := time.Now().Add(10 * time.Second)
+	for controller.getCalls() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if calls := controller.getCalls(); calls != 3 {
+		t.Errorf("expected the object to be reconciled 3 times, got %d", calls)
+	}
+}
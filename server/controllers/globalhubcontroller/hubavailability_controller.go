@@ -0,0 +1,210 @@
+package globalhubcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	hubcontrolplanev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/hubcontrolplane/v1alpha1"
+	"github.com/clyang82/multicluster-global-hub-lite/syncer"
+)
+
+// HubHeartbeatGracePeriod is how long a regional hub stays available without sending its heartbeat
+const HubHeartbeatGracePeriod = 3 * syncer.HeartbeatPeriod
+
+var hubControlPlaneGVR = hubcontrolplanev1alpha1.GroupVersion.WithResource("hubcontrolplanes")
+
+// HubAvailabilityController sets the Available condition of the hubcontrolplanes from the heartbeat sent by the
+// syncers of the regional hubs.
+type HubAvailabilityController struct {
+	stopCh   <-chan struct{}
+	client   dynamic.Interface
+	informer cache.SharedIndexInformer
+	queue    workqueue.RateLimitingInterface
+	recorder record.EventRecorder
+}
+
+func NewHubAvailabilityController(stopChannel <-chan struct{}, client dynamic.Interface,
+	informerFactory dynamicinformer.DynamicSharedInformerFactory, recorder record.EventRecorder) *HubAvailabilityController {
+	c := &HubAvailabilityController{
+		stopCh:   stopChannel,
+		client:   client,
+		informer: informerFactory.ForResource(hubControlPlaneGVR).Informer(),
+		queue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "hubavailability-controller"),
+		recorder: recorder,
+	}
+
+	c.informer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				c.enqueue(obj)
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				c.enqueue(obj)
+			},
+		},
+	)
+	return c
+}
+
+func (c *HubAvailabilityController) Run(numThreads int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	go c.informer.Run(c.stopCh)
+	if !cache.WaitForCacheSync(c.stopCh, c.informer.HasSynced) {
+		klog.Info("Timed out waiting for caches to sync")
+		return
+	}
+
+	klog.Infof("Starting hub availability controller")
+	defer klog.Infof("Shutting down hub availability controller")
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(c.startWorker, time.Second, c.stopCh)
+	}
+	// the heartbeat expires without any change of the hubcontrolplanes
+	go wait.Until(c.enqueueAll, syncer.HeartbeatPeriod, c.stopCh)
+
+	<-c.stopCh
+}
+
+func (c *HubAvailabilityController) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *HubAvailabilityController) enqueueAll() {
+	for _, key := range c.informer.GetStore().ListKeys() {
+		c.queue.Add(key)
+	}
+}
+
+func (c *HubAvailabilityController) startWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *HubAvailabilityController) processNextWorkItem() bool {
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+	defer c.queue.Done(key)
+	if err := c.process(key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("hub availability controller failed to sync %q, err: %w", key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *HubAvailabilityController) process(key string) error {
+	item, exists, err := c.informer.GetStore().GetByKey(key)
+	if err != nil || !exists {
+		return err
+	}
+	hubControlPlane, err := toHubControlPlane(item)
+	if err != nil {
+		return err
+	}
+
+	condition := HubAvailableCondition(hubControlPlane.Status.HeartbeatTime, time.Now())
+	existing := meta.FindStatusCondition(hubControlPlane.Status.Conditions, hubcontrolplanev1alpha1.HubControlPlaneAvailable)
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason {
+		return nil
+	}
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return c.updateCondition(hubControlPlane.Name, condition)
+	}); err != nil {
+		return err
+	}
+	switch {
+	case condition.Status == metav1.ConditionFalse:
+		c.recorder.Event(item.(runtime.Object), corev1.EventTypeWarning, HubUnavailableReason, condition.Message)
+	case condition.Status == metav1.ConditionTrue && existing != nil && existing.Status == metav1.ConditionFalse:
+		c.recorder.Event(item.(runtime.Object), corev1.EventTypeNormal, HubAvailableReason, condition.Message)
+	}
+	return nil
+}
+
+func (c *HubAvailabilityController) updateCondition(name string, condition metav1.Condition) error {
+	obj, err := c.client.Resource(hubControlPlaneGVR).Get(context.TODO(), name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	hubControlPlane, err := toHubControlPlane(obj)
+	if err != nil {
+		return err
+	}
+	meta.SetStatusCondition(&hubControlPlane.Status.Conditions, condition)
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(hubControlPlane)
+	if err != nil {
+		return err
+	}
+	if _, err := c.client.Resource(hubControlPlaneGVR).UpdateStatus(context.TODO(),
+		&unstructured.Unstructured{Object: content}, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	klog.Infof("updated the hubcontrolplane %s: %s is %s, %s", name, condition.Type, condition.Status, condition.Message)
+	return nil
+}
+
+// HubAvailableCondition returns the Available condition of a regional hub from the last time its syncer sent the
+// heartbeat, the regional hub is unavailable once the heartbeat is older than the grace period.
+func HubAvailableCondition(heartbeatTime *metav1.Time, now time.Time) metav1.Condition {
+	condition := metav1.Condition{Type: hubcontrolplanev1alpha1.HubControlPlaneAvailable}
+	switch {
+	case heartbeatTime == nil:
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "HeartbeatNotReceived"
+		condition.Message = "the syncer of the regional hub has not sent its heartbeat yet"
+	case now.Sub(heartbeatTime.Time) > HubHeartbeatGracePeriod:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "HeartbeatExpired"
+		condition.Message = fmt.Sprintf("the last heartbeat of the regional hub was received at %s",
+			heartbeatTime.UTC().Format(time.RFC3339))
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "HeartbeatReceived"
+		condition.Message = "the syncer of the regional hub sends its heartbeat"
+	}
+	return condition
+}
+
+func toHubControlPlane(obj interface{}) (*hubcontrolplanev1alpha1.HubControlPlane, error) {
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+	}
+	hubControlPlane := &hubcontrolplanev1alpha1.HubControlPlane{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unObj.UnstructuredContent(), hubControlPlane); err != nil {
+		return nil, err
+	}
+	return hubControlPlane, nil
+}
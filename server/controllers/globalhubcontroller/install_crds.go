@@ -13,17 +13,21 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
+// globalHubGroup is the group of the resources only the global hub serves
+const globalHubGroup = "global-hub.open-cluster-management.io"
+
 //go:embed manifests
 var crdManifestsFS embed.FS
 
-func InstallGlobalHubCRDs(dynamicClient dynamic.Interface) error {
+// InstallGlobalHubCRDs installs the CRDs of the open cluster management resources, the CRDs of the global hub group
+// are only installed on the global hub.
+func InstallGlobalHubCRDs(dynamicClient dynamic.Interface, global bool) error {
 	return fs.WalkDir(crdManifestsFS, "manifests", func(file string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
 		if !d.IsDir() {
-			klog.Infof("Installing CRD %s", file)
 			b, err := crdManifestsFS.ReadFile(file)
 			if err != nil {
 				return err
@@ -33,6 +37,10 @@ func InstallGlobalHubCRDs(dynamicClient dynamic.Interface) error {
 			if err != nil {
 				return err
 			}
+			if group, _, _ := unstructured.NestedString(obj.Object, "spec", "group"); group == globalHubGroup && !global {
+				return nil
+			}
+			klog.Infof("Installing CRD %s", file)
 			_, err = dynamicClient.
 				Resource(apiextensionsv1.SchemeGroupVersion.WithResource("customresourcedefinitions")).
 				Create(context.TODO(), obj, metav1.CreateOptions{})
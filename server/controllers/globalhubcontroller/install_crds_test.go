@@ -0,0 +1,43 @@
+package globalhubcontroller_test
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/controllers/globalhubcontroller"
+)
+
+func TestInstallGlobalHubCRDs(t *testing.T) {
+	crdGVR := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	for _, test := range []struct {
+		global    bool
+		installed bool
+	}{{true, true}, {false, false}} {
+		client := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{crdGVR: "CustomResourceDefinitionList"})
+		if err := globalhubcontroller.InstallGlobalHubCRDs(client, test.global); err != nil {
+			t.Fatal(err)
+		}
+		crds, err := client.Resource(crdGVR).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		installed := map[string]bool{}
+		for _, crd := range crds.Items {
+			installed[crd.GetName()] = true
+		}
+		// the regional hubs get the open cluster management CRDs the syncers apply
+		if !installed["policies.policy.open-cluster-management.io"] {
+			t.Errorf("global %v: expected the policies to be installed", test.global)
+		}
+		if installed["statusbundles.global-hub.open-cluster-management.io"] != test.installed ||
+			installed["notificationrules.global-hub.open-cluster-management.io"] != test.installed {
+			t.Errorf("global %v: expected the global hub CRDs to be installed %v", test.global, test.installed)
+		}
+	}
+}
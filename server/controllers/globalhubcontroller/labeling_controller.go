@@ -0,0 +1,63 @@
+package globalhubcontroller
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// labelingController labels the global objects of a resource with their original namespace, so the syncers propagate
+// them to the regional hubs.
+type labelingController struct {
+	name   string
+	client dynamic.Interface
+	gvr    schema.GroupVersionResource
+}
+
+func NewLabelingController(dynamicClient dynamic.Interface, name string, gvr schema.GroupVersionResource) IController {
+	return &labelingController{
+		name:   name,
+		client: dynamicClient,
+		gvr:    gvr,
+	}
+}
+
+func (c *labelingController) GetName() string {
+	return c.name
+}
+
+func (c *labelingController) GetGVR() schema.GroupVersionResource {
+	return c.gvr
+}
+
+func (c *labelingController) CreateInstanceFunc() func() client.Object {
+	return func() client.Object {
+		return &unstructured.Unstructured{}
+	}
+}
+
+func (c *labelingController) ReconcileFunc() func(stopCha <-chan struct{}, obj interface{}) error {
+	return func(stopCha <-chan struct{}, obj interface{}) error {
+		unObj, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+		}
+
+		labels := unObj.GetLabels()
+		if _, ok := labels[GlobalHubPolicyNamespaceLabel]; ok {
+			return nil
+		}
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[GlobalHubPolicyNamespaceLabel] = unObj.GetNamespace()
+		unObj.SetLabels(labels)
+		_, err := c.client.Resource(c.gvr).Namespace(unObj.GetNamespace()).Update(context.TODO(), unObj, metav1.UpdateOptions{})
+		return err
+	}
+}
@@ -0,0 +1,48 @@
+package globalhubcontroller_test
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/controllers/globalhubcontroller"
+)
+
+func TestLabelingController(t *testing.T) {
+	subscriptionGVR := schema.GroupVersionResource{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "subscriptions"}
+	subscription := &unstructured.Unstructured{}
+	subscription.SetAPIVersion("apps.open-cluster-management.io/v1")
+	subscription.SetKind("Subscription")
+	subscription.SetNamespace("default")
+	subscription.SetName("subscription1")
+
+	client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), subscription)
+	controller := globalhubcontroller.NewLabelingController(client, "subscription-controller", subscriptionGVR)
+	if controller.GetName() != "subscription-controller" || controller.GetGVR() != subscriptionGVR {
+		t.Errorf("unexpected controller %s of %s", controller.GetName(), controller.GetGVR())
+	}
+	reconcile := controller.ReconcileFunc()
+	if err := reconcile(nil, subscription); err != nil {
+		t.Fatal(err)
+	}
+
+	labeled, err := client.Resource(subscriptionGVR).Namespace("default").Get(context.TODO(), "subscription1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if namespace := labeled.GetLabels()[globalhubcontroller.GlobalHubPolicyNamespaceLabel]; namespace != "default" {
+		t.Errorf("expected the original namespace default, got %q", namespace)
+	}
+	// the labeled object is not updated again
+	if err := reconcile(nil, labeled); err != nil {
+		t.Fatal(err)
+	}
+	if actions := len(client.Actions()); actions != 2 {
+		t.Errorf("expected a single update, got %d actions", actions)
+	}
+}
@@ -0,0 +1,563 @@
+package globalhubcontroller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+
+	hubcontrolplanev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/hubcontrolplane/v1alpha1"
+	notificationrulev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/notificationrule/v1alpha1"
+)
+
+const (
+	// DefaultNotificationTemplate is the payload of the webhooks without template, Slack and Teams accept it
+	DefaultNotificationTemplate = `{"text": {{ json .Message }}}`
+
+	defaultDedupeWindow = 5 * time.Minute
+	defaultMaxPerMinute = 10
+	// a notification is dropped once it failed to be delivered maxDeliveryAttempts times
+	maxDeliveryAttempts = 5
+	webhookTimeout      = 10 * time.Second
+)
+
+var notificationRuleGVR = notificationrulev1alpha1.GroupVersion.WithResource("notificationrules")
+
+// Notification is a change delivered to the webhook of a notification rule
+type Notification struct {
+	Rule      string
+	Event     notificationrulev1alpha1.NotificationEvent
+	Namespace string
+	Policy    string
+	Hub       string
+	Message   string
+	Time      time.Time
+}
+
+// ruleLimiter limits the notifications of a rule to its maximum per minute
+type ruleLimiter struct {
+	maxPerMinute int32
+	limiter      flowcontrol.RateLimiter
+}
+
+// NotificationController delivers the compliance changes of the global policies on the regional hubs and the
+// availability changes of the regional hubs to the webhooks of the notification rules. The objects listed when the
+// controller starts only set the states the changes are detected from, so a restart notifies nothing.
+type NotificationController struct {
+	stopCh         <-chan struct{}
+	client         dynamic.Interface
+	ruleInformer   cache.SharedIndexInformer
+	policyInformer cache.SharedIndexInformer
+	hubInformer    cache.SharedIndexInformer
+	queue          workqueue.RateLimitingInterface
+	httpClient     *http.Client
+
+	lock sync.Mutex
+	// policyStates is the compliance state of the global policies per regional hub, keyed by namespace/name/hub
+	policyStates map[string]string
+	hubStates    map[string]metav1.ConditionStatus
+	// dedupeExpiry is when a notification can be delivered again, keyed by rule/event/namespace/name/hub
+	dedupeExpiry map[string]time.Time
+	limiters     map[string]*ruleLimiter
+}
+
+func NewNotificationController(stopChannel <-chan struct{}, client dynamic.Interface,
+	informerFactory dynamicinformer.DynamicSharedInformerFactory) *NotificationController {
+	c := &NotificationController{
+		stopCh:         stopChannel,
+		client:         client,
+		ruleInformer:   informerFactory.ForResource(notificationRuleGVR).Informer(),
+		policyInformer: informerFactory.ForResource(policyv1.SchemeGroupVersion.WithResource("policies")).Informer(),
+		hubInformer:    informerFactory.ForResource(hubControlPlaneGVR).Informer(),
+		// the webhooks are retried after 1s, 2s, 4s and 8s
+		queue: workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Second, time.Minute),
+			"notification-controller"),
+		httpClient:   NewWebhookClient(),
+		policyStates: map[string]string{},
+		hubStates:    map[string]metav1.ConditionStatus{},
+		dedupeExpiry: map[string]time.Time{},
+		limiters:     map[string]*ruleLimiter{},
+	}
+
+	// the added objects are either listed at startup or new, only their updates are changes
+	c.policyInformer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				c.policyChanged(obj, false)
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				c.policyChanged(obj, true)
+			},
+			DeleteFunc: func(obj interface{}) {
+				c.policyDeleted(obj)
+			},
+		},
+	)
+	c.hubInformer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				c.hubChanged(obj, false)
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				c.hubChanged(obj, true)
+			},
+		},
+	)
+	return c
+}
+
+func (c *NotificationController) Run(numThreads int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	go c.ruleInformer.Run(c.stopCh)
+	go c.policyInformer.Run(c.stopCh)
+	go c.hubInformer.Run(c.stopCh)
+	if !cache.WaitForCacheSync(c.stopCh, c.ruleInformer.HasSynced, c.policyInformer.HasSynced, c.hubInformer.HasSynced) {
+		klog.Info("Timed out waiting for caches to sync")
+		return
+	}
+
+	klog.Infof("Starting notification controller")
+	defer klog.Infof("Shutting down notification controller")
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(c.startWorker, time.Second, c.stopCh)
+	}
+	go wait.Until(c.pruneDedupe, time.Minute, c.stopCh)
+
+	<-c.stopCh
+}
+
+// policyChanged notifies the global policies becoming non compliant on a regional hub, and compliant again.
+func (c *NotificationController) policyChanged(obj interface{}, updated bool) {
+	policy, ok := obj.(*unstructured.Unstructured)
+	if !ok || policy.GetLabels()[GlobalHubPolicyNamespaceLabel] != policy.GetNamespace() {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for hub, state := range PolicyComplianceByHub(policy) {
+		key := strings.Join([]string{policy.GetNamespace(), policy.GetName(), hub}, "/")
+		previous := c.policyStates[key]
+		c.policyStates[key] = state
+		if !updated || state == previous {
+			continue
+		}
+		switch {
+		case state == string(policyv1.NonCompliant):
+			c.notify(notificationrulev1alpha1.PolicyNonCompliant, policy.GetNamespace(), policy.GetName(), hub,
+				policy.GetLabels(), fmt.Sprintf("Global policy %s/%s is NonCompliant on the regional hub %s",
+					policy.GetNamespace(), policy.GetName(), hub))
+		case state == string(policyv1.Compliant) && previous == string(policyv1.NonCompliant):
+			c.notify(notificationrulev1alpha1.PolicyCompliant, policy.GetNamespace(), policy.GetName(), hub,
+				policy.GetLabels(), fmt.Sprintf("Global policy %s/%s is Compliant again on the regional hub %s",
+					policy.GetNamespace(), policy.GetName(), hub))
+		}
+	}
+}
+
+func (c *NotificationController) policyDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	policy, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	prefix := policy.GetNamespace() + "/" + policy.GetName() + "/"
+	for key := range c.policyStates {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.policyStates, key)
+		}
+	}
+}
+
+// hubChanged notifies the regional hubs becoming unavailable, and available again.
+func (c *NotificationController) hubChanged(obj interface{}, updated bool) {
+	hubControlPlane, err := toHubControlPlane(obj)
+	if err != nil {
+		return
+	}
+	condition := meta.FindStatusCondition(hubControlPlane.Status.Conditions, hubcontrolplanev1alpha1.HubControlPlaneAvailable)
+	if condition == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	hub := hubControlPlane.Name
+	previous := c.hubStates[hub]
+	c.hubStates[hub] = condition.Status
+	if !updated || condition.Status == previous {
+		return
+	}
+	switch {
+	case condition.Status == metav1.ConditionFalse:
+		c.notify(notificationrulev1alpha1.HubUnavailable, "", "", hub, nil,
+			fmt.Sprintf("Regional hub %s is unavailable: %s", hub, condition.Message))
+	case condition.Status == metav1.ConditionTrue && previous == metav1.ConditionFalse:
+		c.notify(notificationrulev1alpha1.HubAvailable, "", "", hub, nil,
+			fmt.Sprintf("Regional hub %s is available again", hub))
+	}
+}
+
+// notify queues a notification for every rule matching the event.
+func (c *NotificationController) notify(event notificationrulev1alpha1.NotificationEvent, namespace, policy, hub string,
+	policyLabels map[string]string, message string) {
+	klog.Infof("notifying %s: %s", event, message)
+	now := time.Now()
+	for _, item := range c.ruleInformer.GetStore().List() {
+		rule, err := toNotificationRule(item)
+		if err != nil {
+			utilruntime.HandleError(err)
+			continue
+		}
+		if !RuleMatches(rule, event, hub, policyLabels) {
+			continue
+		}
+		c.queue.Add(&Notification{
+			Rule:      rule.Name,
+			Event:     event,
+			Namespace: namespace,
+			Policy:    policy,
+			Hub:       hub,
+			Message:   message,
+			Time:      now,
+		})
+	}
+}
+
+func (c *NotificationController) startWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *NotificationController) processNextWorkItem() bool {
+	item, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(item)
+	notification := item.(*Notification)
+
+	if err := c.process(notification); err != nil {
+		if c.queue.NumRequeues(notification) < maxDeliveryAttempts-1 {
+			utilruntime.HandleError(fmt.Errorf("notification controller failed to deliver %s of rule %q, err: %w",
+				notification.Event, notification.Rule, err))
+			c.queue.AddRateLimited(notification)
+			return true
+		}
+		klog.Errorf("dropped the notification %s of rule %s after %d attempts: %v", notification.Event,
+			notification.Rule, maxDeliveryAttempts, err)
+		c.updateStatus(notification.Rule, func(status *notificationrulev1alpha1.NotificationRuleStatus) {
+			status.Failed++
+			status.LastError = err.Error()
+		})
+	}
+	c.queue.Forget(notification)
+	return true
+}
+
+func (c *NotificationController) process(notification *Notification) error {
+	item, exists, err := c.ruleInformer.GetStore().GetByKey(notification.Rule)
+	if err != nil || !exists {
+		return err
+	}
+	rule, err := toNotificationRule(item)
+	if err != nil {
+		return err
+	}
+
+	// the retries are already deduplicated and rate limited
+	if c.queue.NumRequeues(notification) == 0 && !c.accept(rule, notification) {
+		klog.Infof("suppressed the notification %s of rule %s: %s", notification.Event, rule.Name, notification.Message)
+		c.updateStatus(rule.Name, func(status *notificationrulev1alpha1.NotificationRuleStatus) {
+			status.Suppressed++
+		})
+		return nil
+	}
+
+	if err := c.deliver(rule, notification); err != nil {
+		return err
+	}
+	klog.Infof("delivered the notification %s of rule %s", notification.Event, rule.Name)
+	c.updateStatus(rule.Name, func(status *notificationrulev1alpha1.NotificationRuleStatus) {
+		now := metav1.Now()
+		status.Delivered++
+		status.LastDeliveryTime = &now
+	})
+	return nil
+}
+
+// accept returns false when the same notification was accepted within the dedupe window of the rule, or when the
+// rule already reached its maximum of notifications per minute.
+func (c *NotificationController) accept(rule *notificationrulev1alpha1.NotificationRule, notification *Notification) bool {
+	window := defaultDedupeWindow
+	if rule.Spec.DedupeWindow != nil {
+		window = rule.Spec.DedupeWindow.Duration
+	}
+	maxPerMinute := int32(defaultMaxPerMinute)
+	if rule.Spec.MaxPerMinute != nil && *rule.Spec.MaxPerMinute > 0 {
+		maxPerMinute = *rule.Spec.MaxPerMinute
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := strings.Join([]string{rule.Name, string(notification.Event), notification.Namespace, notification.Policy,
+		notification.Hub}, "/")
+	if expiry, ok := c.dedupeExpiry[key]; ok && notification.Time.Before(expiry) {
+		return false
+	}
+	limiter, ok := c.limiters[rule.Name]
+	if !ok || limiter.maxPerMinute != maxPerMinute {
+		limiter = &ruleLimiter{
+			maxPerMinute: maxPerMinute,
+			limiter:      flowcontrol.NewTokenBucketRateLimiter(float32(maxPerMinute)/60, int(maxPerMinute)),
+		}
+		c.limiters[rule.Name] = limiter
+	}
+	if !limiter.limiter.TryAccept() {
+		return false
+	}
+	c.dedupeExpiry[key] = notification.Time.Add(window)
+	return true
+}
+
+func (c *NotificationController) pruneDedupe() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	for key, expiry := range c.dedupeExpiry {
+		if now.After(expiry) {
+			delete(c.dedupeExpiry, key)
+		}
+	}
+}
+
+// NewWebhookClient returns the client delivering the notifications. The webhooks are set by the users of the global
+// hub, so the client only connects to public addresses, checked once the host is resolved, and doesn't follow the
+// redirects, which could point anywhere.
+func NewWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("the webhook address %s is not public", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+// ValidateWebhookURL returns an error when the URL of a webhook is not an https URL of a host.
+func ValidateWebhookURL(webhookURL string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("the webhook URL %q must be https", webhookURL)
+	}
+	if len(u.Hostname()) == 0 || u.User != nil {
+		return fmt.Errorf("the webhook URL %q must have a host and no user", webhookURL)
+	}
+	return nil
+}
+
+// deliver posts the notification to the webhook of the rule.
+func (c *NotificationController) deliver(rule *notificationrulev1alpha1.NotificationRule, notification *Notification) error {
+	if err := ValidateWebhookURL(rule.Spec.Webhook.URL); err != nil {
+		return err
+	}
+	payload, err := RenderNotification(rule.Spec.Webhook.Template, notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, rule.Spec.Webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	contentType := rule.Spec.Webhook.ContentType
+	if len(contentType) == 0 {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	// the redirects are not followed, they fail the delivery
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("the webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (c *NotificationController) updateStatus(name string, update func(status *notificationrulev1alpha1.NotificationRuleStatus)) {
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := c.client.Resource(notificationRuleGVR).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		rule, err := toNotificationRule(obj)
+		if err != nil {
+			return err
+		}
+		update(&rule.Status)
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(rule)
+		if err != nil {
+			return err
+		}
+		_, err = c.client.Resource(notificationRuleGVR).UpdateStatus(context.TODO(),
+			&unstructured.Unstructured{Object: content}, metav1.UpdateOptions{})
+		return err
+	}); err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("failed to update the status of the notificationrule %s: %v", name, err)
+	}
+}
+
+// RuleMatches returns true when the rule notifies the event of the regional hub, the policy labels are only matched
+// for the policy events.
+func RuleMatches(rule *notificationrulev1alpha1.NotificationRule, event notificationrulev1alpha1.NotificationEvent,
+	hub string, policyLabels map[string]string) bool {
+	matched := false
+	for _, e := range rule.Spec.Events {
+		if e == event {
+			matched = true
+		}
+	}
+	if !matched {
+		return false
+	}
+	if len(rule.Spec.Hubs) > 0 {
+		matched = false
+		for _, h := range rule.Spec.Hubs {
+			if h == hub {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if event != notificationrulev1alpha1.PolicyNonCompliant && event != notificationrulev1alpha1.PolicyCompliant {
+		return true
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&rule.Spec.PolicySelector)
+	if err != nil {
+		klog.Errorf("invalid policy selector of the notificationrule %s: %v", rule.Name, err)
+		return false
+	}
+	return selector.Matches(labels.Set(policyLabels))
+}
+
+// PolicyComplianceByHub returns the compliance state of the global policy on every regional hub from its compliance
+// summary, the regional hubs without compliant or non compliant clusters are left out.
+func PolicyComplianceByHub(policy *unstructured.Unstructured) map[string]string {
+	states := map[string]string{}
+	summaries, _, _ := unstructured.NestedSlice(policy.Object, "status", "complianceSummary", "summaries")
+	for _, item := range summaries {
+		summary, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		hub, _, _ := unstructured.NestedString(summary, "name")
+		nonCompliant, _, _ := unstructured.NestedInt64(summary, "noncompliant")
+		compliant, _, _ := unstructured.NestedInt64(summary, "compliant")
+		switch {
+		case len(hub) == 0:
+		case nonCompliant > 0:
+			states[hub] = string(policyv1.NonCompliant)
+		case compliant > 0:
+			states[hub] = string(policyv1.Compliant)
+		}
+	}
+	return states
+}
+
+// RenderNotification executes the template of a webhook with the notification, the default template when it is
+// empty.
+func RenderNotification(text string, notification *Notification) ([]byte, error) {
+	if len(text) == 0 {
+		text = DefaultNotificationTemplate
+	}
+	tmpl, err := template.New("notification").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, notification); err != nil {
+		return nil, fmt.Errorf("failed to execute the webhook template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func toNotificationRule(obj interface{}) (*notificationrulev1alpha1.NotificationRule, error) {
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+	}
+	rule := &notificationrulev1alpha1.NotificationRule{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unObj.UnstructuredContent(), rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
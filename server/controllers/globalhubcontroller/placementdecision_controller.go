@@ -0,0 +1,185 @@
+package globalhubcontroller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PlacementLabel is set by the placement controller of the regional hubs on the decisions of a placement
+const PlacementLabel = "cluster.open-cluster-management.io/placement"
+
+// DecisionSummary is the status.decisionSummary of a global placement, the clusters selected on every regional hub.
+type DecisionSummary struct {
+	NumberOfSelectedClusters int32          `json:"numberOfSelectedClusters,omitempty"`
+	Summaries                []HubDecisions `json:"summaries,omitempty"`
+}
+
+type HubDecisions struct {
+	Name                     string   `json:"name,omitempty"`
+	NumberOfSelectedClusters int32    `json:"numberOfSelectedClusters,omitempty"`
+	Clusters                 []string `json:"clusters,omitempty"`
+}
+
+type placementDecisionController struct {
+	client       dynamic.Interface
+	gvr          schema.GroupVersionResource
+	placementGVR schema.GroupVersionResource
+}
+
+func NewPlacementDecisionController(dynamicClient dynamic.Interface) IController {
+	return &placementDecisionController{
+		client:       dynamicClient,
+		gvr:          schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placementdecisions"},
+		placementGVR: schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placements"},
+	}
+}
+
+func (c *placementDecisionController) GetName() string {
+	return "placementdecision-controller"
+}
+
+func (c *placementDecisionController) GetGVR() schema.GroupVersionResource {
+	return c.gvr
+}
+
+func (c *placementDecisionController) CreateInstanceFunc() func() client.Object {
+	return func() client.Object {
+		return &unstructured.Unstructured{}
+	}
+}
+
+func (c *placementDecisionController) ReconcileFunc() func(stopCh <-chan struct{}, obj interface{}) error {
+	return func(stopCh <-chan struct{}, obj interface{}) error {
+		unObj, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+		}
+
+		// only the decisions reported by the syncers are labeled with the namespace of the global placement
+		originalNamespace, ok := unObj.GetLabels()[GlobalHubPolicyNamespaceLabel]
+		if !ok || originalNamespace == unObj.GetNamespace() {
+			return nil
+		}
+		placementName, ok := unObj.GetLabels()[PlacementLabel]
+		if !ok {
+			klog.Warningf("the placementdecision(%s/%s) has no placement label", unObj.GetNamespace(), unObj.GetName())
+			return nil
+		}
+
+		return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			return c.updateGlobalHubPlacement(originalNamespace, placementName)
+		})
+	}
+}
+
+// DeleteFunc recomputes the status of the global placement without the decisions deleted from a regional hub.
+func (c *placementDecisionController) DeleteFunc() func(stopCh <-chan struct{}, obj interface{}) error {
+	return c.ReconcileFunc()
+}
+
+// updateGlobalHubPlacement sets the clusters selected on every regional hub to the status of the global placement.
+func (c *placementDecisionController) updateGlobalHubPlacement(namespace, name string) error {
+	globalObj, err := c.client.Resource(c.placementGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		klog.Infof("the placement(%s) is not existed in global hub namespace(%s)", name, namespace)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{
+		GlobalHubPolicyNamespaceLabel: namespace,
+		PlacementLabel:                name,
+	})
+	decisions, err := c.client.Resource(c.gvr).Namespace(metav1.NamespaceAll).List(context.TODO(),
+		metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+
+	summary, err := summarizeDecisions(namespace, decisions.Items)
+	if err != nil {
+		return err
+	}
+	summaryMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(summary)
+	if err != nil {
+		return err
+	}
+
+	existing, _, err := unstructured.NestedMap(globalObj.Object, "status", "decisionSummary")
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existing, summaryMap) {
+		return nil
+	}
+	if err := unstructured.SetNestedMap(globalObj.Object, summaryMap, "status", "decisionSummary"); err != nil {
+		return err
+	}
+	if err := unstructured.SetNestedField(globalObj.Object, int64(summary.NumberOfSelectedClusters),
+		"status", "numberOfSelectedClusters"); err != nil {
+		return err
+	}
+
+	if _, err := c.client.Resource(c.placementGVR).Namespace(namespace).UpdateStatus(context.TODO(),
+		globalObj, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	klog.Infof("updated global placement: %s/%s with %d selected clusters", namespace, name, summary.NumberOfSelectedClusters)
+	return nil
+}
+
+// summarizeDecisions groups the selected clusters by regional hub, the namespace of the reported decisions.
+func summarizeDecisions(namespace string, decisions []unstructured.Unstructured) (*DecisionSummary, error) {
+	clustersByHub := map[string][]string{}
+	for _, decision := range decisions {
+		hub := decision.GetNamespace()
+		if hub == namespace {
+			continue
+		}
+		items, _, err := unstructured.NestedSlice(decision.Object, "status", "decisions")
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := clustersByHub[hub]; !ok {
+			clustersByHub[hub] = []string{}
+		}
+		for _, item := range items {
+			clusterDecision, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// the empty decisions are not scheduled yet
+			if cluster, _, _ := unstructured.NestedString(clusterDecision, "clusterName"); cluster != "" {
+				clustersByHub[hub] = append(clustersByHub[hub], cluster)
+			}
+		}
+	}
+
+	summary := &DecisionSummary{}
+	for hub, clusters := range clustersByHub {
+		sort.Strings(clusters)
+		summary.NumberOfSelectedClusters += int32(len(clusters))
+		summary.Summaries = append(summary.Summaries, HubDecisions{
+			Name:                     hub,
+			NumberOfSelectedClusters: int32(len(clusters)),
+			Clusters:                 clusters,
+		})
+	}
+	sort.Slice(summary.Summaries, func(i, j int) bool { return summary.Summaries[i].Name < summary.Summaries[j].Name })
+	return summary, nil
+}
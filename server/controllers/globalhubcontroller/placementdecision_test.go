@@ -0,0 +1,76 @@
+package globalhubcontroller_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/controllers/globalhubcontroller"
+)
+
+func TestPlacementDecisionDeleted(t *testing.T) {
+	placementGVR := schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placements"}
+	decisionGVR := schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placementdecisions"}
+	placement := &unstructured.Unstructured{}
+	placement.SetAPIVersion("cluster.open-cluster-management.io/v1beta1")
+	placement.SetKind("Placement")
+	placement.SetNamespace("default")
+	placement.SetName("placement1")
+	newDecision := func(hub string, clusters ...string) *unstructured.Unstructured {
+		decision := &unstructured.Unstructured{}
+		decision.SetAPIVersion("cluster.open-cluster-management.io/v1beta1")
+		decision.SetKind("PlacementDecision")
+		decision.SetNamespace(hub)
+		decision.SetName("placement1-decision-1")
+		decision.SetLabels(map[string]string{
+			globalhubcontroller.GlobalHubPolicyNamespaceLabel: "default",
+			globalhubcontroller.PlacementLabel:                "placement1",
+		})
+		decisions := []interface{}{}
+		for _, cluster := range clusters {
+			decisions = append(decisions, map[string]interface{}{"clusterName": cluster})
+		}
+		decision.Object["status"] = map[string]interface{}{"decisions": decisions}
+		return decision
+	}
+
+	client := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{placementGVR: "PlacementList", decisionGVR: "PlacementDecisionList"},
+		placement, newDecision("hub1", "cluster1"), newDecision("hub2", "cluster2", "cluster3"))
+	informerFactory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go globalhubcontroller.NewGenericController(stopCh, client, informerFactory,
+		globalhubcontroller.NewPlacementDecisionController(client), nil).Run(1)
+
+	selectedClusters := func(expected int64) error {
+		return wait.PollImmediate(10*time.Millisecond, 10*time.Second, func() (bool, error) {
+			obj, err := client.Resource(placementGVR).Namespace("default").Get(context.TODO(), "placement1", metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			selected, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberOfSelectedClusters")
+			return selected == expected, nil
+		})
+	}
+	if err := selectedClusters(3); err != nil {
+		t.Fatalf("expected the clusters of hub1 and hub2 to be selected: %v", err)
+	}
+
+	// the clusters of the regional hub are removed from the global placement with its decision
+	if err := client.Resource(decisionGVR).Namespace("hub2").Delete(context.TODO(), "placement1-decision-1",
+		metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := selectedClusters(1); err != nil {
+		t.Errorf("expected only the clusters of hub1 to be selected: %v", err)
+	}
+}
@@ -4,21 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	policyv1 "open-cluster-management.io/governance-policy-propagator/api/v1"
+
+	"github.com/clyang82/multicluster-global-hub-lite/apis"
+	"github.com/clyang82/multicluster-global-hub-lite/server/compliancehistory"
 )
 
-const GlobalHubPolicyNamespaceLabel = "global-hub.open-cluster-management.io/original-namespace"
+const GlobalHubPolicyNamespaceLabel = apis.GlobalHubPolicyNamespaceLabel
 
 // ComplianceSummary ComplianceSummary `json:"complianceSummary,omitempty"` // used by global policy
 type ComplianceSummary struct {
@@ -36,15 +42,24 @@ type ClusterSummary struct {
 type policyController struct {
 	client    dynamic.Interface
 	policyGVR schema.GroupVersionResource
+	// history records the compliance changes of the global policies when it is set
+	history *compliancehistory.Store
+	// recorder records the status aggregation on the global policies when it is set
+	recorder record.EventRecorder
 }
 
-func NewPolicyController(dynamicClient dynamic.Interface) IController {
+func NewPolicyController(dynamicClient dynamic.Interface, history *compliancehistory.Store) IController {
 	return &policyController{
 		client:    dynamicClient,
 		policyGVR: policyv1.SchemeGroupVersion.WithResource("policies"),
+		history:   history,
 	}
 }
 
+func (c *policyController) SetEventRecorder(recorder record.EventRecorder) {
+	c.recorder = recorder
+}
+
 func (c *policyController) GetName() string {
 	return "policy-controller"
 }
@@ -103,8 +118,61 @@ func (c *policyController) ReconcileFunc() func(stopCh <-chan struct{}, obj inte
 		}); err != nil {
 			return err
 		}
+		return c.recordCompliance(syncerPolicy, originalNamespace)
+	}
+}
+
+// recordCompliance records the compliance of the global policy on the regional hub and its managed clusters, the
+// history keeps the changes from the time they are seen by the global hub.
+func (c *policyController) recordCompliance(syncerPolicy *policyv1.Policy, originalNamespace string) error {
+	if c.history == nil {
 		return nil
 	}
+	now := time.Now()
+	hubState := ""
+	records := []compliancehistory.Record{}
+	for _, cluster := range syncerPolicy.Status.Status {
+		if cluster == nil {
+			continue
+		}
+		// the regional hub is non compliant when a managed cluster is, pending when one is pending and none is non
+		// compliant, like the views of the global policies
+		state := string(cluster.ComplianceState)
+		switch state {
+		case string(policyv1.NonCompliant):
+			hubState = state
+		case string(policyv1.Compliant):
+			if hubState == "" {
+				hubState = state
+			}
+		default:
+			state = "Pending"
+			if hubState != string(policyv1.NonCompliant) {
+				hubState = state
+			}
+		}
+		records = append(records, compliancehistory.Record{
+			Time:            now,
+			Namespace:       originalNamespace,
+			Policy:          syncerPolicy.GetName(),
+			Hub:             syncerPolicy.GetNamespace(),
+			Cluster:         cluster.ClusterName,
+			ComplianceState: state,
+		})
+	}
+	if hubState != "" {
+		records = append(records, compliancehistory.Record{
+			Time:            now,
+			Namespace:       originalNamespace,
+			Policy:          syncerPolicy.GetName(),
+			Hub:             syncerPolicy.GetNamespace(),
+			ComplianceState: hubState,
+		})
+	}
+	if err := c.history.Record(records); err != nil {
+		return fmt.Errorf("failed to record the compliance history of the policy(%s/%s): %w", originalNamespace, syncerPolicy.GetName(), err)
+	}
+	return nil
 }
 
 func (c *policyController) updateGlobalHubPolicy(stopCh <-chan struct{}, syncerPolicy *policyv1.Policy, originalNamespace string) error {
@@ -153,6 +221,11 @@ func (c *policyController) updateGlobalHubPolicy(stopCh <-chan struct{}, syncerP
 	for index, complianceSummary := range policyComplianceSummary.Summaries {
 		if newClusterSummary.Name == complianceSummary.Name {
 			exist = true
+			if complianceSummary.Compliant == newClusterSummary.Compliant &&
+				complianceSummary.NonCompliant == newClusterSummary.NonCompliant {
+				// the resync and the changes of the other fields of the syncer policy don't change the compliance
+				return nil
+			}
 			policyComplianceSummary.Compliant += (newClusterSummary.Compliant - complianceSummary.Compliant)
 			policyComplianceSummary.NonCompliant += (newClusterSummary.NonCompliant - complianceSummary.NonCompliant)
 			policyComplianceSummary.Summaries[index].Compliant = newClusterSummary.Compliant
@@ -183,5 +256,10 @@ func (c *policyController) updateGlobalHubPolicy(stopCh <-chan struct{}, syncerP
 	}
 
 	klog.Infof("updated global policy: %s/%s by syncer policy: %s/%s", globalObj.GetNamespace(), globalObj.GetName(), syncerPolicy.GetNamespace(), syncerPolicy.GetName())
+	if c.recorder != nil {
+		c.recorder.Eventf(globalObj, corev1.EventTypeNormal, StatusAggregatedReason,
+			"Aggregated the compliance of the regional hub %s: %d compliant, %d non compliant clusters",
+			syncerPolicy.GetNamespace(), newClusterSummary.Compliant, newClusterSummary.NonCompliant)
+	}
 	return nil
 }
@@ -62,7 +62,7 @@ func TestMain(m *testing.M) {
 
 func TestPolicySummary(t *testing.T) {
 	// 1. get the reconcile function
-	policyController := globalhubcontroller.NewPolicyController(client)
+	policyController := globalhubcontroller.NewPolicyController(client, nil)
 	reconcileFunc := policyController.ReconcileFunc()
 
 	if err := createNamespace(context.TODO(), "hub1"); err != nil {
@@ -0,0 +1,317 @@
+package globalhubcontroller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+
+	hubcontrolplanev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/hubcontrolplane/v1alpha1"
+)
+
+// propagatedGVRs are the resources synced to the regional hubs which report their propagation in their status, the
+// channels have no status subresource and the status of the globalmanagedclustersets is owned by their controller.
+var propagatedGVRs = []schema.GroupVersionResource{
+	{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"},
+	{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "placementbindings"},
+	{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "placementrules"},
+	{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placements"},
+	{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "subscriptions"},
+	{Group: "app.k8s.io", Version: "v1beta1", Resource: "applications"},
+}
+
+// HubPropagation is the state of a global object on a regional hub
+type HubPropagation struct {
+	Hub                string `json:"hub"`
+	State              string `json:"state"`
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"`
+	Message            string `json:"message,omitempty"`
+}
+
+// propagationKey is a global hub object
+type propagationKey struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+// PropagationController sets status.propagation of the global hub objects from the outcome of their applies reported
+// by the syncers in the hubcontrolplanes of the regional hubs.
+type PropagationController struct {
+	stopCh      <-chan struct{}
+	client      dynamic.Interface
+	hubInformer cache.SharedIndexInformer
+	informers   map[schema.GroupVersionResource]cache.SharedIndexInformer
+	queue       workqueue.RateLimitingInterface
+	// generationTimes are the times the generations of the global hub objects were seen, the regional hubs reporting
+	// after that time without the object applied it
+	lock            sync.Mutex
+	generationTimes map[propagationKey]time.Time
+}
+
+func NewPropagationController(stopChannel <-chan struct{}, client dynamic.Interface,
+	informerFactory dynamicinformer.DynamicSharedInformerFactory) *PropagationController {
+	c := &PropagationController{
+		stopCh:      stopChannel,
+		client:      client,
+		hubInformer: informerFactory.ForResource(hubControlPlaneGVR).Informer(),
+		informers:   map[schema.GroupVersionResource]cache.SharedIndexInformer{},
+		queue:       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "propagation-controller"),
+
+		generationTimes: map[propagationKey]time.Time{},
+	}
+
+	for _, gvr := range propagatedGVRs {
+		gvr := gvr
+		informer := informerFactory.ForResource(gvr).Informer()
+		// the status updates of the controller keep the generation
+		informer.AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+					// the time of the generation of the objects listed at startup is unknown, their creation is the earliest
+					if unObj, ok := obj.(*unstructured.Unstructured); ok {
+						c.setGenerationTime(gvr, unObj, unObj.GetCreationTimestamp().Time)
+					}
+					c.enqueue(gvr, obj)
+				},
+				UpdateFunc: func(old, obj interface{}) {
+					oldObj, ok := old.(*unstructured.Unstructured)
+					newObj, ok2 := obj.(*unstructured.Unstructured)
+					if ok && ok2 && oldObj.GetGeneration() == newObj.GetGeneration() {
+						return
+					}
+					if ok2 {
+						c.setGenerationTime(gvr, newObj, time.Now())
+					}
+					c.enqueue(gvr, obj)
+				},
+				DeleteFunc: func(obj interface{}) {
+					if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+						obj = tombstone.Obj
+					}
+					if unObj, ok := obj.(*unstructured.Unstructured); ok {
+						c.lock.Lock()
+						delete(c.generationTimes, propagationKey{gvr: gvr, namespace: unObj.GetNamespace(), name: unObj.GetName()})
+						c.lock.Unlock()
+					}
+				},
+			},
+		)
+		c.informers[gvr] = informer
+	}
+
+	c.hubInformer.AddEventHandler(
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(_ interface{}) {
+				c.enqueueAll()
+			},
+			UpdateFunc: func(old, obj interface{}) {
+				oldHub, err := toHubControlPlane(old)
+				if err != nil {
+					return
+				}
+				newHub, err := toHubControlPlane(obj)
+				if err != nil || equality.Semantic.DeepEqual(oldHub.Status.PropagationTime, newHub.Status.PropagationTime) &&
+					equality.Semantic.DeepEqual(oldHub.Status.Propagation, newHub.Status.Propagation) {
+					return
+				}
+				c.enqueueAll()
+			},
+			DeleteFunc: func(_ interface{}) {
+				c.enqueueAll()
+			},
+		},
+	)
+	return c
+}
+
+func (c *PropagationController) Run(numThreads int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	go c.hubInformer.Run(c.stopCh)
+	synced := []cache.InformerSynced{c.hubInformer.HasSynced}
+	for _, informer := range c.informers {
+		go informer.Run(c.stopCh)
+		synced = append(synced, informer.HasSynced)
+	}
+	if !cache.WaitForCacheSync(c.stopCh, synced...) {
+		klog.Info("Timed out waiting for caches to sync")
+		return
+	}
+
+	klog.Infof("Starting propagation controller")
+	defer klog.Infof("Shutting down propagation controller")
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(c.startWorker, time.Second, c.stopCh)
+	}
+
+	<-c.stopCh
+}
+
+func (c *PropagationController) enqueue(gvr schema.GroupVersionResource, obj interface{}) {
+	unObj, ok := obj.(*unstructured.Unstructured)
+	if !ok || !isGlobalHubObject(unObj) {
+		return
+	}
+	c.queue.Add(propagationKey{gvr: gvr, namespace: unObj.GetNamespace(), name: unObj.GetName()})
+}
+
+func (c *PropagationController) setGenerationTime(gvr schema.GroupVersionResource, obj *unstructured.Unstructured,
+	generationTime time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generationTimes[propagationKey{gvr: gvr, namespace: obj.GetNamespace(), name: obj.GetName()}] = generationTime
+}
+
+func (c *PropagationController) enqueueAll() {
+	for gvr, informer := range c.informers {
+		for _, obj := range informer.GetStore().List() {
+			c.enqueue(gvr, obj)
+		}
+	}
+}
+
+func (c *PropagationController) startWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *PropagationController) processNextWorkItem() bool {
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(propagationKey)
+	defer c.queue.Done(key)
+	if err := c.process(key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("propagation controller failed to sync %s %s/%s, err: %w",
+			key.gvr.Resource, key.namespace, key.name, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *PropagationController) process(key propagationKey) error {
+	item, exists, err := c.informers[key.gvr].GetStore().GetByKey(key.namespace + "/" + key.name)
+	if err != nil || !exists {
+		return err
+	}
+	globalObj, ok := item.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", item)
+	}
+
+	hubs := []*hubcontrolplanev1alpha1.HubControlPlane{}
+	for _, obj := range c.hubInformer.GetStore().List() {
+		hubControlPlane, err := toHubControlPlane(obj)
+		if err != nil {
+			return err
+		}
+		hubs = append(hubs, hubControlPlane)
+	}
+
+	c.lock.Lock()
+	generationTime := c.generationTimes[key]
+	c.lock.Unlock()
+	propagation := PropagationStatus(hubs, key.gvr, globalObj, generationTime)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return c.updatePropagation(key, propagation)
+	})
+}
+
+// updatePropagation sets the propagation in the status of the global hub object when it changed.
+func (c *PropagationController) updatePropagation(key propagationKey, propagation []HubPropagation) error {
+	globalObj, err := c.client.Resource(key.gvr).Namespace(key.namespace).Get(context.TODO(), key.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	existing, _, err := unstructured.NestedSlice(globalObj.Object, "status", "propagation")
+	if err != nil {
+		return err
+	}
+	updated := make([]interface{}, 0, len(propagation))
+	for _, hubPropagation := range propagation {
+		item := map[string]interface{}{
+			"hub":   hubPropagation.Hub,
+			"state": hubPropagation.State,
+		}
+		if hubPropagation.ObservedGeneration != 0 {
+			item["observedGeneration"] = hubPropagation.ObservedGeneration
+		}
+		if hubPropagation.Message != "" {
+			item["message"] = hubPropagation.Message
+		}
+		updated = append(updated, item)
+	}
+	if len(existing) == 0 && len(updated) == 0 || equality.Semantic.DeepEqual(existing, updated) {
+		return nil
+	}
+
+	if err := unstructured.SetNestedSlice(globalObj.Object, updated, "status", "propagation"); err != nil {
+		return err
+	}
+	if _, err := c.client.Resource(key.gvr).Namespace(key.namespace).UpdateStatus(context.TODO(), globalObj, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	klog.V(2).Infof("updated the propagation of %s %s/%s", key.gvr.Resource, key.namespace, key.name)
+	return nil
+}
+
+// PropagationStatus returns the state of the global hub object on every regional hub sorted by hub. The syncers report
+// the objects not applied at their last generation, the object is Pending on a regional hub until its syncer reports
+// without it after generationTime, the time its generation was seen, then Applied. The object reported by the syncer
+// is Pending until the syncer applies its current generation, then Applied or Failed.
+func PropagationStatus(hubs []*hubcontrolplanev1alpha1.HubControlPlane, gvr schema.GroupVersionResource,
+	globalObj *unstructured.Unstructured, generationTime time.Time) []HubPropagation {
+	propagation := []HubPropagation{}
+	for _, hub := range hubs {
+		hubPropagation := HubPropagation{Hub: hub.Name, State: hubcontrolplanev1alpha1.PropagationPending}
+		reported := false
+		for _, report := range hub.Status.Propagation {
+			if report.Group != gvr.Group || report.Resource != gvr.Resource ||
+				report.Namespace != globalObj.GetNamespace() || report.Name != globalObj.GetName() {
+				continue
+			}
+			reported = true
+			hubPropagation.ObservedGeneration = report.Generation
+			if report.Generation >= globalObj.GetGeneration() {
+				hubPropagation.State = report.State
+				hubPropagation.Message = report.Message
+			}
+			break
+		}
+		if !reported && hub.Status.PropagationTime != nil && hub.Status.PropagationOmitted == 0 &&
+			!hub.Status.PropagationTime.Time.Before(generationTime) {
+			hubPropagation.State = hubcontrolplanev1alpha1.PropagationApplied
+			hubPropagation.ObservedGeneration = globalObj.GetGeneration()
+		}
+		propagation = append(propagation, hubPropagation)
+	}
+	sort.Slice(propagation, func(i, j int) bool {
+		return propagation[i].Hub < propagation[j].Hub
+	})
+	return propagation
+}
@@ -0,0 +1,70 @@
+package globalhubcontroller_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	hubcontrolplanev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/hubcontrolplane/v1alpha1"
+	"github.com/clyang82/multicluster-global-hub-lite/server/controllers/globalhubcontroller"
+)
+
+func TestPropagationStatus(t *testing.T) {
+	policyGVR := schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}
+	policy := &unstructured.Unstructured{}
+	policy.SetNamespace("default")
+	policy.SetName("policy1")
+	policy.SetGeneration(2)
+
+	report := func(generation int64, state, message string) hubcontrolplanev1alpha1.PropagationReport {
+		return hubcontrolplanev1alpha1.PropagationReport{
+			Group:      policyGVR.Group,
+			Resource:   policyGVR.Resource,
+			Namespace:  "default",
+			Name:       "policy1",
+			Generation: generation,
+			State:      state,
+			Message:    message,
+		}
+	}
+	generationTime := time.Date(2022, time.August, 1, 12, 0, 0, 0, time.UTC)
+	reportedAfter := metav1.NewTime(generationTime.Add(time.Second))
+	reportedBefore := metav1.NewTime(generationTime.Add(-time.Second))
+	hub := func(name string, reportTime *metav1.Time, omitted int32,
+		reports ...hubcontrolplanev1alpha1.PropagationReport) *hubcontrolplanev1alpha1.HubControlPlane {
+		return &hubcontrolplanev1alpha1.HubControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: hubcontrolplanev1alpha1.HubControlPlaneStatus{
+				Propagation:        reports,
+				PropagationTime:    reportTime,
+				PropagationOmitted: omitted,
+			},
+		}
+	}
+
+	hubs := []*hubcontrolplanev1alpha1.HubControlPlane{
+		hub("hub4", &reportedAfter, 0, report(2, hubcontrolplanev1alpha1.PropagationFailed, "admission webhook denied the request")),
+		hub("hub1", &reportedAfter, 0),
+		hub("hub2", &reportedAfter, 0, report(1, hubcontrolplanev1alpha1.PropagationPending, "")),
+		hub("hub3", nil, 0),
+		hub("hub5", &reportedBefore, 0),
+		hub("hub6", &reportedAfter, 1),
+	}
+	expected := []globalhubcontroller.HubPropagation{
+		{Hub: "hub1", State: hubcontrolplanev1alpha1.PropagationApplied, ObservedGeneration: 2},
+		{Hub: "hub2", State: hubcontrolplanev1alpha1.PropagationPending, ObservedGeneration: 1},
+		{Hub: "hub3", State: hubcontrolplanev1alpha1.PropagationPending},
+		{Hub: "hub4", State: hubcontrolplanev1alpha1.PropagationFailed, ObservedGeneration: 2,
+			Message: "admission webhook denied the request"},
+		{Hub: "hub5", State: hubcontrolplanev1alpha1.PropagationPending},
+		{Hub: "hub6", State: hubcontrolplanev1alpha1.PropagationPending},
+	}
+	propagation := globalhubcontroller.PropagationStatus(hubs, policyGVR, policy, generationTime)
+	if !reflect.DeepEqual(propagation, expected) {
+		t.Errorf("expected %v, got %v", expected, propagation)
+	}
+}
@@ -0,0 +1,133 @@
+package globalhubcontroller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	statusbundlev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/statusbundle/v1alpha1"
+	"github.com/clyang82/multicluster-global-hub-lite/syncer"
+)
+
+// statusBundleController unpacks the StatusBundles written by the syncers into the copies of the objects in the
+// namespaces of the regional hubs, the same copies the syncers write one by one without bundles.
+type statusBundleController struct {
+	client dynamic.Interface
+	gvr    schema.GroupVersionResource
+
+	lock sync.Mutex
+	// applied are the hashes of the objects applied from every bundle, only the objects changed since are applied
+	applied map[string]map[string][32]byte
+}
+
+func NewStatusBundleController(dynamicClient dynamic.Interface) IController {
+	return &statusBundleController{
+		client:  dynamicClient,
+		gvr:     statusbundlev1alpha1.GroupVersion.WithResource("statusbundles"),
+		applied: map[string]map[string][32]byte{},
+	}
+}
+
+func (c *statusBundleController) GetName() string {
+	return "statusbundle-controller"
+}
+
+func (c *statusBundleController) GetGVR() schema.GroupVersionResource {
+	return c.gvr
+}
+
+func (c *statusBundleController) CreateInstanceFunc() func() client.Object {
+	return func() client.Object {
+		return &unstructured.Unstructured{}
+	}
+}
+
+func (c *statusBundleController) ReconcileFunc() func(stopCh <-chan struct{}, obj interface{}) error {
+	return func(stopCh <-chan struct{}, obj interface{}) error {
+		unObj, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("cann't convert obj(%+v) to *unstructured.Unstructured", obj)
+		}
+		statusBundle := &statusbundlev1alpha1.StatusBundle{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unObj.UnstructuredContent(), statusBundle); err != nil {
+			return err
+		}
+
+		bundle, err := syncer.DecodeBundle(statusBundle.Spec.Objects)
+		if err != nil {
+			klog.Errorf("failed to decode the statusbundle(%s/%s): %v", statusBundle.Namespace, statusBundle.Name, err)
+			return nil
+		}
+		source := schema.GroupVersionResource{
+			Group:    statusBundle.Spec.Source.Group,
+			Version:  statusBundle.Spec.Source.Version,
+			Resource: statusBundle.Spec.Source.Resource,
+		}
+		if bundle.GVR() != source {
+			klog.Errorf("reject the statusbundle(%s/%s) of %s with the objects of %s", statusBundle.Namespace, statusBundle.Name,
+				source.Resource, bundle.GVR().Resource)
+			return nil
+		}
+		// the objects of a bundle are only applied to the namespace of its regional hub
+		bundle.Hub = statusBundle.Namespace
+		return c.unpack(context.TODO(), statusBundle.Namespace+"/"+statusBundle.Name, bundle)
+	}
+}
+
+// unpack applies the objects of the bundle changed since they were last applied. Only the resources reported by the
+// status syncers are unpacked, in the namespace of the regional hub or, for the managedclusters and the
+// hubcontrolplane, cluster scoped.
+func (c *statusBundleController) unpack(ctx context.Context, key string, bundle *syncer.Bundle) error {
+	if err := syncer.CheckStatusBundle(bundle.Hub, bundle); err != nil {
+		klog.Errorf("reject the statusbundle(%s): %v", key, err)
+		return nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	applied, ok := c.applied[key]
+	if !ok {
+		applied = map[string][32]byte{}
+	}
+	current := map[string][32]byte{}
+	errs := []error{}
+	changed := 0
+	for _, bundleObj := range bundle.Objects {
+		if bundleObj.Object == nil {
+			continue
+		}
+		data, err := json.Marshal(bundleObj)
+		if err != nil {
+			return err
+		}
+		objKey := bundleObj.Namespace + "/" + bundleObj.Name
+		hash := sha256.Sum256(data)
+		if previous, ok := applied[objKey]; ok && previous == hash {
+			current[objKey] = hash
+			continue
+		}
+		if err := syncer.ApplyBundleObject(ctx, c.client, bundle.Hub, bundle.GVR(), bundleObj); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		current[objKey] = hash
+		changed++
+	}
+	c.applied[key] = current
+
+	if changed > 0 {
+		klog.Infof("applied %d of the %d %s in the statusbundle(%s) of %s", changed, len(bundle.Objects), bundle.Resource, key, bundle.Hub)
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,72 @@
+package globalhubcontroller_test
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+
+	statusbundlev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/statusbundle/v1alpha1"
+	"github.com/clyang82/multicluster-global-hub-lite/server/controllers/globalhubcontroller"
+	"github.com/clyang82/multicluster-global-hub-lite/syncer"
+)
+
+func TestStatusBundleUnpack(t *testing.T) {
+	policyGVR := schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}
+	clusterRoleBindingGVR := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"}
+
+	newStatusBundle := func(source schema.GroupVersionResource, bundle *syncer.Bundle) *unstructured.Unstructured {
+		data, err := syncer.EncodeBundle(bundle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statusBundle := &statusbundlev1alpha1.StatusBundle{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "hub1", Name: syncer.StatusBundleName(source, 0)},
+			Spec: statusbundlev1alpha1.StatusBundleSpec{
+				Hub: "hub1",
+				Source: statusbundlev1alpha1.BundledResource{
+					Group:    source.Group,
+					Version:  source.Version,
+					Resource: source.Resource,
+				},
+				Objects: data,
+			},
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(statusBundle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &unstructured.Unstructured{Object: content}
+	}
+	newBundle := func(gvr schema.GroupVersionResource, kind, namespace, name string) *syncer.Bundle {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(gvr.GroupVersion().String())
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		return &syncer.Bundle{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource,
+			Objects: []syncer.BundleObject{{Namespace: namespace, Name: name, Object: obj}}}
+	}
+
+	for _, test := range []struct {
+		name         string
+		statusBundle *unstructured.Unstructured
+		applied      bool
+	}{
+		{"policy of the hub", newStatusBundle(policyGVR, newBundle(policyGVR, "Policy", "hub1", "policy1")), true},
+		{"policy of another hub", newStatusBundle(policyGVR, newBundle(policyGVR, "Policy", "hub2", "policy1")), false},
+		{"clusterrolebinding", newStatusBundle(clusterRoleBindingGVR, newBundle(clusterRoleBindingGVR, "ClusterRoleBinding", "", "admin")), false},
+		{"clusterrolebinding of a policy bundle", newStatusBundle(policyGVR, newBundle(clusterRoleBindingGVR, "ClusterRoleBinding", "", "admin")), false},
+	} {
+		client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+		reconcile := globalhubcontroller.NewStatusBundleController(client).ReconcileFunc()
+		// the fake client does not support server-side apply, the objects unpacked fail to be applied
+		err := reconcile(nil, test.statusBundle)
+		if applied := len(client.Actions()) > 0; applied != test.applied {
+			t.Errorf("%s: expected applied %v, got %d actions (%v)", test.name, test.applied, len(client.Actions()), err)
+		}
+	}
+}
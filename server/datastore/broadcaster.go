@@ -0,0 +1,82 @@
+package datastore
+
+import (
+	"sync"
+)
+
+// subscriberBufferSize is the number of changes a watcher may lag behind before it is canceled.
+const subscriberBufferSize = 1024
+
+// subscriber receives the changes of the keys in [start, end).
+type subscriber struct {
+	start, end string
+	events     chan *row
+	// overflowed is closed when the subscriber couldn't keep up, the watch must be canceled
+	overflowed chan struct{}
+	once       sync.Once
+}
+
+func (s *subscriber) matches(key string) bool {
+	switch s.end {
+	case "":
+		return key == s.start
+	case "\x00":
+		return key >= s.start
+	default:
+		return key >= s.start && key < s.end
+	}
+}
+
+// broadcaster fans the committed rows out to the watchers.
+type broadcaster struct {
+	lock        sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subscribers: map[*subscriber]struct{}{}}
+}
+
+func (b *broadcaster) subscribe(start, end string) *subscriber {
+	sub := &subscriber{
+		start:      start,
+		end:        end,
+		events:     make(chan *row, subscriberBufferSize),
+		overflowed: make(chan struct{}),
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (b *broadcaster) unsubscribe(sub *subscriber) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.subscribers, sub)
+}
+
+// publish never blocks the writer, a subscriber with a full buffer is flagged as overflowed instead.
+func (b *broadcaster) publish(r *row) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for sub := range b.subscribers {
+		if !sub.matches(r.name) {
+			continue
+		}
+		select {
+		case sub.events <- r:
+		default:
+			sub.once.Do(func() { close(sub.overflowed) })
+		}
+	}
+}
+
+func (b *broadcaster) close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for sub := range b.subscribers {
+		sub.once.Do(func() { close(sub.overflowed) })
+		delete(b.subscribers, sub)
+	}
+}
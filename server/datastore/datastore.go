@@ -0,0 +1,74 @@
+// Package datastore serves the etcd v3 API on top of a SQL database, so the apiserver can keep the
+// resources in sqlite or postgres instead of etcd.
+package datastore
+
+import (
+	"context"
+	"net"
+
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
+)
+
+// etcdVersion is the etcd version reported to the clients.
+const etcdVersion = "3.5.1"
+
+// ClientInfo describes how to connect to the datastore.
+type ClientInfo struct {
+	Endpoints []string
+}
+
+// Listen opens the datastore and serves the etcd API on a local port until the context is done.
+func Listen(ctx context.Context, endpoint string) (ClientInfo, error) {
+	klog.Info("Creating SQL datastore")
+	s, err := newStore(ctx, endpoint)
+	if err != nil {
+		return ClientInfo{}, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		s.close()
+		return ClientInfo{}, err
+	}
+
+	server := grpc.NewServer()
+	etcdserverpb.RegisterKVServer(server, &kvServer{store: s})
+	etcdserverpb.RegisterWatchServer(server, &watchServer{store: s})
+	etcdserverpb.RegisterLeaseServer(server, &leaseServer{store: s})
+	etcdserverpb.RegisterMaintenanceServer(server, &maintenanceServer{store: s})
+
+	go s.runExpiry(ctx)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			klog.Errorf("Datastore server stopped: %v", err)
+		}
+	}()
+	// Shutdown when context is closed
+	go func() {
+		<-ctx.Done()
+		server.Stop()
+		if err := s.close(); err != nil {
+			klog.Errorf("Failed to close the datastore: %v", err)
+		}
+	}()
+
+	return ClientInfo{Endpoints: []string{"http://" + listener.Addr().String()}}, nil
+}
+
+// maintenanceServer answers the status requests, used by the apiserver health checks.
+type maintenanceServer struct {
+	etcdserverpb.UnimplementedMaintenanceServer
+	store *store
+}
+
+func (m *maintenanceServer) Status(ctx context.Context, req *etcdserverpb.StatusRequest) (*etcdserverpb.StatusResponse, error) {
+	return &etcdserverpb.StatusResponse{
+		Header:    header(m.store),
+		Version:   etcdVersion,
+		Leader:    1,
+		RaftIndex: uint64(m.store.currentRevision()),
+		RaftTerm:  1,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package datastore_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/datastore"
+)
+
+func TestDatastore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clientInfo, err := datastore.Listen(ctx, "sqlite://"+filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("failed to start the datastore: %v", err)
+	}
+	client, err := clientv3.New(clientv3.Config{Endpoints: clientInfo.Endpoints, DialTimeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("failed to connect to the datastore: %v", err)
+	}
+	defer client.Close()
+
+	watchCh := client.Watch(ctx, "/registry/", clientv3.WithPrefix(), clientv3.WithPrevKV())
+
+	// create the key the way the kube storage does
+	key := "/registry/policies/default/policy1"
+	txn, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, "v1")).
+		Commit()
+	if err != nil || !txn.Succeeded {
+		t.Fatalf("failed to create the key: %v", err)
+	}
+
+	get, err := client.Get(ctx, key)
+	if err != nil || len(get.Kvs) != 1 || string(get.Kvs[0].Value) != "v1" {
+		t.Fatalf("unexpected get result %v: %v", get, err)
+	}
+	modRevision := get.Kvs[0].ModRevision
+
+	// a create of an existing key fails
+	txn, err = client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, "v1")).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	if err != nil || txn.Succeeded {
+		t.Fatalf("expected the create to fail: %v", err)
+	}
+
+	// update guarded by the mod revision
+	txn, err = client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", modRevision)).
+		Then(clientv3.OpPut(key, "v2")).
+		Commit()
+	if err != nil || !txn.Succeeded {
+		t.Fatalf("failed to update the key: %v", err)
+	}
+
+	list, err := client.Get(ctx, "/registry/policies/", clientv3.WithPrefix())
+	if err != nil || list.Count != 1 || list.Kvs[0].Version != 2 || list.Kvs[0].CreateRevision != modRevision {
+		t.Fatalf("unexpected list result %v: %v", list, err)
+	}
+
+	if _, err := client.Delete(ctx, key); err != nil {
+		t.Fatalf("failed to delete the key: %v", err)
+	}
+
+	var events []*clientv3.Event
+	timeout := time.After(10 * time.Second)
+	for len(events) < 3 {
+		select {
+		case resp := <-watchCh:
+			events = append(events, resp.Events...)
+		case <-timeout:
+			t.Fatalf("expected 3 events, got %d", len(events))
+		}
+	}
+	if events[0].Type != mvccpb.PUT || !events[0].IsCreate() ||
+		events[1].Type != mvccpb.PUT || string(events[1].PrevKv.Value) != "v1" ||
+		events[2].Type != mvccpb.DELETE || string(events[2].PrevKv.Value) != "v2" {
+		t.Errorf("unexpected events %v", events)
+	}
+
+	// a watch from an old revision replays the history
+	replay := client.Watch(ctx, key, clientv3.WithRev(modRevision))
+	select {
+	case resp := <-replay:
+		if len(resp.Events) != 3 {
+			t.Errorf("expected 3 replayed events, got %d", len(resp.Events))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("no replayed events")
+	}
+}
@@ -0,0 +1,260 @@
+package datastore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// kvServer implements the subset of the etcd KV API used by the kube storage layer.
+// A transaction may contain several writes, but unlike etcd each of them gets its own revision.
+type kvServer struct {
+	etcdserverpb.UnimplementedKVServer
+	store *store
+}
+
+func header(s *store) *etcdserverpb.ResponseHeader {
+	return &etcdserverpb.ResponseHeader{ClusterId: 1, MemberId: 1, RaftTerm: 1, Revision: s.currentRevision()}
+}
+
+func (k *kvServer) Range(ctx context.Context, req *etcdserverpb.RangeRequest) (*etcdserverpb.RangeResponse, error) {
+	return k.rangeKeys(ctx, req)
+}
+
+func (k *kvServer) Put(ctx context.Context, req *etcdserverpb.PutRequest) (*etcdserverpb.PutResponse, error) {
+	k.store.writeLock.Lock()
+	defer k.store.writeLock.Unlock()
+	return k.put(ctx, req)
+}
+
+func (k *kvServer) DeleteRange(ctx context.Context, req *etcdserverpb.DeleteRangeRequest) (*etcdserverpb.DeleteRangeResponse, error) {
+	k.store.writeLock.Lock()
+	defer k.store.writeLock.Unlock()
+	return k.deleteRange(ctx, req)
+}
+
+func (k *kvServer) Txn(ctx context.Context, req *etcdserverpb.TxnRequest) (*etcdserverpb.TxnResponse, error) {
+	k.store.writeLock.Lock()
+	defer k.store.writeLock.Unlock()
+
+	succeeded := true
+	for _, c := range req.Compare {
+		ok, err := k.compare(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			succeeded = false
+			break
+		}
+	}
+
+	ops := req.Success
+	if !succeeded {
+		ops = req.Failure
+	}
+
+	responses := make([]*etcdserverpb.ResponseOp, 0, len(ops))
+	for _, op := range ops {
+		switch {
+		case op.GetRequestRange() != nil:
+			resp, err := k.rangeKeys(ctx, op.GetRequestRange())
+			if err != nil {
+				return nil, err
+			}
+			responses = append(responses, &etcdserverpb.ResponseOp{Response: &etcdserverpb.ResponseOp_ResponseRange{ResponseRange: resp}})
+		case op.GetRequestPut() != nil:
+			resp, err := k.put(ctx, op.GetRequestPut())
+			if err != nil {
+				return nil, err
+			}
+			responses = append(responses, &etcdserverpb.ResponseOp{Response: &etcdserverpb.ResponseOp_ResponsePut{ResponsePut: resp}})
+		case op.GetRequestDeleteRange() != nil:
+			resp, err := k.deleteRange(ctx, op.GetRequestDeleteRange())
+			if err != nil {
+				return nil, err
+			}
+			responses = append(responses, &etcdserverpb.ResponseOp{Response: &etcdserverpb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: resp}})
+		default:
+			return nil, status.Error(codes.Unimplemented, "nested transactions are not supported by the datastore")
+		}
+	}
+
+	return &etcdserverpb.TxnResponse{Header: header(k.store), Succeeded: succeeded, Responses: responses}, nil
+}
+
+func (k *kvServer) Compact(ctx context.Context, req *etcdserverpb.CompactionRequest) (*etcdserverpb.CompactionResponse, error) {
+	if req.Revision > k.store.currentRevision() {
+		return nil, rpctypes.ErrGRPCFutureRev
+	}
+	if err := k.store.compact(ctx, req.Revision); err != nil {
+		return nil, err
+	}
+	return &etcdserverpb.CompactionResponse{Header: header(k.store)}, nil
+}
+
+func (k *kvServer) rangeKeys(ctx context.Context, req *etcdserverpb.RangeRequest) (*etcdserverpb.RangeResponse, error) {
+	if req.Revision > 0 && req.Revision < k.store.compactRevision() {
+		return nil, rpctypes.ErrGRPCCompacted
+	}
+	if req.Revision > k.store.currentRevision() {
+		return nil, rpctypes.ErrGRPCFutureRev
+	}
+
+	resp := &etcdserverpb.RangeResponse{Header: header(k.store)}
+	var rows []*row
+	if len(req.RangeEnd) == 0 {
+		r, err := k.store.get(ctx, string(req.Key), req.Revision)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			rows = append(rows, r)
+		}
+		resp.Count = int64(len(rows))
+	} else {
+		var err error
+		rows, resp.Count, err = k.store.list(ctx, string(req.Key), string(req.RangeEnd), req.Revision, req.Limit, req.CountOnly)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if req.CountOnly {
+		return resp, nil
+	}
+
+	for _, r := range rows {
+		kv := r.keyValue()
+		if req.KeysOnly {
+			kv.Value = nil
+		}
+		resp.Kvs = append(resp.Kvs, kv)
+	}
+	resp.More = resp.Count > int64(len(resp.Kvs))
+	return resp, nil
+}
+
+// put expects the write lock to be held.
+func (k *kvServer) put(ctx context.Context, req *etcdserverpb.PutRequest) (*etcdserverpb.PutResponse, error) {
+	if req.IgnoreValue || req.IgnoreLease {
+		return nil, status.Error(codes.Unimplemented, "ignore_value and ignore_lease are not supported by the datastore")
+	}
+
+	var expiresAt int64
+	if req.Lease != 0 {
+		ttl, ok := k.store.leases.ttl(req.Lease)
+		if !ok {
+			return nil, rpctypes.ErrGRPCLeaseNotFound
+		}
+		expiresAt = time.Now().Unix() + ttl
+	}
+
+	r, err := k.store.write(ctx, string(req.Key), req.Value, false, req.Lease, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	resp := &etcdserverpb.PutResponse{Header: header(k.store)}
+	if req.PrevKv {
+		resp.PrevKv = r.prevKeyValue()
+	}
+	return resp, nil
+}
+
+// deleteRange expects the write lock to be held.
+func (k *kvServer) deleteRange(ctx context.Context, req *etcdserverpb.DeleteRangeRequest) (*etcdserverpb.DeleteRangeResponse, error) {
+	keys := []string{string(req.Key)}
+	if len(req.RangeEnd) > 0 {
+		rows, _, err := k.store.list(ctx, string(req.Key), string(req.RangeEnd), 0, 0, false)
+		if err != nil {
+			return nil, err
+		}
+		keys = keys[:0]
+		for _, r := range rows {
+			keys = append(keys, r.name)
+		}
+	}
+
+	resp := &etcdserverpb.DeleteRangeResponse{}
+	for _, key := range keys {
+		r, err := k.store.write(ctx, key, nil, true, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			continue
+		}
+		resp.Deleted++
+		if req.PrevKv {
+			resp.PrevKvs = append(resp.PrevKvs, r.prevKeyValue())
+		}
+	}
+	resp.Header = header(k.store)
+	return resp, nil
+}
+
+func (k *kvServer) compare(ctx context.Context, c *etcdserverpb.Compare) (bool, error) {
+	if len(c.RangeEnd) > 0 {
+		return false, status.Error(codes.Unimplemented, "range compares are not supported by the datastore")
+	}
+	r, err := k.store.get(ctx, string(c.Key), 0)
+	if err != nil {
+		return false, err
+	}
+
+	var result int
+	switch c.Target {
+	case etcdserverpb.Compare_VALUE:
+		if r == nil {
+			return false, nil
+		}
+		result = bytes.Compare(r.value, c.GetValue())
+	case etcdserverpb.Compare_VERSION:
+		result = compareInt64(rowField(r, func(r *row) int64 { return r.version }), c.GetVersion())
+	case etcdserverpb.Compare_CREATE:
+		result = compareInt64(rowField(r, (*row).getCreateRevision), c.GetCreateRevision())
+	case etcdserverpb.Compare_MOD:
+		result = compareInt64(rowField(r, func(r *row) int64 { return r.id }), c.GetModRevision())
+	case etcdserverpb.Compare_LEASE:
+		result = compareInt64(rowField(r, func(r *row) int64 { return r.lease }), c.GetLease())
+	default:
+		return false, status.Error(codes.InvalidArgument, fmt.Sprintf("unknown compare target %v", c.Target))
+	}
+
+	switch c.Result {
+	case etcdserverpb.Compare_EQUAL:
+		return result == 0, nil
+	case etcdserverpb.Compare_NOT_EQUAL:
+		return result != 0, nil
+	case etcdserverpb.Compare_GREATER:
+		return result > 0, nil
+	case etcdserverpb.Compare_LESS:
+		return result < 0, nil
+	default:
+		return false, status.Error(codes.InvalidArgument, fmt.Sprintf("unknown compare result %v", c.Result))
+	}
+}
+
+// rowField returns the field of the row, or 0 if the key doesn't exist.
+func rowField(r *row, field func(*row) int64) int64 {
+	if r == nil {
+		return 0
+	}
+	return field(r)
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,165 @@
+package datastore
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// expireInterval is how often the keys attached to an expired lease are deleted.
+const expireInterval = 5 * time.Second
+
+// leases keeps the granted leases in memory, the expiry of each key is persisted along with the key.
+type leases struct {
+	lock   sync.RWMutex
+	nextID int64
+	ttls   map[int64]int64
+}
+
+func newLeases() *leases {
+	return &leases{
+		nextID: time.Now().UnixNano(),
+		ttls:   map[int64]int64{},
+	}
+}
+
+func (l *leases) grant(id, ttl int64) int64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if id == 0 {
+		l.nextID++
+		id = l.nextID
+	}
+	l.ttls[id] = ttl
+	return id
+}
+
+func (l *leases) ttl(id int64) (int64, bool) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	ttl, ok := l.ttls[id]
+	return ttl, ok
+}
+
+func (l *leases) revoke(id int64) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	_, ok := l.ttls[id]
+	delete(l.ttls, id)
+	return ok
+}
+
+func (l *leases) list() []int64 {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	ids := make([]int64, 0, len(l.ttls))
+	for id := range l.ttls {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+type leaseServer struct {
+	etcdserverpb.UnimplementedLeaseServer
+	store *store
+}
+
+func (l *leaseServer) LeaseGrant(ctx context.Context, req *etcdserverpb.LeaseGrantRequest) (*etcdserverpb.LeaseGrantResponse, error) {
+	id := l.store.leases.grant(req.ID, req.TTL)
+	return &etcdserverpb.LeaseGrantResponse{Header: header(l.store), ID: id, TTL: req.TTL}, nil
+}
+
+func (l *leaseServer) LeaseRevoke(ctx context.Context, req *etcdserverpb.LeaseRevokeRequest) (*etcdserverpb.LeaseRevokeResponse, error) {
+	if !l.store.leases.revoke(req.ID) {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+
+	l.store.writeLock.Lock()
+	defer l.store.writeLock.Unlock()
+	rows, err := l.store.query(ctx, "SELECT "+columnsOf("k")+" FROM "+tableName+" k WHERE k.lease = ? AND k.deleted = 0"+
+		" AND k.id = (SELECT MAX(id) FROM "+tableName+" l WHERE l.name = k.name)", req.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		if _, err := l.store.write(ctx, r.name, nil, true, 0, 0); err != nil {
+			return nil, err
+		}
+	}
+	return &etcdserverpb.LeaseRevokeResponse{Header: header(l.store)}, nil
+}
+
+func (l *leaseServer) LeaseKeepAlive(stream etcdserverpb.Lease_LeaseKeepAliveServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp := &etcdserverpb.LeaseKeepAliveResponse{Header: header(l.store), ID: req.ID}
+		if ttl, ok := l.store.leases.ttl(req.ID); ok {
+			if _, err := l.store.db.ExecContext(stream.Context(), l.store.dialect.rebind("UPDATE "+tableName+
+				" SET expires_at = ? WHERE lease = ? AND deleted = 0"), time.Now().Unix()+ttl, req.ID); err != nil {
+				return err
+			}
+			resp.TTL = ttl
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *leaseServer) LeaseTimeToLive(ctx context.Context, req *etcdserverpb.LeaseTimeToLiveRequest) (*etcdserverpb.LeaseTimeToLiveResponse, error) {
+	ttl, ok := l.store.leases.ttl(req.ID)
+	if !ok {
+		return &etcdserverpb.LeaseTimeToLiveResponse{Header: header(l.store), ID: req.ID, TTL: -1}, nil
+	}
+	return &etcdserverpb.LeaseTimeToLiveResponse{Header: header(l.store), ID: req.ID, TTL: ttl, GrantedTTL: ttl}, nil
+}
+
+func (l *leaseServer) LeaseLeases(ctx context.Context, req *etcdserverpb.LeaseLeasesRequest) (*etcdserverpb.LeaseLeasesResponse, error) {
+	resp := &etcdserverpb.LeaseLeasesResponse{Header: header(l.store)}
+	for _, id := range l.store.leases.list() {
+		resp.Leases = append(resp.Leases, &etcdserverpb.LeaseStatus{ID: id})
+	}
+	return resp, nil
+}
+
+// runExpiry deletes the keys whose lease expired until the context is done.
+func (s *store) runExpiry(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		rows, err := s.expired(ctx, time.Now().Unix())
+		if err != nil {
+			klog.Errorf("Failed to list the expired keys: %v", err)
+			return
+		}
+		for _, r := range rows {
+			if err := s.deleteIfExpired(ctx, r); err != nil {
+				klog.Errorf("Failed to delete the expired key %s: %v", r.name, err)
+			}
+		}
+	}, expireInterval)
+}
+
+func (s *store) deleteIfExpired(ctx context.Context, expired *row) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	// the key may have been updated or renewed in the meantime
+	current, err := s.get(ctx, expired.name, 0)
+	if err != nil || current == nil || current.id != expired.id {
+		return err
+	}
+	_, err = s.write(ctx, expired.name, nil, true, 0, 0)
+	return err
+}
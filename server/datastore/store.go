@@ -0,0 +1,318 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"k8s.io/klog/v2"
+)
+
+// initializedKey is written when the datastore is created, it lives outside of the apiserver keys.
+const initializedKey = "/globalhub/datastore/initialized"
+
+const columns = "id, name, created, deleted, create_revision, prev_revision, version, lease, expires_at, value, old_value"
+
+// row is one revision of a key. Every write appends a row, the row id is the etcd revision.
+type row struct {
+	id             int64
+	name           string
+	created        bool
+	deleted        bool
+	createRevision int64
+	prevRevision   int64
+	version        int64
+	lease          int64
+	expiresAt      int64
+	value          []byte
+	oldValue       []byte
+}
+
+// keyValue converts the row to the etcd representation.
+func (r *row) keyValue() *mvccpb.KeyValue {
+	if r.deleted {
+		return &mvccpb.KeyValue{Key: []byte(r.name), ModRevision: r.id}
+	}
+	return &mvccpb.KeyValue{
+		Key:            []byte(r.name),
+		Value:          r.value,
+		CreateRevision: r.getCreateRevision(),
+		ModRevision:    r.id,
+		Version:        r.version,
+		Lease:          r.lease,
+	}
+}
+
+// prevKeyValue rebuilds the key value replaced by this row, nil if the row created the key.
+func (r *row) prevKeyValue() *mvccpb.KeyValue {
+	if r.created {
+		return nil
+	}
+	version := r.version - 1
+	if r.deleted {
+		version = r.version
+	}
+	return &mvccpb.KeyValue{
+		Key:            []byte(r.name),
+		Value:          r.oldValue,
+		CreateRevision: r.createRevision,
+		ModRevision:    r.prevRevision,
+		Version:        version,
+	}
+}
+
+func (r *row) getCreateRevision() int64 {
+	if r.created {
+		return r.id
+	}
+	return r.createRevision
+}
+
+// store is a log structured key value store on top of a SQL database. All the writes go through this
+// process and are serialized, which is what allows the watches to be served without polling the database.
+type store struct {
+	db      *sql.DB
+	dialect *dialect
+
+	// writeLock serializes the writes, so the revisions are committed and published in order
+	writeLock   sync.Mutex
+	currentRev  int64
+	compactRev  int64
+	broadcaster *broadcaster
+	leases      *leases
+}
+
+func newStore(ctx context.Context, endpoint string) (*store, error) {
+	dialect, dataSourceName, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(dialect.driver, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to the datastore: %w", err)
+	}
+	for _, stmt := range dialect.schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("failed to create the datastore schema: %w", err)
+		}
+	}
+
+	s := &store{
+		db:          db,
+		dialect:     dialect,
+		broadcaster: newBroadcaster(),
+		leases:      newLeases(),
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM "+tableName).Scan(&s.currentRev); err != nil {
+		return nil, err
+	}
+	if s.currentRev == 0 {
+		// like etcd, start at revision 1, the apiserver rejects the resource version 0 returned by an empty store
+		if _, err := s.write(ctx, initializedKey, nil, false, 0, 0); err != nil {
+			return nil, err
+		}
+	}
+	// the history before a restart is not tracked, clients watching from older revisions have to relist
+	s.compactRev = s.currentRev
+
+	klog.Infof("Datastore %s started at revision %d", dialect.driver, s.currentRev)
+	return s, nil
+}
+
+func (s *store) currentRevision() int64 {
+	return atomic.LoadInt64(&s.currentRev)
+}
+
+func (s *store) compactRevision() int64 {
+	return atomic.LoadInt64(&s.compactRev)
+}
+
+func (s *store) query(ctx context.Context, query string, args ...interface{}) ([]*row, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*row
+	for rows.Next() {
+		r := &row{}
+		var created, deleted int
+		if err := rows.Scan(&r.id, &r.name, &created, &deleted, &r.createRevision, &r.prevRevision,
+			&r.version, &r.lease, &r.expiresAt, &r.value, &r.oldValue); err != nil {
+			return nil, err
+		}
+		r.created = created == 1
+		r.deleted = deleted == 1
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// get returns the row of the key at the given revision, nil if the key doesn't exist.
+func (s *store) get(ctx context.Context, key string, rev int64) (*row, error) {
+	if rev <= 0 {
+		rev = s.currentRevision()
+	}
+	rows, err := s.query(ctx, "SELECT "+columns+" FROM "+tableName+" WHERE name = ? AND id <= ? ORDER BY id DESC LIMIT 1", key, rev)
+	if err != nil || len(rows) == 0 || rows[0].deleted {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+// list returns the keys in [start, end) at the given revision, up to limit keys, and the total number of keys.
+// An end of "\x00" means all the keys greater or equal to start.
+func (s *store) list(ctx context.Context, start, end string, rev, limit int64, countOnly bool) ([]*row, int64, error) {
+	if rev <= 0 {
+		rev = s.currentRevision()
+	}
+
+	where := "name >= ? AND id <= ?"
+	args := []interface{}{start, rev}
+	if end != "\x00" {
+		where += " AND name < ?"
+		args = append(args, end)
+	}
+	latest := "SELECT " + columnsOf("k") + " FROM " + tableName + " k JOIN (SELECT MAX(id) AS id FROM " + tableName +
+		" WHERE " + where + " GROUP BY name) m ON k.id = m.id WHERE k.deleted = 0"
+
+	var count int64
+	if err := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM ("+latest+") c"), args...).Scan(&count); err != nil {
+		return nil, 0, err
+	}
+	if countOnly {
+		return nil, count, nil
+	}
+
+	query := latest + " ORDER BY k.name"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := s.query(ctx, query, args...)
+	return rows, count, err
+}
+
+// history returns the changes in [start, end) with a revision in [fromRev, toRev], in revision order.
+func (s *store) history(ctx context.Context, start, end string, fromRev, toRev int64) ([]*row, error) {
+	query := "SELECT " + columns + " FROM " + tableName + " WHERE id >= ? AND id <= ?"
+	args := []interface{}{fromRev, toRev}
+	switch end {
+	case "":
+		query += " AND name = ?"
+		args = append(args, start)
+	case "\x00":
+		query += " AND name >= ?"
+		args = append(args, start)
+	default:
+		query += " AND name >= ? AND name < ?"
+		args = append(args, start, end)
+	}
+	return s.query(ctx, query+" ORDER BY id", args...)
+}
+
+// expired returns the live keys whose lease expired before now.
+func (s *store) expired(ctx context.Context, now int64) ([]*row, error) {
+	return s.query(ctx, "SELECT "+columnsOf("k")+" FROM "+tableName+" k WHERE k.expires_at > 0 AND k.expires_at <= ? AND k.deleted = 0"+
+		" AND k.id = (SELECT MAX(id) FROM "+tableName+" l WHERE l.name = k.name)", now)
+}
+
+// write appends a new revision of the key, the caller must hold the write lock.
+// It returns nil if a delete is requested for a key which doesn't exist.
+func (s *store) write(ctx context.Context, key string, value []byte, deleted bool, lease, expiresAt int64) (*row, error) {
+	prev, err := s.get(ctx, key, 0)
+	if err != nil {
+		return nil, err
+	}
+	if deleted && prev == nil {
+		return nil, nil
+	}
+
+	r := &row{
+		name:      key,
+		created:   prev == nil,
+		deleted:   deleted,
+		version:   1,
+		lease:     lease,
+		expiresAt: expiresAt,
+		value:     value,
+	}
+	if prev != nil {
+		r.createRevision = prev.getCreateRevision()
+		r.prevRevision = prev.id
+		r.version = prev.version + 1
+		r.oldValue = prev.value
+		if deleted {
+			r.version = prev.version
+			r.value = nil
+			r.lease = 0
+			r.expiresAt = 0
+		}
+	} else {
+		// the create revision of the created rows is their own id
+		latest, err := s.query(ctx, "SELECT "+columns+" FROM "+tableName+" WHERE name = ? ORDER BY id DESC LIMIT 1", key)
+		if err != nil {
+			return nil, err
+		}
+		if len(latest) > 0 {
+			// the key was deleted, keep the previous revision unique for the key
+			r.prevRevision = latest[0].id
+		}
+	}
+
+	err = s.db.QueryRowContext(ctx, s.dialect.rebind("INSERT INTO "+tableName+
+		" (name, created, deleted, create_revision, prev_revision, version, lease, expires_at, value, old_value)"+
+		" VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id"),
+		r.name, boolToInt(r.created), boolToInt(r.deleted), r.createRevision, r.prevRevision,
+		r.version, r.lease, r.expiresAt, r.value, r.oldValue).Scan(&r.id)
+	if err != nil {
+		return nil, err
+	}
+
+	atomic.StoreInt64(&s.currentRev, r.id)
+	s.broadcaster.publish(r)
+	return r, nil
+}
+
+// compact drops the revisions older than rev which are superseded by a newer revision or deleted.
+func (s *store) compact(ctx context.Context, rev int64) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	if rev <= s.compactRevision() {
+		return nil
+	}
+	// never drop the latest row, it holds the current revision
+	_, err := s.db.ExecContext(ctx, s.dialect.rebind("DELETE FROM "+tableName+" WHERE id <= ? AND id < ? AND (deleted = 1 OR id < "+
+		"(SELECT MAX(id) FROM "+tableName+" l WHERE l.name = "+tableName+".name AND l.id <= ?))"), rev, s.currentRevision(), rev)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&s.compactRev, rev)
+	klog.V(4).Infof("Compacted datastore to revision %d", rev)
+	return nil
+}
+
+func (s *store) close() error {
+	s.broadcaster.close()
+	return s.db.Close()
+}
+
+func columnsOf(alias string) string {
+	return alias + ".id, " + alias + ".name, " + alias + ".created, " + alias + ".deleted, " + alias + ".create_revision, " +
+		alias + ".prev_revision, " + alias + ".version, " + alias + ".lease, " + alias + ".expires_at, " + alias + ".value, " + alias + ".old_value"
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package datastore
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"k8s.io/klog/v2"
+)
+
+type watchServer struct {
+	etcdserverpb.UnimplementedWatchServer
+	store *store
+}
+
+// watchStream serves the watches multiplexed on one gRPC stream.
+type watchStream struct {
+	store  *store
+	stream etcdserverpb.Watch_WatchServer
+
+	sendLock sync.Mutex
+	lock     sync.Mutex
+	nextID   int64
+	cancels  map[int64]context.CancelFunc
+}
+
+func (w *watchServer) Watch(stream etcdserverpb.Watch_WatchServer) error {
+	ws := &watchStream{
+		store:   w.store,
+		stream:  stream,
+		cancels: map[int64]context.CancelFunc{},
+	}
+	defer ws.cancelAll()
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case req.GetCreateRequest() != nil:
+			ws.create(req.GetCreateRequest())
+		case req.GetCancelRequest() != nil:
+			ws.cancel(req.GetCancelRequest().WatchId, "", 0)
+		case req.GetProgressRequest() != nil:
+			if err := ws.send(&etcdserverpb.WatchResponse{Header: header(w.store), WatchId: -1}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (ws *watchStream) send(resp *etcdserverpb.WatchResponse) error {
+	ws.sendLock.Lock()
+	defer ws.sendLock.Unlock()
+	return ws.stream.Send(resp)
+}
+
+func (ws *watchStream) create(req *etcdserverpb.WatchCreateRequest) {
+	ws.lock.Lock()
+	id := ws.nextID
+	ws.nextID++
+	ctx, cancel := context.WithCancel(ws.stream.Context())
+	ws.cancels[id] = cancel
+	ws.lock.Unlock()
+
+	start, end := string(req.Key), string(req.RangeEnd)
+	if len(req.RangeEnd) == 1 && req.RangeEnd[0] == 0 {
+		end = "\x00"
+	}
+
+	if req.StartRevision > 0 && req.StartRevision <= ws.store.compactRevision() {
+		if err := ws.send(&etcdserverpb.WatchResponse{Header: header(ws.store), WatchId: id, Created: true}); err != nil {
+			return
+		}
+		ws.cancel(id, "", ws.store.compactRevision())
+		return
+	}
+
+	// subscribe under the write lock, so no change is missed between the replay and the live events
+	ws.store.writeLock.Lock()
+	sub := ws.store.broadcaster.subscribe(start, end)
+	rev := ws.store.currentRevision()
+	ws.store.writeLock.Unlock()
+
+	if err := ws.send(&etcdserverpb.WatchResponse{Header: header(ws.store), WatchId: id, Created: true}); err != nil {
+		ws.store.broadcaster.unsubscribe(sub)
+		return
+	}
+
+	go func() {
+		defer ws.store.broadcaster.unsubscribe(sub)
+		if err := ws.run(ctx, id, req, sub, rev); err != nil {
+			klog.V(4).Infof("Watch %d on %s stopped: %v", id, start, err)
+		}
+	}()
+}
+
+func (ws *watchStream) run(ctx context.Context, id int64, req *etcdserverpb.WatchCreateRequest, sub *subscriber, rev int64) error {
+	lastRev := rev
+	if req.StartRevision > 0 && req.StartRevision <= rev {
+		rows, err := ws.store.history(ctx, sub.start, sub.end, req.StartRevision, rev)
+		if err != nil {
+			return err
+		}
+		if err := ws.sendEvents(id, req, rows); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.overflowed:
+			ws.cancel(id, "the watcher is too slow to keep up with the changes", 0)
+			return nil
+		case r := <-sub.events:
+			if r.id <= lastRev {
+				continue
+			}
+			rows := []*row{r}
+			// batch the changes already waiting in the buffer
+			for len(sub.events) > 0 {
+				if next := <-sub.events; next.id > lastRev {
+					rows = append(rows, next)
+				}
+			}
+			if err := ws.sendEvents(id, req, rows); err != nil {
+				return err
+			}
+			lastRev = rows[len(rows)-1].id
+		}
+	}
+}
+
+func (ws *watchStream) sendEvents(id int64, req *etcdserverpb.WatchCreateRequest, rows []*row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	events := make([]*mvccpb.Event, 0, len(rows))
+	for _, r := range rows {
+		if !ws.filtered(req, r) {
+			events = append(events, toEvent(r, req.PrevKv))
+		}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	h := header(ws.store)
+	h.Revision = rows[len(rows)-1].id
+	return ws.send(&etcdserverpb.WatchResponse{Header: h, WatchId: id, Events: events})
+}
+
+func (ws *watchStream) filtered(req *etcdserverpb.WatchCreateRequest, r *row) bool {
+	for _, f := range req.Filters {
+		if f == etcdserverpb.WatchCreateRequest_NOPUT && !r.deleted {
+			return true
+		}
+		if f == etcdserverpb.WatchCreateRequest_NODELETE && r.deleted {
+			return true
+		}
+	}
+	return false
+}
+
+func toEvent(r *row, withPrevKV bool) *mvccpb.Event {
+	event := &mvccpb.Event{Type: mvccpb.PUT, Kv: r.keyValue()}
+	if r.deleted {
+		event.Type = mvccpb.DELETE
+	}
+	if withPrevKV {
+		event.PrevKv = r.prevKeyValue()
+	}
+	return event
+}
+
+// cancel stops a watch, a compactRev greater than 0 tells the client to relist.
+func (ws *watchStream) cancel(id int64, reason string, compactRev int64) {
+	ws.lock.Lock()
+	cancel, ok := ws.cancels[id]
+	delete(ws.cancels, id)
+	ws.lock.Unlock()
+	if !ok {
+		return
+	}
+	cancel()
+	if err := ws.send(&etcdserverpb.WatchResponse{
+		Header:          header(ws.store),
+		WatchId:         id,
+		Canceled:        true,
+		CancelReason:    reason,
+		CompactRevision: compactRev,
+	}); err != nil {
+		klog.V(4).Infof("Failed to send the cancellation of watch %d: %v", id, err)
+	}
+}
+
+func (ws *watchStream) cancelAll() {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	for id, cancel := range ws.cancels {
+		cancel()
+		delete(ws.cancels, id)
+	}
+}
@@ -0,0 +1,84 @@
+// Package encryption provides a KMS plugin for the encryption at rest of the global hub resources.
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"k8s.io/apiserver/pkg/storage/value/encrypt/envelope"
+	kmsapi "k8s.io/apiserver/pkg/storage/value/encrypt/envelope/v1beta1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// kmsAPIVersion is the KMS API version expected by the apiserver.
+	kmsAPIVersion  = "v1beta1"
+	runtimeName    = "global-hub-kms"
+	runtimeVersion = "0.1.0"
+)
+
+// kmsPlugin exposes a key service through the KMS gRPC API. Any envelope.Service can be plugged in, e.g. a
+// client of a cloud KMS, the apiserver only encrypts the data encryption keys with it.
+type kmsPlugin struct {
+	service envelope.Service
+}
+
+var _ kmsapi.KeyManagementServiceServer = &kmsPlugin{}
+
+func (p *kmsPlugin) Version(ctx context.Context, req *kmsapi.VersionRequest) (*kmsapi.VersionResponse, error) {
+	return &kmsapi.VersionResponse{Version: kmsAPIVersion, RuntimeName: runtimeName, RuntimeVersion: runtimeVersion}, nil
+}
+
+func (p *kmsPlugin) Encrypt(ctx context.Context, req *kmsapi.EncryptRequest) (*kmsapi.EncryptResponse, error) {
+	cipher, err := p.service.Encrypt(req.Plain)
+	if err != nil {
+		return nil, err
+	}
+	return &kmsapi.EncryptResponse{Cipher: cipher}, nil
+}
+
+func (p *kmsPlugin) Decrypt(ctx context.Context, req *kmsapi.DecryptRequest) (*kmsapi.DecryptResponse, error) {
+	plain, err := p.service.Decrypt(req.Cipher)
+	if err != nil {
+		return nil, err
+	}
+	return &kmsapi.DecryptResponse{Plain: plain}, nil
+}
+
+// ServeKMSPlugin serves the key service on the unix socket endpoint, e.g. unix:///var/run/global-hub/kms.sock,
+// until the context is done. The endpoint is referenced by a kms provider of the encryption provider config.
+func ServeKMSPlugin(ctx context.Context, endpoint string, service envelope.Service) error {
+	if !strings.HasPrefix(endpoint, "unix://") {
+		return fmt.Errorf("unsupported KMS plugin endpoint %q, must start with unix://", endpoint)
+	}
+	path := strings.TrimPrefix(endpoint, "unix://")
+	// remove the socket left by a previous run
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	kmsapi.RegisterKeyManagementServiceServer(server, &kmsPlugin{service: service})
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			klog.Errorf("KMS plugin stopped: %v", err)
+		}
+	}()
+	// Shutdown when context is closed
+	go func() {
+		<-ctx.Done()
+		server.Stop()
+	}()
+
+	klog.Infof("KMS plugin listening on %s", endpoint)
+	return nil
+}
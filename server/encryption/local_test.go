@@ -0,0 +1,51 @@
+package encryption_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/encryption"
+)
+
+func writeKey(t *testing.T, key []byte) string {
+	keyFile := filepath.Join(t.TempDir(), "kms.key")
+	if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return keyFile
+}
+
+func TestLocalService(t *testing.T) {
+	service, err := encryption.NewLocalService(writeKey(t, bytes.Repeat([]byte{1}, 32)))
+	if err != nil {
+		t.Fatalf("failed to create the local service: %v", err)
+	}
+
+	plain := []byte("data encryption key")
+	cipher, err := service.Encrypt(plain)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if bytes.Contains(cipher, plain) {
+		t.Errorf("the cipher contains the plain text")
+	}
+	decrypted, err := service.Decrypt(cipher)
+	if err != nil || !bytes.Equal(decrypted, plain) {
+		t.Errorf("expected %q, got %q: %v", plain, decrypted, err)
+	}
+
+	otherService, err := encryption.NewLocalService(writeKey(t, bytes.Repeat([]byte{2}, 32)))
+	if err != nil {
+		t.Fatalf("failed to create the local service: %v", err)
+	}
+	if _, err := otherService.Decrypt(cipher); err == nil {
+		t.Errorf("expected the decryption with another key to fail")
+	}
+
+	if _, err := encryption.NewLocalService(writeKey(t, []byte("short"))); err == nil {
+		t.Errorf("expected an invalid key size to be rejected")
+	}
+}
@@ -34,14 +34,14 @@ type ClientInfo struct {
 	TrustedCAFile string
 }
 
-func Run(ctx context.Context, peerPort, clientPort string) (ClientInfo, error) {
+func Run(ctx context.Context, dir, peerPort, clientPort string) (ClientInfo, error) {
 	klog.Info("Creating embedded etcd server")
 	cfg := embed.NewConfig()
 
 	cfg.Logger = "zap"
 	cfg.LogLevel = "warn"
 
-	cfg.Dir = "/etc/etcd-server"
+	cfg.Dir = dir
 	cfg.AuthToken = ""
 
 	cfg.LPUrls = []url.URL{{Scheme: "https", Host: "localhost:" + peerPort}}
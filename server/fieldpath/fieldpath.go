@@ -0,0 +1,43 @@
+// Package fieldpath reads the values at the dot separated field paths of the aggregation rules and the searches.
+package fieldpath
+
+import "sort"
+
+// Values returns the values at the path, the lists along the path are expanded and the * segments expand the values
+// of the maps, sorted by key.
+func Values(value interface{}, path []string) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		values := []interface{}{}
+		for _, item := range v {
+			values = append(values, Values(item, path)...)
+		}
+		return values
+	case map[string]interface{}:
+		if len(path) == 0 {
+			return []interface{}{v}
+		}
+		if path[0] == "*" {
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			values := []interface{}{}
+			for _, key := range keys {
+				values = append(values, Values(v[key], path[1:])...)
+			}
+			return values
+		}
+		child, ok := v[path[0]]
+		if !ok {
+			return nil
+		}
+		return Values(child, path[1:])
+	default:
+		if len(path) != 0 || value == nil {
+			return nil
+		}
+		return []interface{}{value}
+	}
+}
@@ -0,0 +1,372 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// brokerServerName is the name of the global hub on the broker
+	brokerServerName = "global-hub"
+	// fullSpecPeriod is the period the global hub sends all the spec, for the syncers which missed changes
+	fullSpecPeriod = 5 * time.Minute
+
+	brokerSyncerAgent = "globalhub#broker/v0.0.0"
+)
+
+// startBrokerSyncer starts the spec and the status syncers of a regional hub exchanging the bundles with the global
+// hub through the broker.
+func startBrokerSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int) error {
+	transport, err := NewNATSTransport(cfg.Broker, cfg.SyncerName)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Creating spec and status syncers with the broker %s", cfg.Broker.URL)
+	return startTransportSyncer(ctx, cfg, transport, numSyncerThreads)
+}
+
+// startTransportSyncer starts the spec and the status syncers of a regional hub exchanging the bundles with the
+// global hub through the transport.
+func startTransportSyncer(ctx context.Context, cfg *SyncerConfig, transport Transport, numSyncerThreads int) error {
+	to := rest.CopyConfig(cfg.DownstreamConfig)
+	to.UserAgent = specSyncerAgent
+	specSyncer, err := newController(cfg.SyncerName, newBundleInformerFactory(), nil, dynamic.NewForConfigOrDie(to), nil, SyncDown)
+	if err != nil {
+		return err
+	}
+	specSyncer.transport = transport
+
+	from := rest.CopyConfig(cfg.DownstreamConfig)
+	from.UserAgent = statusSyncerAgent
+	statusSyncer, err := New(cfg.SyncerName, dynamic.NewForConfigOrDie(from), nil, from, SyncUp)
+	if err != nil {
+		return err
+	}
+	statusSyncer.transport = transport
+	if len(cfg.StatusQueueFile) > 0 {
+		if statusSyncer.statusQueue, err = openStatusQueue(cfg.StatusQueueFile); err != nil {
+			return err
+		}
+	}
+
+	go specSyncer.Start(ctx, numSyncerThreads)
+	go statusSyncer.Start(ctx, numSyncerThreads)
+	go func() {
+		<-ctx.Done()
+		transport.Close()
+	}()
+
+	return nil
+}
+
+// startTransport subscribes to the bundles of the syncer, then asks for all the bundles it may have missed.
+func (c *Controller) startTransport(ctx context.Context) error {
+	switch {
+	// the spec syncer of a regional hub
+	case c.direction == SyncDown && c.toClient != nil:
+		bundles, ok := c.fromInformers.(*bundleInformerFactory)
+		if !ok {
+			return fmt.Errorf("%s: the informers are not fed by the broker", c.name)
+		}
+		if err := c.transport.Subscribe(ctx, SpecTopic, bundles.handle); err != nil {
+			return err
+		}
+		return c.transport.Publish(SpecResyncTopic, &Bundle{Hub: c.syncerName})
+
+	// the global hub
+	case c.direction == SyncDown:
+		if err := c.transport.Subscribe(ctx, SpecResyncTopic, func(bundle *Bundle) {
+			klog.Infof("Sending all the spec requested by %s", bundle.Hub)
+			c.publishFullSpec(ctx)
+		}); err != nil {
+			return err
+		}
+		go func() {
+			c.fromInformers.WaitForCacheSync(ctx.Done())
+			wait.UntilWithContext(ctx, c.publishFullSpec, fullSpecPeriod)
+		}()
+		return nil
+
+	// the status syncer of a regional hub
+	default:
+		return c.transport.Subscribe(ctx, StatusResyncTopic, func(*Bundle) {
+			klog.Infof("Sending all the status requested by the global hub")
+			c.resyncStatus()
+		})
+	}
+}
+
+// publishSpec sends the object of the global hub to the syncers.
+func (c *Controller) publishSpec(ctx context.Context, gvr schema.GroupVersionResource, namespace string, upstreamObj *unstructured.Unstructured) error {
+	bundle := newBundle("", gvr)
+	bundle.Objects = []BundleObject{{Namespace: namespace, Name: upstreamObj.GetName(), Object: upstreamObj}}
+	return c.transport.Publish(SpecTopic, bundle)
+}
+
+// publishSpecDeletion sends the deletion of the object of the global hub to the syncers.
+func (c *Controller) publishSpecDeletion(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) error {
+	bundle := newBundle("", gvr)
+	bundle.Objects = []BundleObject{{Namespace: namespace, Name: name, Deleted: true}}
+	return c.transport.Publish(SpecTopic, bundle)
+}
+
+// publishFullSpec sends all the objects of the global hub synced to the regional hubs.
+func (c *Controller) publishFullSpec(ctx context.Context) {
+	for _, gvrstr := range c.gvrs {
+		gvr, _ := schema.ParseResourceArg(gvrstr)
+		informer := c.fromInformers.ForResource(*gvr).Informer()
+		// the syncers delete the objects missing from a full bundle
+		if !informer.HasSynced() {
+			continue
+		}
+		bundle := newBundle("", *gvr)
+		bundle.Full = true
+		for _, item := range informer.GetStore().List() {
+			upstreamObj, ok := item.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			// only the resources in the global hub namespaces are synced
+			if len(upstreamObj.GetNamespace()) > 0 && upstreamObj.GetLabels()[GlobalHubPolicyNamespaceLabel] != upstreamObj.GetNamespace() {
+				continue
+			}
+			bundle.Objects = append(bundle.Objects, BundleObject{
+				Namespace: upstreamObj.GetNamespace(),
+				Name:      upstreamObj.GetName(),
+				Object:    upstreamObj,
+			})
+		}
+		if err := c.transport.Publish(SpecTopic, bundle); err != nil {
+			klog.Errorf("Failed to send all the %s: %v", gvr.Resource, err)
+		}
+	}
+}
+
+// publishStatus sends the object of the regional hub to the global hub, where it is applied to the upstream
+// namespace by the field manager.
+func (c *Controller) publishStatus(gvr schema.GroupVersionResource, upstreamNamespace, manager string, downstreamObj *unstructured.Unstructured) error {
+	upstreamObj := toUpstreamObject(upstreamNamespace, downstreamObj)
+	bundle := newBundle(c.syncerName, gvr)
+	bundle.Objects = []BundleObject{{
+		Namespace: upstreamObj.GetNamespace(),
+		Name:      upstreamObj.GetName(),
+		Manager:   manager,
+		Object:    upstreamObj,
+	}}
+	if err := c.transport.Publish(StatusTopic, bundle); err != nil {
+		return err
+	}
+	klog.Infof("Sent the status of %s %s/%s to the global hub", gvr.Resource, upstreamObj.GetNamespace(), upstreamObj.GetName())
+	return nil
+}
+
+// resyncStatus queues all the objects of the regional hub to send their status again.
+func (c *Controller) resyncStatus() {
+	for _, gvrstr := range c.gvrs {
+		gvr, _ := schema.ParseResourceArg(gvrstr)
+		for _, obj := range c.fromInformers.ForResource(*gvr).Informer().GetStore().List() {
+			// only the managedcluster CRD is reported, as the hubcontrolplane
+			if unstrob, ok := obj.(*unstructured.Unstructured); ok && gvr.Resource == "customresourcedefinitions" &&
+				unstrob.GetName() != "managedclusters.cluster.open-cluster-management.io" {
+				continue
+			}
+			c.AddToQueue(*gvr, obj)
+		}
+	}
+}
+
+// StartBrokerServer sends the spec of the global hub to the syncers using the broker transport and applies the status
+// they send to the global hub.
+func StartBrokerServer(ctx context.Context, config *rest.Config, broker *BrokerConfig) error {
+	transport, err := NewNATSTransport(broker, brokerServerName)
+	if err != nil {
+		return err
+	}
+	if err := startTransportServer(ctx, config, transport); err != nil {
+		transport.Close()
+		return err
+	}
+	klog.Infof("Started the broker transport with %s", broker.URL)
+	return nil
+}
+
+// startTransportServer sends the spec of the global hub to the syncers through the transport and applies the status
+// they send to the global hub.
+func startTransportServer(ctx context.Context, config *rest.Config, transport Transport) error {
+	config = rest.CopyConfig(config)
+	config.UserAgent = brokerSyncerAgent
+	client := dynamic.NewForConfigOrDie(config)
+
+	publisher, err := New(brokerServerName, client, nil, config, SyncDown)
+	if err != nil {
+		return err
+	}
+	publisher.transport = transport
+	publisher.upsertFn = publisher.publishSpec
+	publisher.deleteFn = publisher.publishSpecDeletion
+
+	if err := transport.Subscribe(ctx, StatusTopic, func(bundle *Bundle) {
+		applyStatusBundle(ctx, client, bundle)
+	}); err != nil {
+		return err
+	}
+	// the status sent while the global hub was down is lost
+	if err := transport.Publish(StatusResyncTopic, &Bundle{}); err != nil {
+		return err
+	}
+
+	go publisher.Start(ctx, 1)
+	go func() {
+		<-ctx.Done()
+		transport.Close()
+	}()
+	return nil
+}
+
+// applyStatusBundle applies the objects sent by a syncer, with their status, to the global hub.
+func applyStatusBundle(ctx context.Context, client dynamic.Interface, bundle *Bundle) {
+	for _, bundleObj := range bundle.Objects {
+		if err := ApplyBundleObject(ctx, client, bundle.Hub, bundle.GVR(), bundleObj); err != nil {
+			klog.Error(err)
+		}
+	}
+}
+
+// ApplyBundleObject applies an object sent by the syncer of the hub, with its status, to the global hub. The deleted
+// objects are ignored, the copies on the global hub are kept. Only the objects the status syncer of the hub sends are
+// accepted, see admitStatusObject.
+func ApplyBundleObject(ctx context.Context, client dynamic.Interface, hub string, gvr schema.GroupVersionResource, bundleObj BundleObject) error {
+	if bundleObj.Deleted || bundleObj.Object == nil {
+		return nil
+	}
+	bundleObj, err := admitStatusObject(ctx, client, hub, gvr, bundleObj)
+	if err != nil {
+		return fmt.Errorf("rejected %s %s/%s from %s: %v", gvr.Resource, bundleObj.Namespace, bundleObj.Name, hub, err)
+	}
+	manager := bundleObj.Manager
+	if len(manager) == 0 {
+		manager = syncerApplyManager
+	}
+	// Marshalling the unstructured object is good enough as SSA patch
+	data, err := json.Marshal(bundleObj.Object)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s %s/%s from %s: %v", gvr.Resource, bundleObj.Namespace, bundleObj.Name, hub, err)
+	}
+	resource := client.Resource(gvr).Namespace(bundleObj.Namespace)
+	options := metav1.PatchOptions{FieldManager: manager, Force: pointer.Bool(true)}
+	if _, err := resource.Patch(ctx, bundleObj.Name, types.ApplyPatchType, data, options); err != nil {
+		return fmt.Errorf("failed to apply %s %s/%s from %s: %v", gvr.Resource, bundleObj.Namespace, bundleObj.Name, hub, err)
+	}
+	if _, err := resource.Patch(ctx, bundleObj.Name, types.ApplyPatchType, data, options, "status"); err != nil {
+		return fmt.Errorf("failed to apply the status of %s %s/%s from %s: %v", gvr.Resource, bundleObj.Namespace, bundleObj.Name, hub, err)
+	}
+	klog.V(2).Infof("Applied %s %s/%s from %s", gvr.Resource, bundleObj.Namespace, bundleObj.Name, hub)
+	return nil
+}
+
+// statusResources are the resources the status syncers send to the global hub, by whether they are namespaced
+var statusResources = map[schema.GroupVersionResource]bool{
+	{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}:                 true,
+	{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "subscriptions"}:              true,
+	{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placementdecisions"}: true,
+	managedClusterGVR:  false,
+	hubControlPlaneGVR: false,
+}
+
+// statusManagers are the field managers of the status syncers
+var statusManagers = map[string]bool{
+	"":                       true,
+	syncerApplyManager:       true,
+	driftReportManager:       true,
+	heartbeatManager:         true,
+	propagationReportManager: true,
+}
+
+// CheckStatusBundle rejects the status bundles a transport or a StatusBundle must not hand over for the hub, the
+// bundles of the resources not synced from the regional hubs and the namespaced objects out of the namespace of the
+// hub, or the cluster scoped ones with a namespace.
+func CheckStatusBundle(hub string, bundle *Bundle) error {
+	namespaced, ok := statusResources[bundle.GVR()]
+	if !ok {
+		return fmt.Errorf("%s are not synced from the regional hubs", bundle.GVR().GroupResource())
+	}
+	namespace := ""
+	if namespaced {
+		namespace = hub
+	}
+	for _, bundleObj := range bundle.Objects {
+		if bundleObj.Namespace != namespace || bundleObj.Object != nil && bundleObj.Object.GetNamespace() != namespace {
+			return fmt.Errorf("%s %s/%s is out of the namespace %q", bundle.Resource, bundleObj.Namespace, bundleObj.Name, namespace)
+		}
+	}
+	return nil
+}
+
+// admitStatusObject returns the object as the syncer of the hub is allowed to apply it to the global hub. The
+// namespaced objects are moved to the namespace of the hub, the hubcontrolplane must be the one of the hub, and the
+// managedclusters are labeled with the hub and must not be the ones of another hub. The objects of the other
+// resources are rejected.
+func admitStatusObject(ctx context.Context, client dynamic.Interface, hub string, gvr schema.GroupVersionResource,
+	bundleObj BundleObject) (BundleObject, error) {
+	if errs := validation.IsDNS1123Label(hub); len(errs) > 0 {
+		return bundleObj, fmt.Errorf("invalid regional hub %q: %s", hub, strings.Join(errs, ", "))
+	}
+	namespaced, ok := statusResources[gvr]
+	if !ok {
+		return bundleObj, fmt.Errorf("%s are not synced from the regional hubs", gvr.GroupResource())
+	}
+	if !statusManagers[bundleObj.Manager] {
+		return bundleObj, fmt.Errorf("unknown field manager %q", bundleObj.Manager)
+	}
+	if bundleObj.Object.GetName() != bundleObj.Name {
+		return bundleObj, fmt.Errorf("the object is named %q", bundleObj.Object.GetName())
+	}
+
+	bundleObj.Object = bundleObj.Object.DeepCopy()
+	if namespaced {
+		bundleObj.Namespace = hub
+		bundleObj.Object.SetNamespace(hub)
+		return bundleObj, nil
+	}
+	if len(bundleObj.Namespace) > 0 || len(bundleObj.Object.GetNamespace()) > 0 {
+		return bundleObj, fmt.Errorf("%s are cluster scoped", gvr.Resource)
+	}
+	switch gvr {
+	case hubControlPlaneGVR:
+		if bundleObj.Name != hub {
+			return bundleObj, fmt.Errorf("only the hubcontrolplane %s is synced from %s", hub, hub)
+		}
+	case managedClusterGVR:
+		existing, err := client.Resource(gvr).Get(ctx, bundleObj.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return bundleObj, err
+		}
+		if err == nil {
+			if owner, ok := existing.GetLabels()[RegionalHubLabel]; ok && owner != hub {
+				return bundleObj, fmt.Errorf("the managedcluster is synced from %s", owner)
+			}
+		}
+		labels := bundleObj.Object.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[RegionalHubLabel] = hub
+		bundleObj.Object.SetLabels(labels)
+	}
+	return bundleObj, nil
+}
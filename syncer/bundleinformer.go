@@ -0,0 +1,219 @@
+package syncer
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// bundleWatchBuffer is the number of changes a watch of the spec syncer can lag behind, the informer lists the
+// objects again when it lags further
+const bundleWatchBuffer = 1024
+
+// bundleInformerFactory serves the objects of the spec bundles to the informers of the spec syncer, in place of the
+// watches of the global hub apiserver.
+type bundleInformerFactory struct {
+	lock      sync.Mutex
+	sources   map[schema.GroupVersionResource]*bundleSource
+	informers map[schema.GroupVersionResource]cache.SharedIndexInformer
+	started   map[schema.GroupVersionResource]bool
+}
+
+func newBundleInformerFactory() *bundleInformerFactory {
+	return &bundleInformerFactory{
+		sources:   map[schema.GroupVersionResource]*bundleSource{},
+		informers: map[schema.GroupVersionResource]cache.SharedIndexInformer{},
+		started:   map[schema.GroupVersionResource]bool{},
+	}
+}
+
+func (f *bundleInformerFactory) ForResource(gvr schema.GroupVersionResource) informers.GenericInformer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	informer, ok := f.informers[gvr]
+	if !ok {
+		informer = cache.NewSharedIndexInformer(f.source(gvr), &unstructured.Unstructured{}, resyncPeriod,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		f.informers[gvr] = informer
+	}
+	return &bundleInformer{informer: informer, resource: gvr.GroupResource()}
+}
+
+func (f *bundleInformerFactory) Start(stopCh <-chan struct{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for gvr, informer := range f.informers {
+		if !f.started[gvr] {
+			go informer.Run(stopCh)
+			f.started[gvr] = true
+		}
+	}
+}
+
+func (f *bundleInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool {
+	informers := func() map[schema.GroupVersionResource]cache.SharedIndexInformer {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		informers := map[schema.GroupVersionResource]cache.SharedIndexInformer{}
+		for gvr, informer := range f.informers {
+			if f.started[gvr] {
+				informers[gvr] = informer
+			}
+		}
+		return informers
+	}()
+
+	res := map[schema.GroupVersionResource]bool{}
+	for gvr, informer := range informers {
+		res[gvr] = cache.WaitForCacheSync(stopCh, informer.HasSynced)
+	}
+	return res
+}
+
+// handle applies the changes of the bundle to the objects served to the informers.
+func (f *bundleInformerFactory) handle(bundle *Bundle) {
+	f.lock.Lock()
+	source := f.source(bundle.GVR())
+	f.lock.Unlock()
+	source.apply(bundle)
+}
+
+func (f *bundleInformerFactory) source(gvr schema.GroupVersionResource) *bundleSource {
+	source, ok := f.sources[gvr]
+	if !ok {
+		source = &bundleSource{
+			gvr:      gvr,
+			objects:  map[string]*unstructured.Unstructured{},
+			watchers: map[*watch.ProxyWatcher]chan watch.Event{},
+		}
+		f.sources[gvr] = source
+	}
+	return source
+}
+
+type bundleInformer struct {
+	informer cache.SharedIndexInformer
+	resource schema.GroupResource
+}
+
+func (i *bundleInformer) Informer() cache.SharedIndexInformer {
+	return i.informer
+}
+
+func (i *bundleInformer) Lister() cache.GenericLister {
+	return cache.NewGenericLister(i.informer.GetIndexer(), i.resource)
+}
+
+// bundleSource lists and watches the objects of a resource received in the spec bundles. The resource version of
+// the list is the number of bundles applied, a watch from an older version fails to make the informer list again.
+type bundleSource struct {
+	gvr schema.GroupVersionResource
+
+	lock     sync.Mutex
+	synced   bool
+	version  uint64
+	objects  map[string]*unstructured.Unstructured
+	watchers map[*watch.ProxyWatcher]chan watch.Event
+}
+
+func (s *bundleSource) List(options metav1.ListOptions) (runtime.Object, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// the informer is synced once all the objects are received
+	if !s.synced {
+		return nil, fmt.Errorf("waiting for all the %s from the global hub", s.gvr.Resource)
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetResourceVersion(strconv.FormatUint(s.version, 10))
+	for _, obj := range s.objects {
+		list.Items = append(list.Items, *obj.DeepCopy())
+	}
+	return list, nil
+}
+
+func (s *bundleSource) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if options.ResourceVersion != strconv.FormatUint(s.version, 10) {
+		return nil, k8serrors.NewResourceExpired(fmt.Sprintf("the %s changed since version %s", s.gvr.Resource, options.ResourceVersion))
+	}
+	events := make(chan watch.Event, bundleWatchBuffer)
+	watcher := watch.NewProxyWatcher(events)
+	s.watchers[watcher] = events
+	return watcher, nil
+}
+
+func (s *bundleSource) apply(bundle *Bundle) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.version++
+	version := strconv.FormatUint(s.version, 10)
+	received := map[string]bool{}
+	for _, bundleObj := range bundle.Objects {
+		key := bundleObj.Name
+		if len(bundleObj.Namespace) > 0 {
+			key = bundleObj.Namespace + "/" + key
+		}
+		received[key] = true
+
+		existing, exists := s.objects[key]
+		if bundleObj.Deleted || bundleObj.Object == nil {
+			if exists {
+				delete(s.objects, key)
+				s.notify(watch.Deleted, existing)
+			}
+			continue
+		}
+		obj := bundleObj.Object.DeepCopy()
+		obj.SetResourceVersion(version)
+		s.objects[key] = obj
+		if exists {
+			s.notify(watch.Modified, obj)
+		} else {
+			s.notify(watch.Added, obj)
+		}
+	}
+
+	if bundle.Full {
+		for key, existing := range s.objects {
+			if !received[key] {
+				delete(s.objects, key)
+				s.notify(watch.Deleted, existing)
+			}
+		}
+		s.synced = true
+	}
+}
+
+// notify sends the change to the watches, the watches lagging too far behind are closed.
+func (s *bundleSource) notify(eventType watch.EventType, obj *unstructured.Unstructured) {
+	for watcher, events := range s.watchers {
+		select {
+		case <-watcher.StopChan():
+			delete(s.watchers, watcher)
+			continue
+		default:
+		}
+		select {
+		case events <- watch.Event{Type: eventType, Object: obj.DeepCopy()}:
+		default:
+			close(events)
+			delete(s.watchers, watcher)
+		}
+	}
+}
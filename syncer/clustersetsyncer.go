@@ -0,0 +1,186 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// clusterSetLabel assigns a managedcluster to a managedclusterset
+	clusterSetLabel = "cluster.open-cluster-management.io/clusterset"
+	// globalManagedClusterSetLabel is set on the managedclustersets and the bindings synced from a global
+	// managedclusterset, the value is the name of the set
+	globalManagedClusterSetLabel = "global-hub.open-cluster-management.io/global-managedclusterset"
+)
+
+var (
+	managedClusterSetGVR        = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "managedclustersets"}
+	managedClusterSetBindingGVR = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "managedclustersetbindings"}
+)
+
+// applyClusterSetToDownstream syncs a global managedclusterset to a managedclusterset with the clusters of the
+// regional hub, and binds it to the binding namespaces.
+func (c *Controller) applyClusterSetToDownstream(ctx context.Context, upstreamObj *unstructured.Unstructured) error {
+	name := upstreamObj.GetName()
+	members, err := c.clusterSetMembers(upstreamObj)
+	if err != nil {
+		return err
+	}
+	bindingNamespaces, _, err := unstructured.NestedStringSlice(upstreamObj.Object, "spec", "bindingNamespaces")
+	if err != nil {
+		return err
+	}
+
+	clusterSet := &unstructured.Unstructured{}
+	clusterSet.SetGroupVersionKind(managedClusterSetGVR.GroupVersion().WithKind("ManagedClusterSet"))
+	clusterSet.SetName(name)
+	clusterSet.SetLabels(map[string]string{globalManagedClusterSetLabel: name, SyncerLabel: c.syncerName})
+	if err := c.applyToDownstreamResource(ctx, managedClusterSetGVR, clusterSet); err != nil {
+		return err
+	}
+
+	if err := c.labelClusterSetMembers(ctx, name, members); err != nil {
+		return err
+	}
+
+	for _, namespace := range bindingNamespaces {
+		if err := c.ensureDownstreamNamespaceExists(ctx, namespace, upstreamObj); err != nil {
+			return err
+		}
+		binding := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"clusterSet": name},
+		}}
+		binding.SetGroupVersionKind(managedClusterSetBindingGVR.GroupVersion().WithKind("ManagedClusterSetBinding"))
+		binding.SetName(name)
+		binding.SetNamespace(namespace)
+		binding.SetLabels(map[string]string{globalManagedClusterSetLabel: name, SyncerLabel: c.syncerName})
+		if err := c.applyToDownstreamResource(ctx, managedClusterSetBindingGVR, binding); err != nil {
+			return err
+		}
+	}
+	if err := c.deleteClusterSetBindings(ctx, name, sets.NewString(bindingNamespaces...)); err != nil {
+		return err
+	}
+
+	klog.Infof("Upserted managedclusterset %s with %d clusters from upstream", name, members.Len())
+	return nil
+}
+
+// deleteClusterSetFromDownstream deletes the managedclusterset of a global managedclusterset with its bindings, and
+// removes its clusters from the set.
+func (c *Controller) deleteClusterSetFromDownstream(ctx context.Context, name string) error {
+	if err := c.deleteClusterSetBindings(ctx, name, sets.NewString()); err != nil {
+		return err
+	}
+	if err := c.labelClusterSetMembers(ctx, name, sets.NewString()); err != nil {
+		return err
+	}
+	if err := c.toClient.Resource(managedClusterSetGVR).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	klog.Infof("Deleted managedclusterset %s", name)
+	return nil
+}
+
+// clusterSetMembers returns the clusters of the regional hub selected by the global managedclusterset.
+func (c *Controller) clusterSetMembers(upstreamObj *unstructured.Unstructured) (sets.String, error) {
+	hubs, _, err := unstructured.NestedSlice(upstreamObj.Object, "status", "hubs")
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range hubs {
+		hub, ok := item.(map[string]interface{})
+		if !ok || hub["name"] != c.syncerName {
+			continue
+		}
+		clusters, _, err := unstructured.NestedStringSlice(hub, "clusters")
+		if err != nil {
+			return nil, err
+		}
+		return sets.NewString(clusters...), nil
+	}
+	return sets.NewString(), nil
+}
+
+// labelClusterSetMembers adds the members to the managedclusterset and removes the other clusters of the set. The
+// clusters already in another set are left there, a managedcluster belongs to one set at most.
+func (c *Controller) labelClusterSetMembers(ctx context.Context, name string, members sets.String) error {
+	clusters, err := c.toClient.Resource(managedClusterGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters.Items {
+		current, inSet := cluster.GetLabels()[clusterSetLabel]
+		var value interface{}
+		switch {
+		case members.Has(cluster.GetName()) && !inSet:
+			value = name
+		case members.Has(cluster.GetName()) && current != name:
+			klog.Warningf("managedcluster %s of the global managedclusterset %s is in the managedclusterset %s",
+				cluster.GetName(), name, current)
+			continue
+		case !members.Has(cluster.GetName()) && inSet && current == name:
+			value = nil
+		default:
+			continue
+		}
+
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"labels": map[string]interface{}{clusterSetLabel: value},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := c.toClient.Resource(managedClusterGVR).Patch(ctx, cluster.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteClusterSetBindings deletes the bindings of the managedclusterset outside of the namespaces.
+func (c *Controller) deleteClusterSetBindings(ctx context.Context, name string, namespaces sets.String) error {
+	bindings, err := c.toClient.Resource(managedClusterSetBindingGVR).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", globalManagedClusterSetLabel, name),
+	})
+	if err != nil {
+		return err
+	}
+	for _, binding := range bindings.Items {
+		if namespaces.Has(binding.GetNamespace()) {
+			continue
+		}
+		if err := c.toClient.Resource(managedClusterSetBindingGVR).Namespace(binding.GetNamespace()).
+			Delete(ctx, binding.GetName(), metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		klog.Infof("Deleted managedclustersetbinding %s/%s", binding.GetNamespace(), binding.GetName())
+	}
+	return nil
+}
+
+func (c *Controller) applyToDownstreamResource(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	// Marshalling the unstructured object is good enough as SSA patch
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	if _, err := c.toClient.Resource(gvr).Namespace(obj.GetNamespace()).Patch(ctx, obj.GetName(), types.ApplyPatchType, data,
+		metav1.PatchOptions{FieldManager: syncerApplyManager, Force: pointer.Bool(true)}); err != nil {
+		klog.Infof("Error upserting %s %s/%s: %v", gvr.Resource, obj.GetNamespace(), obj.GetName(), err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+
+	hubcontrolplanev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/hubcontrolplane/v1alpha1"
+)
+
+const (
+	// driftReportManager owns the drift in the status of the hubcontrolplane, the status syncer owns the rest
+	driftReportManager = "syncer-drift"
+	driftReportPeriod  = 10 * time.Second
+)
+
+// driftCounter counts the drifted objects per resource since the syncer started.
+type driftCounter struct {
+	lock              sync.Mutex
+	resources         map[string]int64
+	lastDetectionTime metav1.Time
+	reported          bool
+}
+
+func newDriftCounter() *driftCounter {
+	return &driftCounter{resources: map[string]int64{}, reported: true}
+}
+
+func (d *driftCounter) record(gvr schema.GroupVersionResource) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.resources[gvr.GroupResource().String()]++
+	d.lastDetectionTime = metav1.Now()
+	d.reported = false
+}
+
+// status returns the drift to report, or nil when it is already reported.
+func (d *driftCounter) status() *hubcontrolplanev1alpha1.DriftStatus {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.reported {
+		return nil
+	}
+	status := &hubcontrolplanev1alpha1.DriftStatus{
+		Resources:         map[string]int64{},
+		LastDetectionTime: d.lastDetectionTime.DeepCopy(),
+	}
+	for resource, count := range d.resources {
+		status.Resources[resource] = count
+		status.Detected += count
+	}
+	return status
+}
+
+func (d *driftCounter) markReported(status *hubcontrolplanev1alpha1.DriftStatus) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.lastDetectionTime.Equal(status.LastDetectionTime) {
+		d.reported = true
+	}
+}
+
+// downstreamChanged re-applies the upstream object when its downstream copy is changed or deleted on the regional hub.
+func (c *Controller) downstreamChanged(gvr schema.GroupVersionResource, oldObj, obj interface{}, deleted bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	downstreamObj, ok := obj.(*unstructured.Unstructured)
+	if !ok || !isAppliedBySyncer(downstreamObj) {
+		return
+	}
+	if !deleted && deepEqualApartFromStatus(oldObj, obj) {
+		return
+	}
+
+	key := downstreamObj.GetName()
+	if len(downstreamObj.GetNamespace()) > 0 {
+		key = downstreamObj.GetNamespace() + "/" + key
+	}
+	item, exists, err := c.fromInformers.ForResource(gvr).Informer().GetIndexer().GetByKey(key)
+	if err != nil || !exists {
+		// the downstream object is deleted by the syncer once the upstream object is gone
+		return
+	}
+	upstreamObj, ok := item.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	if !deleted && !drifted(upstreamObj, downstreamObj) {
+		return
+	}
+
+	c.drift.record(gvr)
+	klog.Infof("Detected drift of %s %s on the regional hub, re-applying it", gvr.Resource, key)
+	c.AddToQueue(gvr, upstreamObj)
+}
+
+// reportDrift sets the drift counts to the hubcontrolplane of the regional hub.
+func (c *Controller) reportDrift(ctx context.Context) {
+	drift := c.drift.status()
+	if drift == nil {
+		return
+	}
+
+	if err := c.applyHubControlPlaneStatus(ctx, driftReportManager, hubcontrolplanev1alpha1.HubControlPlaneStatus{
+		Drift: drift,
+	}); err != nil {
+		// the hubcontrolplane is created by the status syncer
+		if !errors.IsNotFound(err) {
+			klog.Errorf("Failed to report the drift to hubcontrolplane %s: %v", c.syncerName, err)
+		}
+		return
+	}
+	c.drift.markReported(drift)
+	klog.Infof("Reported %d drifted objects to hubcontrolplane %s", drift.Detected, c.syncerName)
+}
+
+// applyHubControlPlaneStatus applies the fields of the status owned by the manager to the hubcontrolplane of the
+// regional hub, the status syncer owns the managed clusters.
+func (c *Controller) applyHubControlPlaneStatus(ctx context.Context, manager string,
+	status hubcontrolplanev1alpha1.HubControlPlaneStatus) error {
+	hubControlPlane := &hubcontrolplanev1alpha1.HubControlPlane{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "cluster.open-cluster-management.io/v1alpha1",
+			Kind:       "HubControlPlane",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: c.syncerName,
+		},
+		Status: status,
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(hubControlPlane)
+	if err != nil {
+		return err
+	}
+	unstructured.RemoveNestedField(content, "spec")
+	unstructured.RemoveNestedField(content, "status", "managedClusters")
+	if c.transport != nil {
+		return c.publishStatus(hubControlPlaneGVR, "", manager, &unstructured.Unstructured{Object: content})
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	_, err = c.fromClient.Resource(hubControlPlaneGVR).Patch(ctx, c.syncerName, types.ApplyPatchType, data,
+		metav1.PatchOptions{FieldManager: manager, Force: pointer.Bool(true)}, "status")
+	return err
+}
+
+// isAppliedBySyncer returns true for the downstream objects applied by the spec syncer.
+func isAppliedBySyncer(obj *unstructured.Unstructured) bool {
+	for _, managedFields := range obj.GetManagedFields() {
+		if managedFields.Manager == syncerApplyManager && managedFields.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+	return false
+}
+
+// drifted returns true when the downstream object lost the labels, the annotations or the content of the upstream
+// object, the labels and the annotations added on the regional hub are not a drift.
+func drifted(upstreamObj, downstreamObj *unstructured.Unstructured) bool {
+	for key, value := range upstreamObj.GetLabels() {
+		if downstreamValue, ok := downstreamObj.GetLabels()[key]; !ok || downstreamValue != value {
+			return true
+		}
+	}
+	for key, value := range upstreamObj.GetAnnotations() {
+		if downstreamValue, ok := downstreamObj.GetAnnotations()[key]; !ok || downstreamValue != value {
+			return true
+		}
+	}
+
+	upstreamKeys := sets.StringKeySet(upstreamObj.UnstructuredContent())
+	downstreamKeys := sets.StringKeySet(downstreamObj.UnstructuredContent())
+	for _, key := range upstreamKeys.Union(downstreamKeys).UnsortedList() {
+		if key == "metadata" || key == "status" {
+			continue
+		}
+		if !equality.Semantic.DeepEqual(upstreamObj.UnstructuredContent()[key], downstreamObj.UnstructuredContent()[key]) {
+			return true
+		}
+	}
+	return false
+}
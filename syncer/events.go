@@ -0,0 +1,43 @@
+package syncer
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// PropagatedReason is recorded on the global objects applied to a regional hub
+	PropagatedReason = "Propagated"
+	// PropagationFailedReason is recorded on the global objects the regional hub failed to apply
+	PropagationFailedReason = "PropagationFailed"
+
+	syncerEventComponent = "globalhub-syncer"
+)
+
+// newEventBroadcaster returns a broadcaster recording the events to the global hub, the events are from the host
+// named after the regional hub.
+func newEventBroadcaster(kubeClient kubernetes.Interface, syncerName string) (record.EventBroadcaster, record.EventRecorder) {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: syncerEventComponent, Host: syncerName})
+	return broadcaster, recorder
+}
+
+// recordPropagation records the outcome of applying the global object to the regional hub, nothing is recorded when
+// the syncer has no client of the global hub.
+func (c *Controller) recordPropagation(upstreamObj *unstructured.Unstructured, err error) {
+	if c.recorder == nil {
+		return
+	}
+	if err != nil {
+		c.recorder.Eventf(upstreamObj, corev1.EventTypeWarning, PropagationFailedReason,
+			"Failed to apply to the regional hub %s: %v", c.syncerName, err)
+		return
+	}
+	c.recorder.Eventf(upstreamObj, corev1.EventTypeNormal, PropagatedReason, "Applied to the regional hub %s",
+		c.syncerName)
+}
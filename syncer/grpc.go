@@ -0,0 +1,341 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// grpcCodecName is the content subtype of the sync streams, the messages are JSON encoded
+	grpcCodecName = "json"
+	// grpcHubMetadata is the metadata of the sync streams carrying the name of the regional hub
+	grpcHubMetadata = "x-global-hub-hub"
+	// grpcStatusBufferSize is the number of the status bundles waiting to be acknowledged by the global hub, the
+	// status is queued by the status queue once it is full
+	grpcStatusBufferSize = 4096
+	grpcReconnectWait    = time.Second
+)
+
+// errStatusBufferFull is returned when the global hub does not acknowledge the status bundles for too long
+var errStatusBufferFull = errors.New("too many status bundles waiting to be acknowledged by the global hub")
+
+// syncServiceDesc describes the sync service of the global hub, the messages are JSON encoded so no generated code
+// is needed. Both streams are bidirectional, the syncer acknowledges the spec and the global hub the status.
+var syncServiceDesc = grpc.ServiceDesc{
+	ServiceName: "globalhub.Sync",
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Spec",
+			Handler:       func(srv interface{}, stream grpc.ServerStream) error { return srv.(*grpcServer).spec(stream) },
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Status",
+			Handler:       func(srv interface{}, stream grpc.ServerStream) error { return srv.(*grpcServer).status(stream) },
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
+
+// specRequest subscribes a syncer to the spec from the last generation it acknowledged, it is sent again to
+// acknowledge every generation. The global hub sends all the spec when it can not resume from the generation, e.g.
+// when the epoch of the global hub changed.
+type specRequest struct {
+	Epoch      string `json:"epoch,omitempty"`
+	Generation uint64 `json:"generation,omitempty"`
+}
+
+// specEvent is a spec bundle, the generation is only set on the last bundle of the spec sent all at once.
+type specEvent struct {
+	Epoch      string  `json:"epoch"`
+	Generation uint64  `json:"generation,omitempty"`
+	Bundle     *Bundle `json:"bundle"`
+}
+
+// statusEvent is a status bundle of a syncer, the generations are per epoch of the syncer. The first event of a
+// stream has no bundle and asks for the last generation applied by the global hub.
+type statusEvent struct {
+	Epoch      string  `json:"epoch"`
+	Generation uint64  `json:"generation,omitempty"`
+	Bundle     *Bundle `json:"bundle,omitempty"`
+}
+
+// statusAck acknowledges the status bundles applied by the global hub up to the generation.
+type statusAck struct {
+	Generation uint64 `json:"generation"`
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return grpcCodecName }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// grpcTransport streams the spec from the global hub and the status to it, the streams resume from the last
+// acknowledged generation when they reconnect.
+type grpcTransport struct {
+	conn *grpc.ClientConn
+
+	lock sync.Mutex
+	// epoch identifies the status generations of this syncer
+	epoch      string
+	generation uint64
+	unacked    []*statusEvent
+	connected  bool
+	notify     chan struct{}
+
+	cancel context.CancelFunc
+	ctx    context.Context
+}
+
+// NewGRPCTransport connects to the sync service of the global hub with the credentials of the config, the streams
+// are reconnected until the transport is closed.
+func NewGRPCTransport(address string, config *rest.Config, hub string) (Transport, error) {
+	tlsConfig, err := rest.TLSConfigFor(config)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		return nil, fmt.Errorf("the sync service %s requires TLS", address)
+	}
+	token := config.BearerToken
+	if len(token) == 0 && len(config.BearerTokenFile) > 0 {
+		data, err := os.ReadFile(config.BearerTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+
+	conn, err := grpc.Dial(address,
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+		grpc.WithPerRPCCredentials(tokenCredentials(token)),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: 30 * time.Second, Timeout: 10 * time.Second}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the sync service %s: %v", address, err)
+	}
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(context.Background(), grpcHubMetadata, hub))
+	t := &grpcTransport{
+		conn:   conn,
+		epoch:  rand.String(16),
+		notify: make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	go t.reconnect(ctx, "status", t.statusStream)
+	return t, nil
+}
+
+// Publish queues the status bundles until the global hub acknowledges them, the spec resync requests are not needed
+// as the spec stream resumes on its own.
+func (t *grpcTransport) Publish(topic string, bundle *Bundle) error {
+	if topic != StatusTopic {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.unacked) >= grpcStatusBufferSize {
+		return errStatusBufferFull
+	}
+	t.generation++
+	t.unacked = append(t.unacked, &statusEvent{Epoch: t.epoch, Generation: t.generation, Bundle: bundle})
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Subscribe streams the spec to the handler, the global hub never asks for all the status as it acknowledges it.
+func (t *grpcTransport) Subscribe(ctx context.Context, topic string, handler func(*Bundle)) error {
+	if topic != SpecTopic {
+		return nil
+	}
+	specCtx, cancel := context.WithCancel(t.ctx)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-specCtx.Done():
+		}
+	}()
+	request := &specRequest{}
+	go t.reconnect(specCtx, "spec", func(ctx context.Context) error {
+		return t.specStream(ctx, request, handler)
+	})
+	return nil
+}
+
+func (t *grpcTransport) Connected() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.connected
+}
+
+func (t *grpcTransport) Close() {
+	t.cancel()
+	t.conn.Close()
+}
+
+// reconnect runs the stream until the context is done.
+func (t *grpcTransport) reconnect(ctx context.Context, name string, stream func(context.Context) error) {
+	for ctx.Err() == nil {
+		if err := stream(ctx); err != nil && ctx.Err() == nil {
+			klog.Warningf("The %s stream of the global hub is disconnected: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(grpcReconnectWait):
+		}
+	}
+}
+
+// specStream resumes the spec from the last acknowledged generation, and acknowledges the bundles once handled.
+func (t *grpcTransport) specStream(ctx context.Context, request *specRequest, handler func(*Bundle)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := t.conn.NewStream(ctx, &syncServiceDesc.Streams[0], "/globalhub.Sync/Spec", grpc.CallContentSubtype(grpcCodecName))
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(request); err != nil {
+		return err
+	}
+	klog.Infof("Streaming the spec from the global hub, from generation %d", request.Generation)
+	for {
+		event := &specEvent{}
+		if err := stream.RecvMsg(event); err != nil {
+			return err
+		}
+		if event.Bundle != nil {
+			handler(event.Bundle)
+		}
+		if event.Generation == 0 {
+			continue
+		}
+		request.Epoch, request.Generation = event.Epoch, event.Generation
+		if err := stream.SendMsg(request); err != nil {
+			return err
+		}
+	}
+}
+
+// statusStream sends the status bundles not acknowledged yet, starting after the last generation applied by the
+// global hub.
+func (t *grpcTransport) statusStream(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := t.conn.NewStream(ctx, &syncServiceDesc.Streams[1], "/globalhub.Sync/Status", grpc.CallContentSubtype(grpcCodecName))
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(&statusEvent{Epoch: t.epoch}); err != nil {
+		return err
+	}
+	ack := &statusAck{}
+	if err := stream.RecvMsg(ack); err != nil {
+		return err
+	}
+	t.acknowledged(ack.Generation)
+	t.setConnected(true)
+	defer t.setConnected(false)
+	klog.Infof("Streaming the status to the global hub, from generation %d", ack.Generation)
+
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			ack := &statusAck{}
+			if err := stream.RecvMsg(ack); err != nil {
+				errCh <- err
+				return
+			}
+			t.acknowledged(ack.Generation)
+		}
+	}()
+
+	sent := ack.Generation
+	for {
+		for _, event := range t.unsent(sent) {
+			if err := stream.SendMsg(event); err != nil {
+				return err
+			}
+			sent = event.Generation
+		}
+		select {
+		case <-t.notify:
+		case err := <-errCh:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// acknowledged drops the status bundles applied by the global hub.
+func (t *grpcTransport) acknowledged(generation uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	i := 0
+	for i < len(t.unacked) && t.unacked[i].Generation <= generation {
+		i++
+	}
+	t.unacked = t.unacked[i:]
+}
+
+func (t *grpcTransport) unsent(sent uint64) []*statusEvent {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	events := []*statusEvent{}
+	for _, event := range t.unacked {
+		if event.Generation > sent {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (t *grpcTransport) setConnected(connected bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.connected = connected
+}
+
+// tokenCredentials sends the bearer token of the syncer with every stream.
+type tokenCredentials string
+
+func (c tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	return map[string]string{"authorization": "Bearer " + string(c)}, nil
+}
+
+func (c tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
@@ -0,0 +1,383 @@
+package syncer
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// specLogSize is the number of the spec generations a reconnecting syncer can resume from, it receives all the
+	// spec again when it is further behind
+	specLogSize = 4096
+	// specStreamBuffer is the number of the spec generations a stream can lag behind before it is closed, the syncer
+	// resumes it from its last acknowledged generation
+	specStreamBuffer = 1024
+)
+
+// GRPCAuthFunc authenticates the request of a syncer and authorizes it to sync the regional hub, the request carries
+// the authorization header and the TLS state of the stream.
+type GRPCAuthFunc func(req *http.Request, hub string) error
+
+type hubContextKey struct{}
+
+// grpcServer serves the sync service of the global hub. The spec changes are numbered by generation in a log, a
+// syncer resumes from the last generation it acknowledged when it is still in the log and the epoch of the server is
+// unchanged, otherwise it receives all the spec. The status generations applied are tracked per hub and per epoch of
+// the syncer, a reconnecting syncer only sends the status not applied yet.
+type grpcServer struct {
+	server *grpc.Server
+	auth   GRPCAuthFunc
+
+	lock        sync.Mutex
+	epoch       string
+	generation  uint64
+	log         []*specEvent
+	objects     map[schema.GroupVersionResource]map[string]BundleObject
+	synced      map[schema.GroupVersionResource]bool
+	subscribers map[chan *specEvent]bool
+	acked       map[string]uint64
+
+	statusLock    sync.Mutex
+	statusHandler func(*Bundle)
+	applied       map[string]*statusEvent
+}
+
+// StartGRPCServer serves the sync service on the address, it sends the spec of the global hub to the syncers using the
+// grpc transport and applies the status they send to the global hub.
+func StartGRPCServer(ctx context.Context, config *rest.Config, address string, tlsConfig *tls.Config, auth GRPCAuthFunc) error {
+	s := newGRPCServer(auth)
+	s.server = grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(tlsConfig)),
+		grpc.StreamInterceptor(s.authenticate),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: 10 * time.Second, PermitWithoutStream: true}),
+	)
+	s.server.RegisterService(&syncServiceDesc, s)
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	if err := startTransportServer(ctx, config, s); err != nil {
+		listener.Close()
+		return err
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil {
+			klog.Errorf("The sync service on %s stopped: %v", address, err)
+		}
+	}()
+	klog.Infof("Serving the sync service on %s", address)
+	return nil
+}
+
+// newGRPCServer returns the sync service with a new epoch, the syncers receive all the spec again.
+func newGRPCServer(auth GRPCAuthFunc) *grpcServer {
+	return &grpcServer{
+		auth:        auth,
+		epoch:       rand.String(16),
+		objects:     map[schema.GroupVersionResource]map[string]BundleObject{},
+		synced:      map[schema.GroupVersionResource]bool{},
+		subscribers: map[chan *specEvent]bool{},
+		acked:       map[string]uint64{},
+		applied:     map[string]*statusEvent{},
+	}
+}
+
+// Publish numbers the spec bundles by generation and sends them to the spec streams. The periodic full bundles only
+// add the objects changed since the previous one to the log.
+func (s *grpcServer) Publish(topic string, bundle *Bundle) error {
+	if topic != SpecTopic {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	gvr := bundle.GVR()
+	if _, ok := s.objects[gvr]; !ok {
+		s.objects[gvr] = map[string]BundleObject{}
+	}
+	objects := s.objects[gvr]
+	if bundle.Full && s.synced[gvr] {
+		bundle = diffBundle(objects, bundle)
+		if len(bundle.Objects) == 0 {
+			return nil
+		}
+	}
+	if bundle.Full {
+		s.objects[gvr] = map[string]BundleObject{}
+		objects = s.objects[gvr]
+		s.synced[gvr] = true
+	}
+	for _, bundleObj := range bundle.Objects {
+		key := bundleObjectKey(bundleObj.Namespace, bundleObj.Name)
+		if bundleObj.Deleted || bundleObj.Object == nil {
+			delete(objects, key)
+			continue
+		}
+		objects[key] = bundleObj
+	}
+
+	s.generation++
+	event := &specEvent{Epoch: s.epoch, Generation: s.generation, Bundle: bundle}
+	s.log = append(s.log, event)
+	if len(s.log) > 2*specLogSize {
+		s.log = append([]*specEvent{}, s.log[len(s.log)-specLogSize:]...)
+	}
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// the syncer resumes from its last acknowledged generation
+			close(subscriber)
+			delete(s.subscribers, subscriber)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers the handler of the status bundles, the syncers stream the spec on their own.
+func (s *grpcServer) Subscribe(ctx context.Context, topic string, handler func(*Bundle)) error {
+	if topic != StatusTopic {
+		return nil
+	}
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+	s.statusHandler = handler
+	return nil
+}
+
+func (s *grpcServer) Connected() bool {
+	return true
+}
+
+func (s *grpcServer) Close() {
+	s.server.Stop()
+}
+
+// authenticate checks the credentials of the stream for the hub of its metadata.
+func (s *grpcServer) authenticate(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	hubs := md.Get(grpcHubMetadata)
+	if len(hubs) != 1 || len(hubs[0]) == 0 {
+		return status.Errorf(codes.InvalidArgument, "the %s metadata is required", grpcHubMetadata)
+	}
+	req := &http.Request{Header: http.Header{}}
+	req = req.WithContext(stream.Context())
+	for _, authorization := range md.Get("authorization") {
+		req.Header.Add("Authorization", authorization)
+	}
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			req.TLS = &tlsInfo.State
+		}
+	}
+	if err := s.auth(req, hubs[0]); err != nil {
+		return status.Errorf(codes.PermissionDenied, "%v", err)
+	}
+	return handler(srv, &hubStream{ServerStream: stream, ctx: context.WithValue(stream.Context(), hubContextKey{}, hubs[0])})
+}
+
+// spec streams the spec to a syncer, from the generation it acknowledged last when possible.
+func (s *grpcServer) spec(stream grpc.ServerStream) error {
+	hub := stream.Context().Value(hubContextKey{}).(string)
+	request := &specRequest{}
+	if err := stream.RecvMsg(request); err != nil {
+		return err
+	}
+	events, subscriber := s.subscribe(hub, request)
+	defer s.unsubscribe(subscriber)
+
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			ack := &specRequest{}
+			if err := stream.RecvMsg(ack); err != nil {
+				errCh <- err
+				return
+			}
+			s.acknowledge(hub, ack)
+		}
+	}()
+
+	for _, event := range events {
+		if err := stream.SendMsg(event); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case event, ok := <-subscriber:
+			if !ok {
+				return status.Errorf(codes.ResourceExhausted, "the spec stream of %s is lagging behind", hub)
+			}
+			if err := stream.SendMsg(event); err != nil {
+				return err
+			}
+		case err := <-errCh:
+			return err
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// subscribe returns the spec changes after the generation of the request, or all the spec when the generation is
+// not in the log anymore, then registers the stream for the next changes.
+func (s *grpcServer) subscribe(hub string, request *specRequest) ([]*specEvent, chan *specEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	subscriber := make(chan *specEvent, specStreamBuffer)
+	s.subscribers[subscriber] = true
+
+	if request.Epoch == s.epoch && request.Generation <= s.generation &&
+		(len(s.log) == 0 || request.Generation+1 >= s.log[0].Generation) {
+		events := []*specEvent{}
+		for _, event := range s.log {
+			if event.Generation > request.Generation {
+				events = append(events, event)
+			}
+		}
+		klog.Infof("Resuming the spec of %s from generation %d, %d generations behind (last acknowledged %d)",
+			hub, request.Generation, len(events), s.acked[hub])
+		return events, subscriber
+	}
+
+	events := []*specEvent{}
+	for gvr, objects := range s.objects {
+		if !s.synced[gvr] {
+			continue
+		}
+		bundle := newBundle("", gvr)
+		bundle.Full = true
+		for _, bundleObj := range objects {
+			bundle.Objects = append(bundle.Objects, bundleObj)
+		}
+		events = append(events, &specEvent{Epoch: s.epoch, Bundle: bundle})
+	}
+	if len(events) > 0 {
+		events[len(events)-1].Generation = s.generation
+	}
+	klog.Infof("Sending all the spec to %s at generation %d", hub, s.generation)
+	return events, subscriber
+}
+
+func (s *grpcServer) unsubscribe(subscriber chan *specEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.subscribers, subscriber)
+}
+
+func (s *grpcServer) acknowledge(hub string, ack *specRequest) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if ack.Epoch == s.epoch {
+		s.acked[hub] = ack.Generation
+	}
+	klog.V(4).Infof("The spec of %s is acknowledged at generation %d of %d", hub, ack.Generation, s.generation)
+}
+
+// status applies the status bundles of a syncer in order and acknowledges them, the bundles applied before the
+// syncer reconnected are skipped.
+func (s *grpcServer) status(stream grpc.ServerStream) error {
+	hub := stream.Context().Value(hubContextKey{}).(string)
+	hello := &statusEvent{}
+	if err := stream.RecvMsg(hello); err != nil {
+		return err
+	}
+	if err := stream.SendMsg(&statusAck{Generation: s.appliedGeneration(hub, hello.Epoch)}); err != nil {
+		return err
+	}
+	for {
+		event := &statusEvent{}
+		if err := stream.RecvMsg(event); err != nil {
+			return err
+		}
+		s.applyStatus(hub, event)
+		if err := stream.SendMsg(&statusAck{Generation: event.Generation}); err != nil {
+			return err
+		}
+	}
+}
+
+// appliedGeneration returns the last status generation applied of the epoch of the syncer.
+func (s *grpcServer) appliedGeneration(hub, epoch string) uint64 {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	applied, ok := s.applied[hub]
+	if !ok || applied.Epoch != epoch {
+		return 0
+	}
+	return applied.Generation
+}
+
+// applyStatus applies the status bundle unless it is already applied, the status of a hub is applied in order. The
+// bundles of the resources not synced from the regional hubs or out of the namespace of the hub are dropped, see
+// CheckStatusBundle.
+func (s *grpcServer) applyStatus(hub string, event *statusEvent) {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	applied, ok := s.applied[hub]
+	if ok && applied.Epoch == event.Epoch && event.Generation <= applied.Generation {
+		return
+	}
+	if event.Bundle != nil && s.statusHandler != nil {
+		// the hub of the bundle is the authenticated hub
+		event.Bundle.Hub = hub
+		if err := CheckStatusBundle(hub, event.Bundle); err != nil {
+			klog.Warningf("Dropping the status generation %d of %s: %v", event.Generation, hub, err)
+		} else {
+			s.statusHandler(event.Bundle)
+		}
+	}
+	s.applied[hub] = &statusEvent{Epoch: event.Epoch, Generation: event.Generation}
+}
+
+// diffBundle returns the changes of the full bundle against the objects sent before.
+func diffBundle(objects map[string]BundleObject, full *Bundle) *Bundle {
+	bundle := newBundle(full.Hub, full.GVR())
+	received := map[string]bool{}
+	for _, bundleObj := range full.Objects {
+		key := bundleObjectKey(bundleObj.Namespace, bundleObj.Name)
+		received[key] = true
+		if existing, ok := objects[key]; ok && equality.Semantic.DeepEqual(existing.Object, bundleObj.Object) {
+			continue
+		}
+		bundle.Objects = append(bundle.Objects, bundleObj)
+	}
+	for key, existing := range objects {
+		if !received[key] {
+			bundle.Objects = append(bundle.Objects, BundleObject{Namespace: existing.Namespace, Name: existing.Name, Deleted: true})
+		}
+	}
+	return bundle
+}
+
+// hubStream carries the authenticated hub in the context of the stream.
+type hubStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *hubStream) Context() context.Context {
+	return s.ctx
+}
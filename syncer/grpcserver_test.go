@@ -0,0 +1,249 @@
+package syncer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newHubStatusBundle(hub, resource, namespace, name string) *Bundle {
+	obj := &unstructured.Unstructured{}
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	bundle := &Bundle{Hub: hub, Group: "policy.open-cluster-management.io", Version: "v1", Resource: resource}
+	bundle.Objects = []BundleObject{{Namespace: namespace, Name: name, Object: obj}}
+	return bundle
+}
+
+func TestGRPCServerApplyStatus(t *testing.T) {
+	s := newGRPCServer(nil)
+	applied := []*Bundle{}
+	s.statusHandler = func(bundle *Bundle) {
+		applied = append(applied, bundle)
+	}
+
+	moved := newHubStatusBundle("hub1", "policies", "hub1", "policy1")
+	moved.Objects[0].Object.SetNamespace("default")
+
+	for _, test := range []struct {
+		name    string
+		bundle  *Bundle
+		applied bool
+	}{
+		{"policy of the hub", newHubStatusBundle("hub1", "policies", "hub1", "policy1"), true},
+		{"policy of another hub", newHubStatusBundle("hub2", "policies", "hub1", "policy1"), true},
+		{"policy of another namespace", newHubStatusBundle("hub1", "policies", "hub2", "policy1"), false},
+		{"policy moved to another namespace", moved, false},
+		{"placementbinding", newHubStatusBundle("hub1", "placementbindings", "hub1", "binding1"), false},
+	} {
+		applied = nil
+		s.applyStatus("hub1", &statusEvent{Epoch: "epoch1", Generation: s.appliedGeneration("hub1", "epoch1") + 1, Bundle: test.bundle})
+		if (len(applied) == 1) != test.applied {
+			t.Errorf("%s: expected applied %v, got %d bundles", test.name, test.applied, len(applied))
+			continue
+		}
+		// the hub of the bundle is the authenticated hub
+		if test.applied && applied[0].Hub != "hub1" {
+			t.Errorf("%s: expected the bundle of hub1, got %s", test.name, applied[0].Hub)
+		}
+	}
+	// the dropped bundles are acknowledged, the syncer does not send them again
+	if generation := s.appliedGeneration("hub1", "epoch1"); generation != 5 {
+		t.Errorf("expected the generation 5 to be applied, got %d", generation)
+	}
+}
+
+func newSpecBundle(full bool, names ...string) *Bundle {
+	bundle := &Bundle{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies", Full: full}
+	for _, name := range names {
+		obj := &unstructured.Unstructured{}
+		obj.SetNamespace("default")
+		obj.SetName(name)
+		bundle.Objects = append(bundle.Objects, BundleObject{Namespace: "default", Name: name, Object: obj})
+	}
+	return bundle
+}
+
+// specNames returns the names of the objects of the events, and whether the spec is sent all at once.
+func specNames(events []*specEvent) ([]string, bool) {
+	names := []string{}
+	full := len(events) > 0
+	for _, event := range events {
+		full = full && event.Bundle.Full
+		for _, bundleObj := range event.Bundle.Objects {
+			name := bundleObj.Name
+			if bundleObj.Deleted {
+				name += "-"
+			}
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, full
+}
+
+func TestGRPCServerResumeSpec(t *testing.T) {
+	s := newGRPCServer(nil)
+	for _, bundle := range []*Bundle{
+		newSpecBundle(true, "policy1"),
+		newSpecBundle(false, "policy2"),
+		newSpecBundle(false, "policy3"),
+	} {
+		if err := s.Publish(SpecTopic, bundle); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		name     string
+		request  *specRequest
+		expected []string
+		full     bool
+	}{
+		{"resume", &specRequest{Epoch: s.epoch, Generation: 1}, []string{"policy2", "policy3"}, false},
+		{"up to date", &specRequest{Epoch: s.epoch, Generation: 3}, []string{}, false},
+		{"new syncer", &specRequest{}, []string{"policy1", "policy2", "policy3"}, true},
+		{"epoch changed", &specRequest{Epoch: "epoch1", Generation: 2}, []string{"policy1", "policy2", "policy3"}, true},
+		{"generation ahead", &specRequest{Epoch: s.epoch, Generation: 4}, []string{"policy1", "policy2", "policy3"}, true},
+	} {
+		events, subscriber := s.subscribe("hub1", test.request)
+		s.unsubscribe(subscriber)
+		if names, full := specNames(events); !reflect.DeepEqual(names, test.expected) || full != test.full {
+			t.Errorf("%s: expected %v (full %v), got %v (full %v)", test.name, test.expected, test.full, names, full)
+		}
+		// the syncer acknowledges the last generation of the events
+		if len(events) > 0 && events[len(events)-1].Generation != 3 {
+			t.Errorf("%s: expected the generation 3, got %d", test.name, events[len(events)-1].Generation)
+		}
+	}
+
+	// the periodic full bundle only sends the changes
+	events, subscriber := s.subscribe("hub1", &specRequest{Epoch: s.epoch, Generation: 3})
+	defer s.unsubscribe(subscriber)
+	if err := s.Publish(SpecTopic, newSpecBundle(true, "policy1", "policy2", "policy3")); err != nil {
+		t.Fatal(err)
+	}
+	changed := newSpecBundle(true, "policy1", "policy2")
+	changed.Objects[0].Object.SetLabels(map[string]string{"changed": "true"})
+	if err := s.Publish(SpecTopic, changed); err != nil {
+		t.Fatal(err)
+	}
+	events = append(events, <-subscriber)
+	if names, full := specNames(events); !reflect.DeepEqual(names, []string{"policy1", "policy3-"}) || full {
+		t.Errorf("expected the changes of policy1 and policy3, got %v (full %v)", names, full)
+	}
+	if len(subscriber) != 0 || s.generation != 4 {
+		t.Errorf("expected a single generation for the changes, got %d", s.generation)
+	}
+}
+
+func TestGRPCServerResumeStaleSpec(t *testing.T) {
+	s := newGRPCServer(nil)
+	if err := s.Publish(SpecTopic, newSpecBundle(true, "policy1")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2*specLogSize; i++ {
+		if err := s.Publish(SpecTopic, newSpecBundle(false, fmt.Sprintf("policy%d", i%2+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the generation is not in the log anymore
+	events, subscriber := s.subscribe("hub1", &specRequest{Epoch: s.epoch, Generation: 1})
+	s.unsubscribe(subscriber)
+	if names, full := specNames(events); !reflect.DeepEqual(names, []string{"policy1", "policy2"}) || !full {
+		t.Errorf("expected all the spec, got %v (full %v)", names, full)
+	}
+	if generation := events[len(events)-1].Generation; generation != s.generation {
+		t.Errorf("expected the generation %d, got %d", s.generation, generation)
+	}
+
+	// the oldest generation of the log
+	events, subscriber = s.subscribe("hub1", &specRequest{Epoch: s.epoch, Generation: s.log[0].Generation - 1})
+	s.unsubscribe(subscriber)
+	if _, full := specNames(events); full || len(events) != len(s.log) {
+		t.Errorf("expected the %d generations of the log, got %d (full %v)", len(s.log), len(events), full)
+	}
+}
+
+func TestGRPCServerResumeStatus(t *testing.T) {
+	s := newGRPCServer(nil)
+	applied := 0
+	s.statusHandler = func(*Bundle) {
+		applied++
+	}
+	apply := func(hub, epoch string, generation uint64) {
+		s.applyStatus(hub, &statusEvent{Epoch: epoch, Generation: generation,
+			Bundle: newHubStatusBundle(hub, "policies", hub, "policy1")})
+	}
+
+	apply("hub1", "epoch1", 1)
+	apply("hub1", "epoch1", 2)
+	// the syncer reconnects, the bundles sent before the acknowledgement are sent again
+	if generation := s.appliedGeneration("hub1", "epoch1"); generation != 2 {
+		t.Errorf("expected the generation 2 to be applied, got %d", generation)
+	}
+	apply("hub1", "epoch1", 2)
+	apply("hub1", "epoch1", 3)
+	if applied != 3 {
+		t.Errorf("expected the stale generation to be skipped, got %d bundles applied", applied)
+	}
+
+	// the syncer restarted with a new epoch
+	if generation := s.appliedGeneration("hub1", "epoch2"); generation != 0 {
+		t.Errorf("expected no generation of the new epoch, got %d", generation)
+	}
+	apply("hub1", "epoch2", 1)
+	// the generations are per hub
+	apply("hub2", "epoch1", 1)
+	if applied != 5 {
+		t.Errorf("expected the generations of the new epoch and of hub2 to be applied, got %d bundles applied", applied)
+	}
+}
+
+func TestGRPCServerRestart(t *testing.T) {
+	s := newGRPCServer(nil)
+	if err := s.Publish(SpecTopic, newSpecBundle(true, "policy1", "policy2")); err != nil {
+		t.Fatal(err)
+	}
+	request := &specRequest{Epoch: s.epoch, Generation: s.generation}
+	s.applyStatus("hub1", &statusEvent{Epoch: "epoch1", Generation: 2, Bundle: newHubStatusBundle("hub1", "policies", "hub1", "policy1")})
+
+	// the restarted server waits for the spec of the global hub before sending it all
+	restarted := newGRPCServer(nil)
+	events, subscriber := restarted.subscribe("hub1", request)
+	defer restarted.unsubscribe(subscriber)
+	if len(events) != 0 {
+		t.Errorf("expected no spec before the global hub sends it, got %d events", len(events))
+	}
+	if err := restarted.Publish(SpecTopic, newSpecBundle(true, "policy2")); err != nil {
+		t.Fatal(err)
+	}
+	// the syncer deletes policy1 as it is missing from the full bundle
+	event := <-subscriber
+	if names, full := specNames([]*specEvent{event}); !reflect.DeepEqual(names, []string{"policy2"}) || !full {
+		t.Errorf("expected all the spec, got %v (full %v)", names, full)
+	}
+	if event.Epoch == request.Epoch || event.Generation != 1 {
+		t.Errorf("expected the generation 1 of the new epoch, got %d of %s", event.Generation, event.Epoch)
+	}
+
+	// the syncer sends the status not acknowledged by the previous server again
+	if generation := restarted.appliedGeneration("hub1", "epoch1"); generation != 0 {
+		t.Errorf("expected no status applied by the restarted server, got %d", generation)
+	}
+	transport := &grpcTransport{epoch: "epoch1", notify: make(chan struct{}, 1)}
+	for i := 0; i < 3; i++ {
+		if err := transport.Publish(StatusTopic, newHubStatusBundle("hub1", "policies", "hub1", "policy1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	transport.acknowledged(2)
+	transport.acknowledged(restarted.appliedGeneration("hub1", "epoch1"))
+	if unsent := transport.unsent(0); len(unsent) != 1 || unsent[0].Generation != 3 {
+		t.Errorf("expected the generation 3 to be sent again, got %d events", len(unsent))
+	}
+}
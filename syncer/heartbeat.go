@@ -0,0 +1,34 @@
+package syncer
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	hubcontrolplanev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/hubcontrolplane/v1alpha1"
+)
+
+const (
+	// heartbeatManager owns the heartbeat time in the status of the hubcontrolplane
+	heartbeatManager = "syncer-heartbeat"
+	// HeartbeatPeriod is how often the syncer reports the regional hub is alive to the Global Hub
+	HeartbeatPeriod = 30 * time.Second
+)
+
+// reportHeartbeat sets the heartbeat time to the hubcontrolplane of the regional hub.
+func (c *Controller) reportHeartbeat(ctx context.Context) {
+	now := metav1.Now()
+	if err := c.applyHubControlPlaneStatus(ctx, heartbeatManager, hubcontrolplanev1alpha1.HubControlPlaneStatus{
+		HeartbeatTime: &now,
+	}); err != nil {
+		// the hubcontrolplane is created by the status syncer
+		if !errors.IsNotFound(err) {
+			klog.Errorf("Failed to report the heartbeat to hubcontrolplane %s: %v", c.syncerName, err)
+		}
+		return
+	}
+	klog.V(4).Infof("Reported the heartbeat to hubcontrolplane %s", c.syncerName)
+}
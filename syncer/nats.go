@@ -0,0 +1,275 @@
+package syncer
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+)
+
+// chunkOverhead is the room left in a chunk for the fields of the bundle
+const chunkOverhead = 4 * 1024
+
+// BrokerConfig is the connection to the NATS broker, it is always authenticated and encrypted.
+type BrokerConfig struct {
+	URL string
+	// User and PasswordFile authenticate to the broker, the broker only allows the user of a syncer to publish the
+	// status of its own regional hub, see BrokerUser
+	User         string
+	PasswordFile string
+	// CAFile verifies the certificate of the broker
+	CAFile string
+}
+
+// natsTransport carries the bundles through a NATS broker. The bundles sent by the syncers are published to the
+// subject of their regional hub under their topic, the broker permissions make sure a syncer only publishes to the
+// subjects of its regional hub.
+type natsTransport struct {
+	conn *nats.Conn
+}
+
+// NewNATSTransport connects to the NATS broker, the connection is retried until the transport is closed.
+func NewNATSTransport(config *BrokerConfig, name string) (Transport, error) {
+	if config == nil || len(config.User) == 0 || len(config.PasswordFile) == 0 {
+		return nil, errors.New("the broker transport requires the user and the password of the broker")
+	}
+	if len(config.CAFile) == 0 {
+		return nil, errors.New("the broker transport requires TLS, the CA of the broker is missing")
+	}
+	password, err := os.ReadFile(config.PasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the password of the broker: %v", err)
+	}
+
+	conn, err := nats.Connect(config.URL,
+		nats.Name(name),
+		nats.UserInfo(config.User, strings.TrimSpace(string(password))),
+		nats.RootCAs(config.CAFile),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(time.Second),
+		nats.RetryOnFailedConnect(true),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			klog.Warningf("Disconnected from the broker %s: %v", config.URL, err)
+		}),
+		nats.ReconnectHandler(func(_ *nats.Conn) {
+			klog.Infof("Reconnected to the broker %s", config.URL)
+		}),
+		// e.g. the permission violations
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			klog.Errorf("Error from the broker %s: %v", config.URL, err)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the broker %s: %v", config.URL, err)
+	}
+	return &natsTransport{conn: conn}, nil
+}
+
+// hubTopic returns true for the topics the syncers publish to, their subjects end with the regional hub.
+func hubTopic(topic string) bool {
+	return topic == StatusTopic || topic == SpecResyncTopic
+}
+
+func (t *natsTransport) Publish(topic string, bundle *Bundle) error {
+	subject := topic
+	if hubTopic(topic) {
+		if errs := validation.IsDNS1123Label(bundle.Hub); len(errs) > 0 {
+			return fmt.Errorf("invalid regional hub %q: %s", bundle.Hub, strings.Join(errs, ", "))
+		}
+		subject = topic + "." + bundle.Hub
+	}
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+	if err := t.conn.Publish(subject, data); !errors.Is(err, nats.ErrMaxPayload) {
+		return err
+	}
+
+	// e.g. the full spec of a large fleet, 1MB by default on the broker
+	chunks, err := splitBundle(bundle, int(t.conn.MaxPayload()))
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		if err := t.conn.Publish(subject, data); err != nil {
+			return fmt.Errorf("failed to send chunk %d of %d of the %s: %w", chunk.Chunk, chunk.Chunks, bundle.Resource, err)
+		}
+	}
+	klog.V(2).Infof("Sent the %d %s in %d chunks to %s", len(bundle.Objects), bundle.Resource, len(chunks), subject)
+	return nil
+}
+
+// splitBundle splits the objects of the bundle in chunks of the maximum payload.
+func splitBundle(bundle *Bundle, maxPayload int) ([]*Bundle, error) {
+	id := rand.String(16)
+	newChunk := func() *Bundle {
+		chunk := *bundle
+		chunk.ID = id
+		chunk.Objects = nil
+		return &chunk
+	}
+
+	chunks := []*Bundle{newChunk()}
+	size := 0
+	for _, bundleObj := range bundle.Objects {
+		data, err := json.Marshal(bundleObj)
+		if err != nil {
+			return nil, err
+		}
+		if len(data)+chunkOverhead > maxPayload {
+			return nil, fmt.Errorf("%s %s/%s: %w", bundle.Resource, bundleObj.Namespace, bundleObj.Name, nats.ErrMaxPayload)
+		}
+		chunk := chunks[len(chunks)-1]
+		if size+len(data)+chunkOverhead > maxPayload && len(chunk.Objects) > 0 {
+			chunk = newChunk()
+			chunks = append(chunks, chunk)
+			size = 0
+		}
+		chunk.Objects = append(chunk.Objects, bundleObj)
+		size += len(data) + 1
+	}
+	for i, chunk := range chunks {
+		chunk.Chunk = i + 1
+		chunk.Chunks = len(chunks)
+	}
+	return chunks, nil
+}
+
+// chunkedBundle is a bundle being received in chunks
+type chunkedBundle struct {
+	bundle   *Bundle
+	received map[int]bool
+}
+
+// assemble returns the bundle once all its chunks are received. The chunks of a previous bundle of the same subject
+// and resource which are still missing are dropped, the next bundles replace it.
+func assemble(pending map[string]*chunkedBundle, subject string, chunk *Bundle) *Bundle {
+	if chunk.Chunks <= 1 {
+		return chunk
+	}
+	key := subject + "/" + chunk.GVR().String()
+	assembled, ok := pending[key]
+	if !ok || assembled.bundle.ID != chunk.ID {
+		bundle := *chunk
+		bundle.Objects = nil
+		assembled = &chunkedBundle{bundle: &bundle, received: map[int]bool{}}
+		pending[key] = assembled
+	}
+	if assembled.received[chunk.Chunk] {
+		return nil
+	}
+	assembled.received[chunk.Chunk] = true
+	assembled.bundle.Objects = append(assembled.bundle.Objects, chunk.Objects...)
+	if len(assembled.received) < chunk.Chunks {
+		return nil
+	}
+	delete(pending, key)
+	bundle := assembled.bundle
+	bundle.ID, bundle.Chunk, bundle.Chunks = "", 0, 0
+	return bundle
+}
+
+func (t *natsTransport) Subscribe(ctx context.Context, topic string, handler func(*Bundle)) error {
+	subject := topic
+	if hubTopic(topic) {
+		subject = topic + ".*"
+	}
+	// the messages of a subscription are handled one at a time
+	pending := map[string]*chunkedBundle{}
+	subscription, err := t.conn.Subscribe(subject, func(msg *nats.Msg) {
+		bundle := &Bundle{}
+		if err := json.Unmarshal(msg.Data, bundle); err != nil {
+			klog.Errorf("Failed to decode the bundle from %s: %v", msg.Subject, err)
+			return
+		}
+		if hubTopic(topic) {
+			// the subject is the regional hub the broker allowed the sender to publish for
+			hub := strings.TrimPrefix(msg.Subject, topic+".")
+			if len(bundle.Hub) > 0 && bundle.Hub != hub {
+				klog.Warningf("Dropping the bundle of %s sent to %s", bundle.Hub, msg.Subject)
+				return
+			}
+			bundle.Hub = hub
+		}
+		if bundle = assemble(pending, msg.Subject, bundle); bundle != nil {
+			handler(bundle)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		if err := subscription.Unsubscribe(); err != nil && err != nats.ErrConnectionClosed {
+			klog.Errorf("Failed to unsubscribe from %s: %v", subject, err)
+		}
+	}()
+	return nil
+}
+
+func (t *natsTransport) Connected() bool {
+	return t.conn.IsConnected()
+}
+
+func (t *natsTransport) Close() {
+	t.conn.Close()
+}
+
+// BrokerUser returns the user of the global hub or of the syncer of a regional hub on the broker. The global hub
+// publishes the spec and receives the status of all the regional hubs, a syncer receives the spec and only publishes
+// to the subjects of its regional hub.
+func BrokerUser(name, password string) *natsserver.User {
+	permissions := &natsserver.Permissions{
+		Publish:   &natsserver.SubjectPermission{Allow: []string{StatusTopic + "." + name, SpecResyncTopic + "." + name}},
+		Subscribe: &natsserver.SubjectPermission{Allow: []string{SpecTopic, StatusResyncTopic}},
+	}
+	if name == brokerServerName {
+		permissions = &natsserver.Permissions{
+			Publish:   &natsserver.SubjectPermission{Allow: []string{SpecTopic, StatusResyncTopic}},
+			Subscribe: &natsserver.SubjectPermission{Allow: []string{StatusTopic + ".*", SpecResyncTopic + ".*"}},
+		}
+	}
+	return &natsserver.User{Username: name, Password: password, Permissions: permissions}
+}
+
+// StartEmbeddedBroker starts a NATS broker in process, for the development and the tests. The broker only accepts
+// the users over TLS, the port is random when it is -1.
+func StartEmbeddedBroker(host string, port int, tlsConfig *tls.Config, users []*natsserver.User) (*natsserver.Server, error) {
+	if tlsConfig == nil || len(users) == 0 {
+		return nil, errors.New("the embedded broker requires TLS and users")
+	}
+	broker, err := natsserver.NewServer(&natsserver.Options{
+		Host:       host,
+		Port:       port,
+		TLS:        true,
+		TLSConfig:  tlsConfig,
+		TLSTimeout: 5,
+		Users:      users,
+		NoSigs:     true,
+		NoLog:      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	go broker.Start()
+	if !broker.ReadyForConnections(10 * time.Second) {
+		broker.Shutdown()
+		return nil, fmt.Errorf("the embedded broker on %s:%d is not ready", host, port)
+	}
+	return broker, nil
+}
@@ -0,0 +1,94 @@
+package syncer
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// SyncerLabel is set on the objects applied by the spec syncer to the regional hub, the value is the syncer name
+	SyncerLabel = "global-hub.open-cluster-management.io/syncer"
+
+	orphanCollectionPeriod = 10 * time.Minute
+)
+
+// collectOrphans deletes the objects applied by the spec syncer whose object on the global hub is gone, e.g. deleted
+// while the syncer was down.
+func (c *Controller) collectOrphans(ctx context.Context) {
+	for _, gvrstr := range c.gvrs {
+		gvr, _ := schema.ParseResourceArg(gvrstr)
+		if gvr.Resource == "globalmanagedclustersets" {
+			c.collectOrphanedClusterSets(ctx)
+			continue
+		}
+
+		for _, item := range c.toInformers.ForResource(*gvr).Informer().GetStore().List() {
+			downstreamObj, ok := item.(*unstructured.Unstructured)
+			if !ok || !c.isOwned(downstreamObj) {
+				continue
+			}
+			// the copies made on the regional hub, e.g. the replicated policies, keep the labels in other namespaces
+			if downstreamObj.GetLabels()[GlobalHubPolicyNamespaceLabel] != downstreamObj.GetNamespace() {
+				continue
+			}
+			key := downstreamObj.GetName()
+			if len(downstreamObj.GetNamespace()) > 0 {
+				key = downstreamObj.GetNamespace() + "/" + key
+			}
+			if _, exists, err := c.fromInformers.ForResource(*gvr).Informer().GetStore().GetByKey(key); err != nil || exists {
+				continue
+			}
+
+			if err := c.deleteFromDownstream(ctx, *gvr, downstreamObj.GetNamespace(), downstreamObj.GetName()); err != nil {
+				klog.Errorf("Failed to delete orphaned %s %s: %v", gvr.Resource, key, err)
+				continue
+			}
+			klog.Infof("Deleted orphaned %s %s", gvr.Resource, key)
+		}
+	}
+}
+
+// collectOrphanedClusterSets deletes the managedclustersets and the bindings of the deleted global managedclustersets.
+func (c *Controller) collectOrphanedClusterSets(ctx context.Context) {
+	gvr, _ := schema.ParseResourceArg("globalmanagedclustersets.v1alpha1.global-hub.open-cluster-management.io")
+	names := sets.NewString()
+	for _, downstreamGVR := range []schema.GroupVersionResource{managedClusterSetGVR, managedClusterSetBindingGVR} {
+		list, err := c.toClient.Resource(downstreamGVR).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+			LabelSelector: globalManagedClusterSetLabel,
+		})
+		if err != nil {
+			klog.Errorf("Failed to list %s: %v", downstreamGVR.Resource, err)
+			return
+		}
+		for _, downstreamObj := range list.Items {
+			if c.isOwned(&downstreamObj) {
+				names.Insert(downstreamObj.GetLabels()[globalManagedClusterSetLabel])
+			}
+		}
+	}
+
+	for _, name := range names.List() {
+		if _, exists, err := c.fromInformers.ForResource(*gvr).Informer().GetStore().GetByKey(name); err != nil || exists {
+			continue
+		}
+		if err := c.deleteClusterSetFromDownstream(ctx, name); err != nil {
+			klog.Errorf("Failed to delete orphaned managedclusterset %s: %v", name, err)
+		}
+	}
+}
+
+// isOwned returns true for the objects applied by this syncer, the objects applied before the syncer label was set
+// are identified by the field manager.
+func (c *Controller) isOwned(obj *unstructured.Unstructured) bool {
+	syncer, ok := obj.GetLabels()[SyncerLabel]
+	if ok {
+		return syncer == c.syncerName
+	}
+	return isAppliedBySyncer(obj)
+}
@@ -0,0 +1,178 @@
+package syncer
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	hubcontrolplanev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/hubcontrolplane/v1alpha1"
+)
+
+const (
+	// propagationReportManager owns the propagation in the status of the hubcontrolplane
+	propagationReportManager = "syncer-propagation"
+	propagationReportPeriod  = 5 * time.Second
+	// maxPropagationReports and maxPropagationMessageLength bound the size of the propagation in the status of the
+	// hubcontrolplane, far below the size limit of the requests of the global hub apiserver
+	maxPropagationReports       = 500
+	maxPropagationMessageLength = 512
+)
+
+// propagationReports keeps the outcome of the last apply of every global object to the regional hub, and the last
+// generation of the global objects seen by the syncer.
+type propagationReports struct {
+	lock        sync.Mutex
+	reports     map[string]hubcontrolplanev1alpha1.PropagationReport
+	generations map[string]int64
+	changes     int64
+	reported    int64
+}
+
+func newPropagationReports() *propagationReports {
+	return &propagationReports{
+		reports:     map[string]hubcontrolplanev1alpha1.PropagationReport{},
+		generations: map[string]int64{},
+	}
+}
+
+func propagationKey(gvr schema.GroupVersionResource, namespace, name string) string {
+	return gvr.GroupResource().String() + "/" + namespace + "/" + name
+}
+
+// observe keeps the generation of the global object received from the global hub, the object is Pending until its
+// apply at this generation is recorded.
+func (p *propagationReports) observe(gvr schema.GroupVersionResource, upstreamObj *unstructured.Unstructured) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	key := propagationKey(gvr, upstreamObj.GetNamespace(), upstreamObj.GetName())
+	if generation, ok := p.generations[key]; ok && generation >= upstreamObj.GetGeneration() {
+		return
+	}
+	p.generations[key] = upstreamObj.GetGeneration()
+	if _, ok := p.reports[key]; !ok {
+		p.reports[key] = hubcontrolplanev1alpha1.PropagationReport{
+			Group:     gvr.Group,
+			Resource:  gvr.Resource,
+			Namespace: upstreamObj.GetNamespace(),
+			Name:      upstreamObj.GetName(),
+			State:     hubcontrolplanev1alpha1.PropagationPending,
+		}
+	}
+	p.changes++
+}
+
+func (p *propagationReports) record(gvr schema.GroupVersionResource, upstreamObj *unstructured.Unstructured, err error) {
+	report := hubcontrolplanev1alpha1.PropagationReport{
+		Group:      gvr.Group,
+		Resource:   gvr.Resource,
+		Namespace:  upstreamObj.GetNamespace(),
+		Name:       upstreamObj.GetName(),
+		Generation: upstreamObj.GetGeneration(),
+		State:      hubcontrolplanev1alpha1.PropagationApplied,
+	}
+	if err != nil {
+		report.State = hubcontrolplanev1alpha1.PropagationFailed
+		report.Message = err.Error()
+		if len(report.Message) > maxPropagationMessageLength {
+			report.Message = strings.ToValidUTF8(report.Message[:maxPropagationMessageLength], "")
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	key := propagationKey(gvr, report.Namespace, report.Name)
+	if report.Generation > p.generations[key] {
+		p.generations[key] = report.Generation
+	}
+	if previous, ok := p.reports[key]; ok && previous == report {
+		return
+	}
+	p.reports[key] = report
+	p.changes++
+}
+
+func (p *propagationReports) remove(gvr schema.GroupVersionResource, namespace, name string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	key := propagationKey(gvr, namespace, name)
+	if _, ok := p.reports[key]; !ok {
+		return
+	}
+	delete(p.reports, key)
+	delete(p.generations, key)
+	p.changes++
+}
+
+// status returns the reports of the global objects not applied at their last generation, sorted by resource,
+// namespace and name and limited to maxPropagationReports, with the count of the reports left out and the count of
+// changes they include. It returns false when the reports are already reported.
+func (p *propagationReports) status() ([]hubcontrolplanev1alpha1.PropagationReport, int32, int64, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.changes == p.reported {
+		return nil, 0, 0, false
+	}
+	keys := []string{}
+	for key, generation := range p.generations {
+		if report, ok := p.reports[key]; ok && report.State == hubcontrolplanev1alpha1.PropagationApplied &&
+			report.Generation >= generation {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	omitted := 0
+	if len(keys) > maxPropagationReports {
+		omitted = len(keys) - maxPropagationReports
+		keys = keys[:maxPropagationReports]
+	}
+	reports := make([]hubcontrolplanev1alpha1.PropagationReport, 0, len(keys))
+	for _, key := range keys {
+		report := p.reports[key]
+		if report.Generation < p.generations[key] {
+			report.State = hubcontrolplanev1alpha1.PropagationPending
+			report.Message = ""
+		}
+		reports = append(reports, report)
+	}
+	return reports, int32(omitted), p.changes, true
+}
+
+func (p *propagationReports) markReported(changes int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.reported = changes
+}
+
+// reportPropagation sets the global objects the regional hub did not apply at their last generation to the
+// hubcontrolplane of the regional hub.
+func (c *Controller) reportPropagation(ctx context.Context) {
+	now := metav1.Now()
+	reports, omitted, changes, ok := c.propagation.status()
+	if !ok {
+		return
+	}
+
+	if err := c.applyHubControlPlaneStatus(ctx, propagationReportManager, hubcontrolplanev1alpha1.HubControlPlaneStatus{
+		Propagation:        reports,
+		PropagationTime:    &now,
+		PropagationOmitted: omitted,
+	}); err != nil {
+		// the hubcontrolplane is created by the status syncer
+		if !errors.IsNotFound(err) {
+			klog.Errorf("Failed to report the propagation to hubcontrolplane %s: %v", c.syncerName, err)
+		}
+		return
+	}
+	c.propagation.markReported(changes)
+	klog.Infof("Reported the propagation with %d objects not applied to hubcontrolplane %s", len(reports),
+		c.syncerName)
+}
@@ -0,0 +1,79 @@
+package syncer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	hubcontrolplanev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/hubcontrolplane/v1alpha1"
+)
+
+func TestPropagationStatusSize(t *testing.T) {
+	policyGVR := schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}
+	policies := []*unstructured.Unstructured{}
+	for i := 0; i < 20000; i++ {
+		policy := &unstructured.Unstructured{}
+		policy.SetNamespace(fmt.Sprintf("namespace%05d", i))
+		policy.SetName(fmt.Sprintf("policy%05d", i))
+		policy.SetGeneration(1)
+		policies = append(policies, policy)
+	}
+
+	// the applied objects are not reported
+	propagation := newPropagationReports()
+	for _, policy := range policies {
+		propagation.observe(policyGVR, policy)
+		propagation.record(policyGVR, policy, nil)
+	}
+	reports, omitted, _, ok := propagation.status()
+	if !ok || len(reports) != 0 || omitted != 0 {
+		t.Fatalf("expected no report of the applied objects, got %d reports and %d omitted", len(reports), omitted)
+	}
+
+	// the objects updated on the global hub are Pending until they are applied again
+	policies[0].SetGeneration(2)
+	propagation.observe(policyGVR, policies[0])
+	reports, _, _, _ = propagation.status()
+	if len(reports) != 1 || reports[0].State != hubcontrolplanev1alpha1.PropagationPending || reports[0].Generation != 1 {
+		t.Fatalf("expected the updated object Pending at generation 1, got %v", reports)
+	}
+	propagation.record(policyGVR, policies[0], nil)
+	if reports, _, _, _ = propagation.status(); len(reports) != 0 {
+		t.Fatalf("expected no report once the update is applied, got %v", reports)
+	}
+
+	// the failures with long messages of every object stay under the limit of etcd
+	for _, policy := range policies {
+		propagation.record(policyGVR, policy, errors.New(rand.String(10000)))
+	}
+	reports, omitted, _, _ = propagation.status()
+	if len(reports) != maxPropagationReports || int(omitted) != len(policies)-maxPropagationReports {
+		t.Fatalf("expected %d reports and %d omitted, got %d and %d", maxPropagationReports,
+			len(policies)-maxPropagationReports, len(reports), omitted)
+	}
+	if reports[0].State != hubcontrolplanev1alpha1.PropagationFailed || len(reports[0].Message) != maxPropagationMessageLength {
+		t.Errorf("expected the failure with a truncated message, got %s with %d bytes", reports[0].State,
+			len(reports[0].Message))
+	}
+	now := metav1.Now()
+	encoded, err := json.Marshal(&hubcontrolplanev1alpha1.HubControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Name: "hub1"},
+		Status: hubcontrolplanev1alpha1.HubControlPlaneStatus{
+			Propagation:        reports,
+			PropagationTime:    &now,
+			PropagationOmitted: omitted,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encoded) > etcdRequestLimit {
+		t.Errorf("expected the hubcontrolplane to be under the limit of etcd, got %d bytes", len(encoded))
+	}
+}
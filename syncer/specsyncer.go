@@ -12,6 +12,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
@@ -54,10 +55,25 @@ func NewSpecSyncer(syncerName string, from, to *rest.Config) (*Controller, error
 	fromClient := dynamic.NewForConfigOrDie(from)
 	toClient := dynamic.NewForConfigOrDie(to)
 
-	return New(syncerName, fromClient, toClient, from, SyncDown)
+	c, err := New(syncerName, fromClient, toClient, from, SyncDown)
+	if err != nil {
+		return nil, err
+	}
+	kubeClient, err := kubernetes.NewForConfig(from)
+	if err != nil {
+		return nil, err
+	}
+	c.eventBroadcaster, c.recorder = newEventBroadcaster(kubeClient, syncerName)
+	return c, nil
 }
 
 func (c *Controller) deleteFromDownstream(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) error {
+	if c.propagation != nil {
+		c.propagation.remove(gvr, namespace, name)
+	}
+	if gvr.Resource == "globalmanagedclustersets" {
+		return c.deleteClusterSetFromDownstream(ctx, name)
+	}
 	return c.toClient.Resource(gvr).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
 }
 
@@ -92,6 +108,20 @@ func (c *Controller) ensureDownstreamNamespaceExists(ctx context.Context, downst
 }
 
 func (c *Controller) applyToDownstream(ctx context.Context, gvr schema.GroupVersionResource, downstreamNamespace string, upstreamObj *unstructured.Unstructured) error {
+	err := c.applyObjectToDownstream(ctx, gvr, downstreamNamespace, upstreamObj)
+	c.recordPropagation(upstreamObj, err)
+	if c.propagation != nil {
+		c.propagation.record(gvr, upstreamObj, err)
+	}
+	return err
+}
+
+func (c *Controller) applyObjectToDownstream(ctx context.Context, gvr schema.GroupVersionResource, downstreamNamespace string, upstreamObj *unstructured.Unstructured) error {
+	// the global managedclustersets are synced to managedclustersets with the clusters of the regional hub
+	if gvr.Resource == "globalmanagedclustersets" {
+		return c.applyClusterSetToDownstream(ctx, upstreamObj)
+	}
+
 	if err := c.ensureDownstreamNamespaceExists(ctx, downstreamNamespace, upstreamObj); err != nil {
 		return err
 	}
@@ -109,6 +139,13 @@ func (c *Controller) applyToDownstream(ctx context.Context, gvr schema.GroupVers
 	downstreamObj.SetOwnerReferences(nil)
 	// Strip finalizers to avoid the deletion of the downstream resource from being blocked.
 	downstreamObj.SetFinalizers(nil)
+	// Mark the downstream resource to delete it once orphaned, see collectOrphans
+	labels := downstreamObj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[SyncerLabel] = c.syncerName
+	downstreamObj.SetLabels(labels)
 
 	// Marshalling the unstructured object is good enough as SSA patch
 	data, err := json.Marshal(downstreamObj)
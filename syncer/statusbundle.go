@@ -0,0 +1,302 @@
+package syncer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+
+	statusbundlev1alpha1 "github.com/clyang82/multicluster-global-hub-lite/apis/statusbundle/v1alpha1"
+)
+
+// maxStatusBundleSize is the maximum size of the compressed objects of a StatusBundle, they are base64 encoded in the
+// StatusBundle which must stay under the 1.5MiB request limit of etcd
+const maxStatusBundleSize = 768 * 1024
+
+var statusBundleGVR = statusbundlev1alpha1.GroupVersion.WithResource("statusbundles")
+
+// statusBundler collects the objects reported by the status syncer, the objects of a resource are written to the
+// global hub in a single StatusBundle once per window, in place of the writes per object.
+type statusBundler struct {
+	window time.Duration
+
+	lock    sync.Mutex
+	objects map[schema.GroupVersionResource]map[string]BundleObject
+	changed map[schema.GroupVersionResource]bool
+
+	// shards are the numbers of StatusBundles last written per resource, only used by the flushes
+	shards map[schema.GroupVersionResource]int
+}
+
+func newStatusBundler(window time.Duration) *statusBundler {
+	return &statusBundler{
+		window:  window,
+		objects: map[schema.GroupVersionResource]map[string]BundleObject{},
+		changed: map[schema.GroupVersionResource]bool{},
+		shards:  map[schema.GroupVersionResource]int{},
+	}
+}
+
+// add records the upstream object to be written with the next bundle of its resource.
+func (b *statusBundler) add(gvr schema.GroupVersionResource, manager string, upstreamObj *unstructured.Unstructured) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if _, ok := b.objects[gvr]; !ok {
+		b.objects[gvr] = map[string]BundleObject{}
+	}
+	b.objects[gvr][bundleObjectKey(upstreamObj.GetNamespace(), upstreamObj.GetName())] = BundleObject{
+		Namespace: upstreamObj.GetNamespace(),
+		Name:      upstreamObj.GetName(),
+		Manager:   manager,
+		Object:    upstreamObj,
+	}
+	b.changed[gvr] = true
+}
+
+// remove drops the upstream object from the next bundles of its resource.
+func (b *statusBundler) remove(gvr schema.GroupVersionResource, namespace, name string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	key := bundleObjectKey(namespace, name)
+	if _, ok := b.objects[gvr][key]; ok {
+		delete(b.objects[gvr], key)
+		b.changed[gvr] = true
+	}
+}
+
+// changedBundles returns the full bundles of the resources changed since the last call.
+func (b *statusBundler) changedBundles(hub string) []*Bundle {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	bundles := []*Bundle{}
+	for gvr := range b.changed {
+		bundle := newBundle(hub, gvr)
+		bundle.Full = true
+		for _, bundleObj := range b.objects[gvr] {
+			bundle.Objects = append(bundle.Objects, bundleObj)
+		}
+		// keep the encoded bundle stable, so an unchanged bundle is not written again
+		sort.Slice(bundle.Objects, func(i, j int) bool {
+			return bundleObjectKey(bundle.Objects[i].Namespace, bundle.Objects[i].Name) <
+				bundleObjectKey(bundle.Objects[j].Namespace, bundle.Objects[j].Name)
+		})
+		bundles = append(bundles, bundle)
+	}
+	b.changed = map[schema.GroupVersionResource]bool{}
+	return bundles
+}
+
+func (b *statusBundler) setChanged(gvr schema.GroupVersionResource) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.changed[gvr] = true
+}
+
+// enableStatusBundles makes the status syncer write the objects in a StatusBundle per resource every window.
+func (c *Controller) enableStatusBundles(window time.Duration) {
+	c.statusBundler = newStatusBundler(window)
+	c.deleteFn = c.removeFromStatusBundle
+}
+
+// removeFromStatusBundle drops the deleted object of the regional hub from the bundles, its copy on the global hub is
+// kept as with the writes per object.
+func (c *Controller) removeFromStatusBundle(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) error {
+	// the customresourcedefinitions and clustermanagementaddons are reported as the hubcontrolplane
+	if gvr.Resource == "customresourcedefinitions" || gvr.Resource == "clustermanagementaddons" {
+		return nil
+	}
+	if len(namespace) > 0 {
+		namespace = c.syncerName
+	}
+	c.statusBundler.remove(gvr, namespace, name)
+	return nil
+}
+
+// flushStatusBundles writes the bundles of the resources changed during the window to the global hub, a bundle
+// failing to be written is written again with the next window.
+func (c *Controller) flushStatusBundles(ctx context.Context) {
+	for _, bundle := range c.statusBundler.changedBundles(c.syncerName) {
+		if err := c.writeStatusBundles(ctx, bundle); err != nil {
+			klog.Errorf("Failed to write the status bundle of %s: %v", bundle.Resource, err)
+			c.statusBundler.setChanged(bundle.GVR())
+		}
+	}
+}
+
+// writeStatusBundles writes the bundle in as many StatusBundles as needed to keep them under maxStatusBundleSize, then
+// deletes the StatusBundles of the resource left from a larger bundle.
+func (c *Controller) writeStatusBundles(ctx context.Context, bundle *Bundle) error {
+	shards, err := shardBundle(bundle, maxStatusBundleSize)
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for i, shard := range shards {
+		statusBundle := newStatusBundle(c.syncerName, StatusBundleName(bundle.GVR(), i), shard.bundle, shard.data)
+		if err := c.applyStatusBundleToUpstream(ctx, statusBundle); err != nil {
+			return err
+		}
+		names[statusBundle.Name] = true
+	}
+
+	// the StatusBundles left by the syncer before it restarted are unknown
+	previous, ok := c.statusBundler.shards[bundle.GVR()]
+	if !ok || previous > len(shards) {
+		if err := c.deleteStaleStatusBundles(ctx, bundle.GVR(), names); err != nil {
+			return err
+		}
+	}
+	c.statusBundler.shards[bundle.GVR()] = len(shards)
+	return nil
+}
+
+// statusBundleShard is a part of the objects of a bundle with their encoding
+type statusBundleShard struct {
+	bundle *Bundle
+	data   []byte
+}
+
+// shardBundle splits the objects of the bundle in halves until every shard is encoded in maxSize bytes, a single
+// object over maxSize is left alone in its shard.
+func shardBundle(bundle *Bundle, maxSize int) ([]statusBundleShard, error) {
+	data, err := EncodeBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= maxSize || len(bundle.Objects) <= 1 {
+		return []statusBundleShard{{bundle: bundle, data: data}}, nil
+	}
+	half := len(bundle.Objects) / 2
+	first, second := *bundle, *bundle
+	first.Objects, second.Objects = bundle.Objects[:half], bundle.Objects[half:]
+	shards, err := shardBundle(&first, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	secondShards, err := shardBundle(&second, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return append(shards, secondShards...), nil
+}
+
+func newStatusBundle(hub, name string, bundle *Bundle, data []byte) *statusbundlev1alpha1.StatusBundle {
+	return &statusbundlev1alpha1.StatusBundle{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: statusbundlev1alpha1.GroupVersion.String(),
+			Kind:       "StatusBundle",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: hub,
+		},
+		Spec: statusbundlev1alpha1.StatusBundleSpec{
+			Hub: hub,
+			Source: statusbundlev1alpha1.BundledResource{
+				Group:    bundle.Group,
+				Version:  bundle.Version,
+				Resource: bundle.Resource,
+			},
+			NumberOfObjects: int32(len(bundle.Objects)),
+			Objects:         data,
+		},
+	}
+}
+
+func (c *Controller) applyStatusBundleToUpstream(ctx context.Context, statusBundle *statusbundlev1alpha1.StatusBundle) error {
+	// Marshalling the object is good enough as SSA patch
+	patch, err := json.Marshal(statusBundle)
+	if err != nil {
+		return err
+	}
+	if _, err := c.toClient.Resource(statusBundleGVR).Namespace(c.syncerName).Patch(ctx, statusBundle.Name, types.ApplyPatchType, patch,
+		metav1.PatchOptions{FieldManager: syncerApplyManager, Force: pointer.Bool(true)}); err != nil {
+		return err
+	}
+	klog.Infof("Wrote the status bundle %s of %d %s (%d bytes) to the global hub", statusBundle.Name,
+		statusBundle.Spec.NumberOfObjects, statusBundle.Spec.Source.Resource, len(statusBundle.Spec.Objects))
+	return nil
+}
+
+// deleteStaleStatusBundles deletes the StatusBundles of the resource in the namespace of the hub but the named ones.
+func (c *Controller) deleteStaleStatusBundles(ctx context.Context, gvr schema.GroupVersionResource, names map[string]bool) error {
+	list, err := c.toClient.Resource(statusBundleGVR).Namespace(c.syncerName).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, item := range list.Items {
+		group, _, _ := unstructured.NestedString(item.Object, "spec", "source", "group")
+		resource, _, _ := unstructured.NestedString(item.Object, "spec", "source", "resource")
+		if group != gvr.Group || resource != gvr.Resource || names[item.GetName()] {
+			continue
+		}
+		if err := c.toClient.Resource(statusBundleGVR).Namespace(c.syncerName).Delete(ctx, item.GetName(), metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		klog.Infof("Deleted the status bundle %s of %s", item.GetName(), gvr.Resource)
+	}
+	return nil
+}
+
+// StatusBundleName returns the name of a StatusBundle of the resource in the namespace of a regional hub, the objects
+// of the resource over the size of a StatusBundle are written in several of them numbered from 0.
+func StatusBundleName(gvr schema.GroupVersionResource, shard int) string {
+	if shard == 0 {
+		return gvr.GroupResource().String()
+	}
+	return fmt.Sprintf("%s-%d", gvr.GroupResource().String(), shard)
+}
+
+// EncodeBundle returns the gzip compressed JSON of the bundle.
+func EncodeBundle(bundle *Bundle) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	if err := json.NewEncoder(writer).Encode(bundle); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeBundle returns the bundle of the gzip compressed JSON.
+func DecodeBundle(data []byte) (*Bundle, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &Bundle{}
+	if err := json.Unmarshal(decoded, bundle); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+func bundleObjectKey(namespace, name string) string {
+	if len(namespace) > 0 {
+		return namespace + "/" + name
+	}
+	return name
+}
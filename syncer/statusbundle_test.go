@@ -0,0 +1,116 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/rand"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+)
+
+// etcdRequestLimit is the maximum size of a request of etcd by default
+const etcdRequestLimit = 1536 * 1024
+
+func TestShardBundle(t *testing.T) {
+	bundle := &Bundle{Hub: "hub1", Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies", Full: true}
+	for i := 0; i < 4000; i++ {
+		policy := &unstructured.Unstructured{}
+		policy.SetAPIVersion("policy.open-cluster-management.io/v1")
+		policy.SetKind("Policy")
+		policy.SetNamespace("hub1")
+		policy.SetName(fmt.Sprintf("policy%04d", i))
+		// the random messages of the status barely compress
+		_ = unstructured.SetNestedField(policy.Object, rand.String(1000), "status", "message")
+		bundle.Objects = append(bundle.Objects, BundleObject{Namespace: "hub1", Name: policy.GetName(), Object: policy})
+	}
+
+	// the bundle is over the limit of etcd in a single StatusBundle
+	data, err := EncodeBundle(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := statusBundleSize(t, 0, bundle, data); size <= etcdRequestLimit {
+		t.Fatalf("expected the StatusBundle to be over the limit of etcd, got %d bytes", size)
+	}
+
+	shards, err := shardBundle(bundle, maxStatusBundleSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shards) < 2 {
+		t.Fatalf("expected several shards, got %d", len(shards))
+	}
+	names := []string{}
+	for i, shard := range shards {
+		if size := statusBundleSize(t, i, shard.bundle, shard.data); size > etcdRequestLimit {
+			t.Errorf("expected the shard %d to be under the limit of etcd, got %d bytes", i, size)
+		}
+		decoded, err := DecodeBundle(shard.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.GVR() != bundle.GVR() || decoded.Hub != "hub1" {
+			t.Errorf("unexpected shard %d of %s of %s", i, decoded.Resource, decoded.Hub)
+		}
+		for _, bundleObj := range decoded.Objects {
+			names = append(names, bundleObj.Name)
+		}
+	}
+	if len(names) != len(bundle.Objects) || !sort.StringsAreSorted(names) {
+		t.Errorf("expected the %d objects in order in the shards, got %d", len(bundle.Objects), len(names))
+	}
+}
+
+func statusBundleSize(t *testing.T, shard int, bundle *Bundle, data []byte) int {
+	encoded, err := json.Marshal(newStatusBundle("hub1", StatusBundleName(bundle.GVR(), shard), bundle, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(encoded)
+}
+
+func TestDeleteStaleStatusBundles(t *testing.T) {
+	policyGVR := schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}
+	subscriptionGVR := schema.GroupVersionResource{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "subscriptions"}
+
+	objects := []runtime.Object{}
+	for _, statusBundle := range []struct {
+		gvr   schema.GroupVersionResource
+		shard int
+	}{{policyGVR, 0}, {policyGVR, 1}, {policyGVR, 2}, {subscriptionGVR, 0}} {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(newStatusBundle("hub1",
+			StatusBundleName(statusBundle.gvr, statusBundle.shard), newBundle("hub1", statusBundle.gvr), nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, &unstructured.Unstructured{Object: content})
+	}
+	client := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{statusBundleGVR: "StatusBundleList"}, objects...)
+
+	c := &Controller{syncerName: "hub1", toClient: client}
+	if err := c.deleteStaleStatusBundles(context.TODO(), policyGVR, map[string]bool{StatusBundleName(policyGVR, 0): true}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := client.Resource(statusBundleGVR).Namespace("hub1").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+	sort.Strings(names)
+	expected := []string{StatusBundleName(subscriptionGVR, 0), StatusBundleName(policyGVR, 0)}
+	sort.Strings(expected)
+	if fmt.Sprint(names) != fmt.Sprint(expected) {
+		t.Errorf("expected the StatusBundles %v, got %v", expected, names)
+	}
+}
@@ -0,0 +1,285 @@
+package syncer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	bolt "go.etcd.io/bbolt"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+const (
+	statusQueueReplayPeriod = 5 * time.Second
+	// statusQueueFlushPeriod is the period the status updates queued in memory are written to the file in a single
+	// transaction, the updates of a syncer crashing in between are sent again as the informers list all the objects
+	statusQueueFlushPeriod = 100 * time.Millisecond
+)
+
+var (
+	// pendingBucket maps the sequence of the pending status updates to their object, in the order they are queued
+	pendingBucket = []byte("pending")
+	// sequenceBucket maps the objects to the sequence of their pending status update
+	sequenceBucket = []byte("sequences")
+)
+
+// statusQueue is a durable queue of the status updates to the global hub, the updates of an object are coalesced. The
+// updates are replayed in order when the syncer is started and when the global hub is reachable again. The changes
+// of the queue are kept in memory and written to the file together by flush, in place of a transaction per change.
+type statusQueue struct {
+	db *bolt.DB
+
+	lock    sync.Mutex
+	offline bool
+	// changes are the changes of the queue not written to the file yet, in order
+	changes []queueChange
+	// sequences are the sequences of the objects changed since the last flush, nil once the update is done
+	sequences map[string][]byte
+	// lastSequence is the sequence of the last queued update
+	lastSequence uint64
+}
+
+// queueChange queues the status update of the object at the sequence when value is set, or removes it
+type queueChange struct {
+	key      []byte
+	sequence []byte
+	value    []byte
+}
+
+type queuedObject struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func openStatusQueue(path string) (*statusQueue, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the status queue %s: %v", path, err)
+	}
+	q := &statusQueue{db: db, sequences: map[string][]byte{}}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		pending, err := tx.CreateBucketIfNotExists(pendingBucket)
+		if err != nil {
+			return err
+		}
+		q.lastSequence = pending.Sequence()
+		_, err = tx.CreateBucketIfNotExists(sequenceBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *statusQueue) close() error {
+	if err := q.flush(); err != nil {
+		klog.Errorf("Failed to write the status queue: %v", err)
+	}
+	return q.db.Close()
+}
+
+// add queues the status update of the object, a pending update of the same object is moved to the end of the queue.
+func (q *statusQueue) add(h holder) error {
+	value, err := json.Marshal(queuedObject{
+		Group:     h.gvr.Group,
+		Version:   h.gvr.Version,
+		Resource:  h.gvr.Resource,
+		Namespace: h.namespace,
+		Name:      h.name,
+	})
+	if err != nil {
+		return err
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	key := holderKey(h)
+	q.lastSequence++
+	seq := sequenceKey(q.lastSequence)
+	q.changes = append(q.changes, queueChange{key: key, sequence: seq, value: value})
+	q.sequences[string(key)] = seq
+	return nil
+}
+
+// sequence returns the sequence of the pending status update of the object, or nil when there is none.
+func (q *statusQueue) sequence(h holder) ([]byte, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.currentSequence(holderKey(h))
+}
+
+// currentSequence returns the sequence of the object changed since the last flush, or written to the file.
+func (q *statusQueue) currentSequence(key []byte) ([]byte, error) {
+	if seq, ok := q.sequences[string(key)]; ok {
+		return seq, nil
+	}
+	var seq []byte
+	err := q.db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket(sequenceBucket).Get(key); value != nil {
+			seq = append([]byte{}, value...)
+		}
+		return nil
+	})
+	return seq, err
+}
+
+// done removes the status update of the object once applied, unless the object is queued again since seq was read.
+func (q *statusQueue) done(h holder, seq []byte) error {
+	if seq == nil {
+		return nil
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	key := holderKey(h)
+	current, err := q.currentSequence(key)
+	if err != nil || string(current) != string(seq) {
+		return err
+	}
+	q.changes = append(q.changes, queueChange{key: key, sequence: seq})
+	q.sequences[string(key)] = nil
+	return nil
+}
+
+// flush writes the changes of the queue to the file in a single transaction.
+func (q *statusQueue) flush() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.changes) == 0 {
+		return nil
+	}
+	if err := q.db.Update(func(tx *bolt.Tx) error {
+		pending, sequences := tx.Bucket(pendingBucket), tx.Bucket(sequenceBucket)
+		for _, change := range q.changes {
+			previous := sequences.Get(change.key)
+			if change.value == nil {
+				if string(previous) != string(change.sequence) {
+					continue
+				}
+				if err := pending.Delete(change.sequence); err != nil {
+					return err
+				}
+				if err := sequences.Delete(change.key); err != nil {
+					return err
+				}
+				continue
+			}
+			if previous != nil {
+				if err := pending.Delete(previous); err != nil {
+					return err
+				}
+			}
+			if err := pending.Put(change.sequence, change.value); err != nil {
+				return err
+			}
+			if err := sequences.Put(change.key, change.sequence); err != nil {
+				return err
+			}
+		}
+		return pending.SetSequence(q.lastSequence)
+	}); err != nil {
+		return err
+	}
+	q.changes = nil
+	q.sequences = map[string][]byte{}
+	return nil
+}
+
+// list returns the pending status updates in the order they are queued.
+func (q *statusQueue) list() ([]holder, error) {
+	if err := q.flush(); err != nil {
+		return nil, err
+	}
+	holders := []holder{}
+	err := q.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingBucket).ForEach(func(_, value []byte) error {
+			obj := queuedObject{}
+			if err := json.Unmarshal(value, &obj); err != nil {
+				return err
+			}
+			holders = append(holders, holder{
+				gvr:       schema.GroupVersionResource{Group: obj.Group, Version: obj.Version, Resource: obj.Resource},
+				namespace: obj.Namespace,
+				name:      obj.Name,
+			})
+			return nil
+		})
+	})
+	return holders, err
+}
+
+func (q *statusQueue) isOffline() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.offline
+}
+
+func (q *statusQueue) setOffline(offline bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.offline = offline
+}
+
+// replayStatusQueue adds the pending status updates to the work queue in order, once the global hub is reachable.
+func (c *Controller) replayStatusQueue(ctx context.Context) {
+	if !c.statusQueue.isOffline() {
+		return
+	}
+	if c.transport != nil {
+		if !c.transport.Connected() {
+			return
+		}
+	} else if _, err := c.toClient.Resource(hubControlPlaneGVR).Get(ctx, c.syncerName, metav1.GetOptions{}); isUnreachable(err) {
+		return
+	}
+
+	c.statusQueue.setOffline(false)
+	holders, err := c.statusQueue.list()
+	if err != nil {
+		klog.Errorf("Failed to list the status queue: %v", err)
+		return
+	}
+	klog.Infof("The global hub is reachable, replaying %d status updates", len(holders))
+	for _, h := range holders {
+		c.queue.Add(h)
+	}
+}
+
+// isUnreachable returns true when the error is caused by the global hub being unreachable.
+func isUnreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if k8serrors.IsServiceUnavailable(err) || k8serrors.IsServerTimeout(err) || k8serrors.IsTimeout(err) {
+		return true
+	}
+	if errors.Is(err, nats.ErrConnectionClosed) || errors.Is(err, nats.ErrReconnectBufExceeded) || errors.Is(err, errStatusBufferFull) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func holderKey(h holder) []byte {
+	return []byte(fmt.Sprintf("%s/%s/%s", h.gvr.String(), h.namespace, h.name))
+}
+
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
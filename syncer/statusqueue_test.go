@@ -0,0 +1,76 @@
+package syncer
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestStatusQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status-queue.db")
+	q, err := openStatusQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policyGVR := schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}
+	policy1 := holder{gvr: policyGVR, namespace: "default", name: "policy1"}
+	policy2 := holder{gvr: policyGVR, namespace: "default", name: "policy2"}
+	policy3 := holder{gvr: policyGVR, namespace: "default", name: "policy3"}
+
+	for _, h := range []holder{policy1, policy2, policy3} {
+		if err := q.add(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.flush(); err != nil {
+		t.Fatal(err)
+	}
+	// the update of policy1 is applied while it is queued again, it stays queued
+	seq1, err := q.sequence(policy1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.add(policy1); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.done(policy1, seq1); err != nil {
+		t.Fatal(err)
+	}
+	// the update of policy2 is applied before the next flush
+	seq2, err := q.sequence(policy2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.done(policy2, seq2); err != nil {
+		t.Fatal(err)
+	}
+	if seq, err := q.sequence(policy2); err != nil || seq != nil {
+		t.Errorf("expected no pending update of policy2, got %v (%v)", seq, err)
+	}
+
+	expected := []holder{policy3, policy1}
+	if holders, err := q.list(); err != nil || !reflect.DeepEqual(holders, expected) {
+		t.Errorf("expected %v, got %v (%v)", expected, holders, err)
+	}
+
+	// the changes are written when the queue is closed, and the sequences continue after it is opened again
+	if err := q.add(policy2); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.close(); err != nil {
+		t.Fatal(err)
+	}
+	if q, err = openStatusQueue(path); err != nil {
+		t.Fatal(err)
+	}
+	defer q.close()
+	if err := q.add(policy3); err != nil {
+		t.Fatal(err)
+	}
+	expected = []holder{policy1, policy2, policy3}
+	if holders, err := q.list(); err != nil || !reflect.DeepEqual(holders, expected) {
+		t.Errorf("expected %v, got %v (%v)", expected, holders, err)
+	}
+}
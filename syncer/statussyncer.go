@@ -20,7 +20,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -41,8 +40,12 @@ var (
 	hubControlPlaneGVR        = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1alpha1", Resource: "hubcontrolplanes"}
 	managedClusterGVR         = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}
 	clusterManagementAddonGVR = schema.GroupVersionResource{Group: "addon.open-cluster-management.io", Version: "v1alpha1", Resource: "clustermanagementaddons"}
+	placementGVR              = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placements"}
 )
 
+// placementLabel is set by the placement controller on the decisions of a placement
+const placementLabel = "cluster.open-cluster-management.io/placement"
+
 func deepEqualStatus(oldObj, newObj interface{}) bool {
 	oldUnstrob, isOldObjUnstructured := oldObj.(*unstructured.Unstructured)
 	newUnstrob, isNewObjUnstructured := newObj.(*unstructured.Unstructured)
@@ -82,6 +85,32 @@ func (c *Controller) updateStatusInUpstream(ctx context.Context, gvr schema.Grou
 			// print error and continue
 			klog.Errorf("Failed to apply hubcontrolplane for managedcluster %s change: %v", downstreamObj.GetName(), err)
 		}
+
+		// label the managedcluster with the regional hub, the global managedclustersets select the clusters per hub
+		downstreamObj = downstreamObj.DeepCopy()
+		labels := downstreamObj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[RegionalHubLabel] = c.syncerName
+		downstreamObj.SetLabels(labels)
+	}
+
+	// for placementdecision change, only the decisions of the placements from global hub are synced to upstream
+	if gvr.Resource == "placementdecisions" {
+		decision, err := c.globalPlacementDecision(ctx, downstreamObj)
+		if err != nil || decision == nil {
+			return err
+		}
+		downstreamObj = decision
+	}
+
+	if c.transport != nil {
+		return c.publishStatus(gvr, c.syncerName, syncerApplyManager, downstreamObj)
+	}
+	if c.statusBundler != nil {
+		c.statusBundler.add(gvr, syncerApplyManager, toUpstreamObject(c.syncerName, downstreamObj))
+		return nil
 	}
 
 	upstreamObj := downstreamObj.DeepCopy()
@@ -91,8 +120,7 @@ func (c *Controller) updateStatusInUpstream(ctx context.Context, gvr schema.Grou
 	existing, err := c.toClient.Resource(gvr).Namespace(c.syncerName).Get(ctx, upstreamObj.GetName(), metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			c.applyToUpstream(ctx, gvr, c.syncerName, downstreamObj)
-			return nil
+			return c.applyToUpstream(ctx, gvr, c.syncerName, downstreamObj)
 		}
 		klog.Errorf("Getting upstream resource %s/%s: %v", c.syncerName, upstreamObj.GetName(), err)
 		return err
@@ -109,8 +137,43 @@ func (c *Controller) updateStatusInUpstream(ctx context.Context, gvr schema.Grou
 	return nil
 }
 
+// globalPlacementDecision returns a copy of the decision labeled with the original namespace of its placement, or nil
+// when the placement is not from global hub.
+func (c *Controller) globalPlacementDecision(ctx context.Context, downstreamObj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	placementName, ok := downstreamObj.GetLabels()[placementLabel]
+	if !ok {
+		return nil, nil
+	}
+	placement, err := c.fromClient.Resource(placementGVR).Namespace(downstreamObj.GetNamespace()).Get(ctx, placementName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		klog.Errorf("Getting placement %s/%s of placementdecision %s: %v", downstreamObj.GetNamespace(), placementName, downstreamObj.GetName(), err)
+		return nil, err
+	}
+	originalNamespace, ok := placement.GetLabels()[GlobalHubPolicyNamespaceLabel]
+	if !ok {
+		return nil, nil
+	}
+
+	decision := downstreamObj.DeepCopy()
+	labels := decision.GetLabels()
+	labels[GlobalHubPolicyNamespaceLabel] = originalNamespace
+	decision.SetLabels(labels)
+	return decision, nil
+}
+
 // applyToUpstream is used to apply managedclusters to upstream
 func (c *Controller) applyToUpstream(ctx context.Context, gvr schema.GroupVersionResource, upstreamNamespace string, downstreamObj *unstructured.Unstructured) error {
+	if c.transport != nil {
+		return c.publishStatus(gvr, upstreamNamespace, syncerApplyManager, downstreamObj)
+	}
+	if c.statusBundler != nil {
+		c.statusBundler.add(gvr, syncerApplyManager, toUpstreamObject(upstreamNamespace, downstreamObj))
+		return nil
+	}
+
 	upstreamObj := downstreamObj.DeepCopy()
 	upstreamObj.SetUID("")
 	upstreamObj.SetResourceVersion("")
@@ -164,12 +227,24 @@ func (c *Controller) applyToUpstream(ctx context.Context, gvr schema.GroupVersio
 	return nil
 }
 
-func (c *Controller) applyHubControlPlaneInUpstream(ctx context.Context, gvr schema.GroupVersionResource, upstreamNamespace string, downstreamObj *unstructured.Unstructured) error {
-	syncerNamespace, ok := os.LookupEnv("POD_NAMESPACE")
-	if !ok || syncerNamespace == "" {
-		return fmt.Errorf("empty environment variable: POD_NAMESPACE")
+// toUpstreamObject returns the copy of the object of the regional hub written to the upstream namespace, without the
+// fields set by the regional hub apiserver.
+func toUpstreamObject(upstreamNamespace string, downstreamObj *unstructured.Unstructured) *unstructured.Unstructured {
+	upstreamObj := downstreamObj.DeepCopy()
+	upstreamObj.SetUID("")
+	upstreamObj.SetResourceVersion("")
+	upstreamObj.SetManagedFields(nil)
+	upstreamObj.SetDeletionTimestamp(nil)
+	upstreamObj.SetDeletionGracePeriodSeconds(nil)
+	upstreamObj.SetOwnerReferences(nil)
+	upstreamObj.SetFinalizers(nil)
+	if len(upstreamObj.GetNamespace()) > 0 {
+		upstreamObj.SetNamespace(upstreamNamespace)
 	}
+	return upstreamObj
+}
 
+func (c *Controller) applyHubControlPlaneInUpstream(ctx context.Context, gvr schema.GroupVersionResource, upstreamNamespace string, downstreamObj *unstructured.Unstructured) error {
 	managedClustersStatus := hubcontrolplanev1alpha1.ManagedClustersStatus{}
 	for _, managedClusterItem := range c.fromInformers.ForResource(managedClusterGVR).Informer().GetIndexer().List() {
 		managedClusterUnstrobj, isUnstructured := managedClusterItem.(*unstructured.Unstructured)
@@ -222,7 +297,7 @@ func (c *Controller) applyHubControlPlaneInUpstream(ctx context.Context, gvr sch
 			Kind:       "HubControlPlane",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name: syncerNamespace,
+			Name: c.syncerName,
 		},
 		Spec: hubcontrolplanev1alpha1.HubControlPlaneSpec{
 			Endpoint: c.fromConfig.Host,
@@ -241,7 +316,5 @@ func (c *Controller) applyHubControlPlaneInUpstream(ctx context.Context, gvr sch
 
 	hubControlPlaneUnstrObj.SetUnstructuredContent(hubControlPlaneUnstrContent)
 	hubControlPlaneUnstrObj.SetGroupVersionKind(hubControlPlane.GetObjectKind().GroupVersionKind())
-	c.applyToUpstream(ctx, hubControlPlaneGVR, "", &hubControlPlaneUnstrObj)
-
-	return nil
+	return c.applyToUpstream(ctx, hubControlPlaneGVR, "", &hubControlPlaneUnstrObj)
 }
@@ -15,8 +15,11 @@ import (
 	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+
+	"github.com/clyang82/multicluster-global-hub-lite/apis"
 )
 
 // SyncDirection indicates which direction data is flowing for this particular syncer
@@ -25,6 +28,9 @@ type SyncDirection string
 const (
 	resyncPeriod       = 10 * time.Hour
 	syncerApplyManager = "syncer"
+	// cacheSyncTimeout is how long the syncer waits for the informers of the regional hub to sync before it starts,
+	// the informers of the resources missing on the regional hub never sync
+	cacheSyncTimeout = time.Minute
 
 	// SyncDown indicates a syncer watches resources on the global hub and applies the spec to the leaf cluster
 	SyncDown SyncDirection = "down"
@@ -33,7 +39,10 @@ const (
 	SyncUp SyncDirection = "up"
 
 	// if the Global Hub resources with this label, it means the resources is ready to be syncDown
-	GlobalHubPolicyNamespaceLabel = "global-hub.open-cluster-management.io/original-namespace"
+	GlobalHubPolicyNamespaceLabel = apis.GlobalHubPolicyNamespaceLabel
+
+	// RegionalHubLabel is set on the managedclusters reported to the Global Hub, the value is the syncer name
+	RegionalHubLabel = apis.RegionalHubLabel
 )
 
 // SyncerConfig defines the syncer configuration that is guaranteed to
@@ -42,9 +51,32 @@ type SyncerConfig struct {
 	UpstreamConfig   *rest.Config
 	DownstreamConfig *rest.Config
 	SyncerName       string
+	// StatusQueueFile is the file of the durable queue of the status updates to the global hub, the status updates
+	// are only queued in memory when it is empty
+	StatusQueueFile string
+	// Broker is the broker carrying the spec and the status when set, the upstream config is not used then
+	Broker *BrokerConfig
+	// GRPCAddress is the sync service of the global hub streaming the spec and the status when set, the upstream
+	// config only provides the credentials then
+	GRPCAddress string
+	// StatusBundleWindow is the period the status syncer writes the objects of a resource to the global hub in a
+	// single StatusBundle, the objects are written one by one when it is 0. The broker and grpc transports ignore it.
+	StatusBundleWindow time.Duration
 }
 
 func StartSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int) error {
+	if cfg.Broker != nil {
+		return startBrokerSyncer(ctx, cfg, numSyncerThreads)
+	}
+	if len(cfg.GRPCAddress) > 0 {
+		transport, err := NewGRPCTransport(cfg.GRPCAddress, cfg.UpstreamConfig, cfg.SyncerName)
+		if err != nil {
+			return err
+		}
+		klog.Infof("Creating spec and status syncers with the sync service %s", cfg.GRPCAddress)
+		return startTransportSyncer(ctx, cfg, transport, numSyncerThreads)
+	}
+
 	klog.Infof("Creating spec syncer")
 	specSyncer, err := NewSpecSyncer(cfg.SyncerName, cfg.UpstreamConfig, cfg.DownstreamConfig)
 	if err != nil {
@@ -56,6 +88,14 @@ func StartSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int) e
 	if err != nil {
 		return err
 	}
+	if len(cfg.StatusQueueFile) > 0 {
+		if statusSyncer.statusQueue, err = openStatusQueue(cfg.StatusQueueFile); err != nil {
+			return err
+		}
+	}
+	if cfg.StatusBundleWindow > 0 {
+		statusSyncer.enableStatusBundles(cfg.StatusBundleWindow)
+	}
 
 	go specSyncer.Start(ctx, numSyncerThreads)
 	go statusSyncer.Start(ctx, numSyncerThreads)
@@ -74,8 +114,20 @@ type Controller struct {
 	syncerName string
 
 	fromInformers dynamicinformer.DynamicSharedInformerFactory
+	fromClient    dynamic.Interface
 	fromConfig    *rest.Config
+	toInformers   dynamicinformer.DynamicSharedInformerFactory
 	toClient      dynamic.Interface
+	drift         *driftCounter
+	propagation   *propagationReports
+	statusQueue   *statusQueue
+	// statusBundler collects the status written in StatusBundles when set
+	statusBundler *statusBundler
+	// transport carries the spec and the status in place of the global hub apiserver when set
+	transport Transport
+	// the spec syncer records the propagation events on the global objects when they are set
+	eventBroadcaster record.EventBroadcaster
+	recorder         record.EventRecorder
 
 	upsertFn  UpsertFunc
 	deleteFn  DeleteFunc
@@ -86,6 +138,18 @@ type Controller struct {
 
 // New returns a new syncer Controller syncing spec from "from" to "to".
 func New(syncerName string, fromClient, toClient dynamic.Interface, fromConfig *rest.Config, direction SyncDirection) (*Controller, error) {
+	fromInformers := dynamicinformer.NewFilteredDynamicSharedInformerFactory(fromClient, resyncPeriod,
+		metav1.NamespaceAll, func(o *metav1.ListOptions) {
+			o.LabelSelector = fmt.Sprintf("!%s", "policy.open-cluster-management.io/root-policy")
+		})
+	return newController(syncerName, fromInformers, fromClient, toClient, fromConfig, direction)
+}
+
+// newController returns a new syncer Controller syncing the objects of the informers to "to". The client of the
+// global hub is nil when the syncer uses the broker transport, so is the client of the regional hub on the global hub
+// side of the broker transport.
+func newController(syncerName string, fromInformers dynamicinformer.DynamicSharedInformerFactory, fromClient, toClient dynamic.Interface,
+	fromConfig *rest.Config, direction SyncDirection) (*Controller, error) {
 	controllerName := string(direction) + "--regional-hub-->global-hub"
 	if direction == SyncDown {
 		controllerName = string(direction) + "--global-hub-->regional-hub"
@@ -96,6 +160,7 @@ func New(syncerName string, fromClient, toClient dynamic.Interface, fromConfig *
 		name:       controllerName,
 		syncerName: syncerName,
 		queue:      queue,
+		fromClient: fromClient,
 		toClient:   toClient,
 		fromConfig: fromConfig,
 		direction:  direction,
@@ -108,24 +173,48 @@ func New(syncerName string, fromClient, toClient dynamic.Interface, fromConfig *
 			"policies.v1.policy.open-cluster-management.io",
 			"placementbindings.v1.policy.open-cluster-management.io",
 			"placementrules.v1.apps.open-cluster-management.io",
+			"placements.v1beta1.cluster.open-cluster-management.io",
+			"subscriptions.v1.apps.open-cluster-management.io",
+			"channels.v1.apps.open-cluster-management.io",
+			"applications.v1beta1.app.k8s.io",
+			"globalmanagedclustersets.v1alpha1.global-hub.open-cluster-management.io",
 		}
 	} else {
 		c.upsertFn = c.updateStatusInUpstream
 		c.gvrs = []string{
 			"policies.v1.policy.open-cluster-management.io",
+			"subscriptions.v1.apps.open-cluster-management.io",
+			"placementdecisions.v1beta1.cluster.open-cluster-management.io",
 			"managedclusters.v1.cluster.open-cluster-management.io",
 			"clustermanagementaddons.v1alpha1.addon.open-cluster-management.io",
 			"customresourcedefinitions.v1.apiextensions.k8s.io",
 		}
 	}
 
-	fromInformers := dynamicinformer.NewFilteredDynamicSharedInformerFactory(fromClient, resyncPeriod,
-		metav1.NamespaceAll, func(o *metav1.ListOptions) {
-			o.LabelSelector = fmt.Sprintf("!%s", "policy.open-cluster-management.io/root-policy")
-		})
-
-	// toInformers := dynamicinformer.NewFilteredDynamicSharedInformerFactory(toClient, resyncPeriod,
-	// 	metav1.NamespaceAll, func(o *metav1.ListOptions) {})
+	if direction == SyncDown && toClient != nil {
+		c.drift = newDriftCounter()
+		c.propagation = newPropagationReports()
+		// watch the downstream copies labeled with their original namespace to revert the changes on the regional hub
+		c.toInformers = dynamicinformer.NewFilteredDynamicSharedInformerFactory(toClient, resyncPeriod,
+			metav1.NamespaceAll, func(o *metav1.ListOptions) {
+				o.LabelSelector = GlobalHubPolicyNamespaceLabel
+			})
+		for _, gvrstr := range c.gvrs {
+			gvr, _ := schema.ParseResourceArg(gvrstr)
+			// the global managedclustersets are not copied to the regional hubs
+			if gvr.Resource == "globalmanagedclustersets" {
+				continue
+			}
+			c.toInformers.ForResource(*gvr).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+				UpdateFunc: func(oldObj, newObj interface{}) {
+					c.downstreamChanged(*gvr, oldObj, newObj, false)
+				},
+				DeleteFunc: func(obj interface{}) {
+					c.downstreamChanged(*gvr, nil, obj, true)
+				},
+			})
+		}
+	}
 
 	for _, gvrstr := range c.gvrs {
 		gvr, _ := schema.ParseResourceArg(gvrstr)
@@ -155,6 +244,9 @@ func New(syncerName string, fromClient, toClient dynamic.Interface, fromConfig *
 				}
 
 				if shouldEnqueue {
+					if c.propagation != nil {
+						c.propagation.observe(*gvr, unstrob)
+					}
 					c.AddToQueue(*gvr, obj)
 				}
 			},
@@ -183,7 +275,12 @@ func New(syncerName string, fromClient, toClient dynamic.Interface, fromConfig *
 
 				if shouldEnqueue {
 					if c.direction == SyncDown {
-						if !deepEqualApartFromStatus(oldObj, newObj) {
+						// the members of the global managedclustersets are in the status
+						if !deepEqualApartFromStatus(oldObj, newObj) ||
+							(gvr.Resource == "globalmanagedclustersets" && !deepEqualStatus(oldObj, newObj)) {
+							if c.propagation != nil {
+								c.propagation.observe(*gvr, unstrob)
+							}
 							c.AddToQueue(*gvr, newObj)
 						}
 					} else {
@@ -257,22 +354,69 @@ func (c *Controller) AddToQueue(gvr schema.GroupVersionResource, obj interface{}
 
 	klog.Infof("Syncer %s: adding %s %s to queue", c.name, gvr, qualifiedName)
 
-	c.queue.Add(
-		holder{
-			gvr:       gvr,
-			namespace: metaObj.GetNamespace(),
-			name:      metaObj.GetName(),
-		},
-	)
+	h := holder{
+		gvr:       gvr,
+		namespace: metaObj.GetNamespace(),
+		name:      metaObj.GetName(),
+	}
+	if c.statusQueue != nil {
+		if err := c.statusQueue.add(h); err != nil {
+			klog.Errorf("%s: error adding %s %s to the status queue: %v", c.name, gvr, qualifiedName, err)
+		} else if c.statusQueue.isOffline() {
+			// the status update is replayed once the global hub is reachable
+			return
+		}
+	}
+	c.queue.Add(h)
 }
 
 // Start starts N worker processes processing work items.
 func (c *Controller) Start(ctx context.Context, numThreads int) {
 	defer runtime.HandleCrash()
 	defer c.queue.ShutDown()
+	if c.eventBroadcaster != nil {
+		defer c.eventBroadcaster.Shutdown()
+	}
+
+	if c.statusQueue != nil {
+		defer c.statusQueue.close()
+		// replay the status updates pending when the syncer was stopped, before the informers queue the objects
+		c.statusQueue.setOffline(true)
+		c.replayStatusQueue(ctx)
+		go wait.UntilWithContext(ctx, c.replayStatusQueue, statusQueueReplayPeriod)
+		go wait.Until(func() {
+			if err := c.statusQueue.flush(); err != nil {
+				klog.Errorf("%s: error writing the status queue: %v", c.name, err)
+			}
+		}, statusQueueFlushPeriod, ctx.Done())
+	}
 
 	c.fromInformers.Start(ctx.Done())
-	c.fromInformers.WaitForCacheSync(ctx.Done())
+	// the informers of the spec syncer using the broker are synced once it receives all the spec
+	if c.transport != nil {
+		if err := c.startTransport(ctx); err != nil {
+			klog.Errorf("%s: failed to start the transport: %v", c.name, err)
+			return
+		}
+	}
+	if c.direction == SyncUp {
+		// e.g. the subscriptions and the placementdecisions are missing without their CRDs on the regional hub
+		c.waitForCacheSync(ctx, c.fromInformers, cacheSyncTimeout)
+	} else {
+		c.fromInformers.WaitForCacheSync(ctx.Done())
+	}
+	if c.toInformers != nil {
+		c.toInformers.Start(ctx.Done())
+		c.waitForCacheSync(ctx, c.toInformers, cacheSyncTimeout)
+		go wait.UntilWithContext(ctx, c.reportDrift, driftReportPeriod)
+		go wait.UntilWithContext(ctx, c.reportHeartbeat, HeartbeatPeriod)
+		go wait.UntilWithContext(ctx, c.reportPropagation, propagationReportPeriod)
+		// the deletes missed while the syncer was down are collected at startup, then periodically
+		go wait.UntilWithContext(ctx, c.collectOrphans, orphanCollectionPeriod)
+	}
+	if c.statusBundler != nil {
+		go wait.UntilWithContext(ctx, c.flushStatusBundles, c.statusBundler.window)
+	}
 
 	klog.InfoS("Starting syncer workers", "controller", c.name)
 	defer klog.InfoS("Stopping syncer workers", "controller", c.name)
@@ -283,6 +427,24 @@ func (c *Controller) Start(ctx context.Context, numThreads int) {
 	<-ctx.Done()
 }
 
+// waitForCacheSync waits for the informers of the regional hub to sync until the timeout, and returns the resources
+// not synced. The resources not served by the regional hub, e.g. when the CRDs of the applications are not installed,
+// don't block the syncer, their informers keep listing in the background and sync once the CRDs are installed.
+func (c *Controller) waitForCacheSync(ctx context.Context, informers dynamicinformer.DynamicSharedInformerFactory,
+	timeout time.Duration) []schema.GroupVersionResource {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	unsynced := []schema.GroupVersionResource{}
+	for gvr, synced := range informers.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			klog.Warningf("%s: the %s are not synced after %s, their CRD may be missing on the regional hub", c.name, gvr.GroupResource(), timeout)
+			unsynced = append(unsynced, gvr)
+		}
+	}
+	return unsynced
+}
+
 // startWorker processes work items until stopCh is closed.
 func (c *Controller) startWorker(ctx context.Context) {
 	for c.processNextWorkItem(ctx) {
@@ -301,13 +463,32 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 	// other workers.
 	defer c.queue.Done(key)
 
+	var seq []byte
+	if c.statusQueue != nil {
+		var err error
+		if seq, err = c.statusQueue.sequence(h); err != nil {
+			klog.Errorf("%s: error reading the status queue: %v", c.name, err)
+		}
+	}
+
 	if err := c.process(ctx, h); err != nil {
 		runtime.HandleError(fmt.Errorf("syncer %q failed to sync %q, err: %w", c.name, key, err))
+		if c.statusQueue != nil && isUnreachable(err) {
+			// keep the status update in the status queue until the global hub is reachable
+			c.statusQueue.setOffline(true)
+			c.queue.Forget(key)
+			return true
+		}
 		c.queue.AddRateLimited(key)
 		return true
 	}
 
 	c.queue.Forget(key)
+	if c.statusQueue != nil {
+		if err := c.statusQueue.done(h, seq); err != nil {
+			klog.Errorf("%s: error removing %q from the status queue: %v", c.name, key, err)
+		}
+	}
 
 	return true
 }
@@ -326,8 +507,9 @@ func (c *Controller) process(ctx context.Context, h holder) error {
 		return err
 	}
 
-	if c.direction == SyncDown && !exists {
+	if !exists {
 		klog.InfoS("Object doesn't exist:", "direction", c.direction, "namespace", h.namespace, "name", h.name)
+		// the status syncer has no deleteFn unless it writes StatusBundles, the status of a deleted object is not updated anymore
 		if c.deleteFn != nil {
 			return c.deleteFn(ctx, h.gvr, h.namespace, h.name)
 		}
@@ -0,0 +1,45 @@
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestWaitForCacheSyncMissingResources(t *testing.T) {
+	policyGVR := schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}
+	subscriptionGVR := schema.GroupVersionResource{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "subscriptions"}
+	client := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{policyGVR: "PolicyList", subscriptionGVR: "SubscriptionList"})
+	// the CRD of the subscriptions is not installed on the regional hub
+	client.PrependReactor("list", "subscriptions", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(subscriptionGVR.GroupResource(), "")
+	})
+
+	informers := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	informers.ForResource(policyGVR).Informer()
+	informers.ForResource(subscriptionGVR).Informer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	informers.Start(ctx.Done())
+
+	c := &Controller{name: "test"}
+	start := time.Now()
+	if unsynced := c.waitForCacheSync(ctx, informers, time.Second); !reflect.DeepEqual(unsynced, []schema.GroupVersionResource{subscriptionGVR}) {
+		t.Errorf("expected the subscriptions not to be synced, got %v", unsynced)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the missing resources not to block the syncer, waited %s", elapsed)
+	}
+	if !informers.ForResource(policyGVR).Informer().HasSynced() {
+		t.Error("expected the policies to be synced")
+	}
+}
@@ -0,0 +1,67 @@
+package syncer
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// SpecTopic carries the spec bundles from the global hub to the syncers
+	SpecTopic = "globalhub.spec"
+	// StatusTopic carries the status bundles from the syncers to the global hub
+	StatusTopic = "globalhub.status"
+	// SpecResyncTopic carries the requests of the syncers for all the spec, e.g. when they start
+	SpecResyncTopic = "globalhub.resync.spec"
+	// StatusResyncTopic carries the requests of the global hub for all the status, e.g. when it starts
+	StatusResyncTopic = "globalhub.resync.status"
+)
+
+// Transport carries the bundles between the global hub and the syncers, in place of the watches and the writes of
+// the syncers against the global hub apiserver.
+type Transport interface {
+	// Publish sends the bundle to the topic
+	Publish(topic string, bundle *Bundle) error
+	// Subscribe calls the handler with the bundles sent to the topic until the context is done, the bundles of a
+	// topic are handled one at a time
+	Subscribe(ctx context.Context, topic string, handler func(*Bundle)) error
+	// Connected returns true when the bundles can be sent
+	Connected() bool
+	Close()
+}
+
+// Bundle is a set of changes of the objects of one resource.
+type Bundle struct {
+	// Hub is the regional hub sending the status or requesting the spec
+	Hub      string `json:"hub,omitempty"`
+	Group    string `json:"group,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	// Full is set when the bundle has all the objects of the resource, the objects missing from it are deleted
+	Full    bool           `json:"full,omitempty"`
+	Objects []BundleObject `json:"objects,omitempty"`
+	// ID, Chunk and Chunks are set on the chunks of a bundle larger than the maximum payload of the transport, the
+	// chunks of a bundle share its ID and the bundle is only handled once all of them are received
+	ID     string `json:"id,omitempty"`
+	Chunk  int    `json:"chunk,omitempty"`
+	Chunks int    `json:"chunks,omitempty"`
+}
+
+// BundleObject is the change of an object, the object is nil when it is deleted.
+type BundleObject struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Deleted   bool   `json:"deleted,omitempty"`
+	// Manager is the field manager applying the object on the global hub
+	Manager string                     `json:"manager,omitempty"`
+	Object  *unstructured.Unstructured `json:"object,omitempty"`
+}
+
+func newBundle(hub string, gvr schema.GroupVersionResource) *Bundle {
+	return &Bundle{Hub: hub, Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource}
+}
+
+func (b *Bundle) GVR() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: b.Group, Version: b.Version, Resource: b.Resource}
+}
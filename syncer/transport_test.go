@@ -0,0 +1,253 @@
+package syncer_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	certutil "k8s.io/client-go/util/cert"
+
+	"github.com/clyang82/multicluster-global-hub-lite/syncer"
+)
+
+// startBroker starts an embedded broker with the users of the global hub and of the regional hub hub1, and returns
+// their configs.
+func startBroker(t *testing.T) (*natsserver.Server, map[string]*syncer.BrokerConfig) {
+	dir := t.TempDir()
+	certPEM, keyPEM, err := certutil.GenerateSelfSignedCertKey("127.0.0.1", []net.IP{net.ParseIP("127.0.0.1")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(caFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	configs := map[string]*syncer.BrokerConfig{}
+	users := []*natsserver.User{}
+	for _, user := range []string{"global-hub", "hub1"} {
+		passwordFile := filepath.Join(dir, user+".password")
+		if err := os.WriteFile(passwordFile, []byte(user+"-password"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, syncer.BrokerUser(user, user+"-password"))
+		configs[user] = &syncer.BrokerConfig{User: user, PasswordFile: passwordFile, CAFile: caFile}
+	}
+
+	broker, err := syncer.StartEmbeddedBroker("127.0.0.1", -1, &tls.Config{Certificates: []tls.Certificate{cert}}, users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, config := range configs {
+		config.URL = broker.ClientURL()
+	}
+	return broker, configs
+}
+
+func newPolicyBundle(hub string) *syncer.Bundle {
+	policy := &unstructured.Unstructured{}
+	policy.SetAPIVersion("policy.open-cluster-management.io/v1")
+	policy.SetKind("Policy")
+	policy.SetNamespace(hub)
+	policy.SetName("policy1")
+	_ = unstructured.SetNestedField(policy.Object, "Compliant", "status", "compliant")
+	return &syncer.Bundle{
+		Hub:      hub,
+		Group:    "policy.open-cluster-management.io",
+		Version:  "v1",
+		Resource: "policies",
+		Objects:  []syncer.BundleObject{{Namespace: hub, Name: "policy1", Object: policy}},
+	}
+}
+
+func TestNATSTransport(t *testing.T) {
+	broker, configs := startBroker(t)
+	defer broker.Shutdown()
+
+	globalHub, err := syncer.NewNATSTransport(configs["global-hub"], "global-hub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer globalHub.Close()
+	hub1, err := syncer.NewNATSTransport(configs["hub1"], "hub1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hub1.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan *syncer.Bundle, 2)
+	if err := globalHub.Subscribe(ctx, syncer.StatusTopic, func(bundle *syncer.Bundle) {
+		received <- bundle
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the broker denies the status of another regional hub
+	if err := hub1.Publish(syncer.StatusTopic, newPolicyBundle("hub2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := hub1.Publish(syncer.StatusTopic, newPolicyBundle("hub1")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case bundle := <-received:
+		if bundle.Hub != "hub1" || bundle.GVR().Resource != "policies" || len(bundle.Objects) != 1 {
+			t.Fatalf("unexpected bundle %+v", bundle)
+		}
+		compliant, _, _ := unstructured.NestedString(bundle.Objects[0].Object.Object, "status", "compliant")
+		if compliant != "Compliant" {
+			t.Errorf("expected the status of the policy, got %v", bundle.Objects[0].Object.Object)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the bundle is not received")
+	}
+	select {
+	case bundle := <-received:
+		t.Errorf("unexpected bundle of %s", bundle.Hub)
+	case <-time.After(time.Second):
+	}
+	if !hub1.Connected() {
+		t.Error("expected the transport to be connected")
+	}
+}
+
+func TestNATSTransportChunks(t *testing.T) {
+	broker, configs := startBroker(t)
+	defer broker.Shutdown()
+
+	globalHub, err := syncer.NewNATSTransport(configs["global-hub"], "global-hub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer globalHub.Close()
+	hub1, err := syncer.NewNATSTransport(configs["hub1"], "hub1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hub1.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan *syncer.Bundle, 2)
+	if err := hub1.Subscribe(ctx, syncer.SpecTopic, func(bundle *syncer.Bundle) {
+		received <- bundle
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// about 3MB, over the default maximum payload of 1MB of the broker
+	full := &syncer.Bundle{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies", Full: true}
+	for i := 0; i < 3000; i++ {
+		policy := &unstructured.Unstructured{}
+		policy.SetAPIVersion("policy.open-cluster-management.io/v1")
+		policy.SetKind("Policy")
+		policy.SetNamespace("default")
+		policy.SetName(fmt.Sprintf("policy%d", i))
+		policy.SetAnnotations(map[string]string{"description": strings.Repeat("x", 1000)})
+		full.Objects = append(full.Objects, syncer.BundleObject{Namespace: "default", Name: policy.GetName(), Object: policy})
+	}
+	if err := globalHub.Publish(syncer.SpecTopic, full); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case bundle := <-received:
+		if !bundle.Full || len(bundle.Objects) != 3000 || bundle.Chunks != 0 {
+			t.Fatalf("expected the full bundle of 3000 policies, got %d objects (full %v, chunks %d)",
+				len(bundle.Objects), bundle.Full, bundle.Chunks)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the bundle is not received")
+	}
+
+	// a single object over the maximum payload can't be sent
+	huge := &unstructured.Unstructured{}
+	huge.SetAPIVersion("policy.open-cluster-management.io/v1")
+	huge.SetKind("Policy")
+	huge.SetNamespace("default")
+	huge.SetName("huge")
+	huge.SetAnnotations(map[string]string{"description": strings.Repeat("x", 2*1024*1024)})
+	err = globalHub.Publish(syncer.SpecTopic, &syncer.Bundle{Group: "policy.open-cluster-management.io", Version: "v1",
+		Resource: "policies", Objects: []syncer.BundleObject{{Namespace: "default", Name: "huge", Object: huge}}})
+	if !errors.Is(err, nats.ErrMaxPayload) {
+		t.Errorf("expected %v, got %v", nats.ErrMaxPayload, err)
+	}
+}
+
+func TestNATSTransportRequiresTLSAndCredentials(t *testing.T) {
+	broker, configs := startBroker(t)
+	defer broker.Shutdown()
+
+	for name, config := range map[string]*syncer.BrokerConfig{
+		"no credentials": {URL: broker.ClientURL(), CAFile: configs["hub1"].CAFile},
+		"no CA":          {URL: broker.ClientURL(), User: "hub1", PasswordFile: configs["hub1"].PasswordFile},
+	} {
+		if _, err := syncer.NewNATSTransport(config, "hub1"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestApplyBundleObject(t *testing.T) {
+	policyGVR := schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}
+	hubControlPlaneGVR := schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1alpha1", Resource: "hubcontrolplanes"}
+	managedClusterGVR := schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}
+	clusterRoleBindingGVR := schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"}
+
+	object := func(namespace, name string, labels map[string]string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		obj.SetLabels(labels)
+		return obj
+	}
+	cluster2 := object("", "cluster2", map[string]string{syncer.RegionalHubLabel: "hub2"})
+	cluster2.SetAPIVersion("cluster.open-cluster-management.io/v1")
+	cluster2.SetKind("ManagedCluster")
+	client := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{managedClusterGVR: "ManagedClusterList"}, cluster2)
+
+	for _, test := range []struct {
+		name      string
+		gvr       schema.GroupVersionResource
+		bundleObj syncer.BundleObject
+		rejected  bool
+	}{
+		{"policy of the hub", policyGVR, syncer.BundleObject{Namespace: "hub1", Name: "policy1", Object: object("hub1", "policy1", nil)}, false},
+		{"policy of another namespace", policyGVR, syncer.BundleObject{Namespace: "default", Name: "policy1", Object: object("default", "policy1", nil)}, false},
+		{"unknown field manager", policyGVR, syncer.BundleObject{Namespace: "hub1", Name: "policy1", Manager: "kubectl", Object: object("hub1", "policy1", nil)}, true},
+		{"renamed object", policyGVR, syncer.BundleObject{Namespace: "hub1", Name: "policy1", Object: object("hub1", "policy2", nil)}, true},
+		{"hubcontrolplane of the hub", hubControlPlaneGVR, syncer.BundleObject{Name: "hub1", Object: object("", "hub1", nil)}, false},
+		{"hubcontrolplane of another hub", hubControlPlaneGVR, syncer.BundleObject{Name: "hub2", Object: object("", "hub2", nil)}, true},
+		{"managedcluster of the hub", managedClusterGVR, syncer.BundleObject{Name: "cluster1", Object: object("", "cluster1", nil)}, false},
+		{"managedcluster of another hub", managedClusterGVR, syncer.BundleObject{Name: "cluster2", Object: object("", "cluster2", nil)}, true},
+		{"namespaced managedcluster", managedClusterGVR, syncer.BundleObject{Namespace: "hub1", Name: "cluster1", Object: object("hub1", "cluster1", nil)}, true},
+		{"clusterrolebinding", clusterRoleBindingGVR, syncer.BundleObject{Name: "admin", Object: object("", "admin", nil)}, true},
+	} {
+		err := syncer.ApplyBundleObject(context.TODO(), client, "hub1", test.gvr, test.bundleObj)
+		// the fake client does not support server-side apply, the admitted objects fail to be applied
+		if rejected := err != nil && strings.Contains(err.Error(), "rejected"); rejected != test.rejected {
+			t.Errorf("%s: expected rejected %v, got %v", test.name, test.rejected, err)
+		}
+	}
+}
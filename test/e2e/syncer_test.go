@@ -0,0 +1,443 @@
+package e2e_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/clyang82/multicluster-global-hub-lite/test/framework"
+)
+
+var env *framework.Environment
+
+func TestMain(m *testing.M) {
+	var err error
+	env, err = framework.Start("hub1", "hub2")
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	if err := env.Stop(); err != nil {
+		panic(err)
+	}
+	os.Exit(code)
+}
+
+func TestPolicyPropagation(t *testing.T) {
+	ctx := context.TODO()
+	if err := env.GlobalHub.CreateNamespace(ctx, "propagation"); err != nil {
+		t.Fatal(err)
+	}
+	for _, hub := range env.RegionalHubs {
+		if err := hub.CreateNamespace(ctx, "propagation"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := env.GlobalHub.CreatePolicy(ctx, "propagation", "propagation-policy"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.WaitForPropagation(framework.PolicyGVR, "propagation", "propagation-policy"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestComplianceRollUp(t *testing.T) {
+	ctx := context.TODO()
+	if err := env.GlobalHub.CreateNamespace(ctx, "rollup"); err != nil {
+		t.Fatal(err)
+	}
+	for _, hub := range env.RegionalHubs {
+		if err := hub.CreateNamespace(ctx, "rollup"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := env.GlobalHub.CreatePolicy(ctx, "rollup", "rollup-policy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.WaitForPropagation(framework.PolicyGVR, "rollup", "rollup-policy"); err != nil {
+		t.Fatal(err)
+	}
+
+	// every regional hub reports one compliant and one non compliant cluster
+	for _, hub := range env.RegionalHubs {
+		if err := hub.SetPolicyCompliance(ctx, "rollup", "rollup-policy", map[string]string{
+			hub.Name + "-cluster1": "Compliant",
+			hub.Name + "-cluster2": "NonCompliant",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	regionalHubs := int64(len(env.RegionalHubs))
+	if err := env.WaitForComplianceSummary("rollup", "rollup-policy", regionalHubs, regionalHubs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPolicyDeletion(t *testing.T) {
+	ctx := context.TODO()
+	if err := env.GlobalHub.CreateNamespace(ctx, "deletion"); err != nil {
+		t.Fatal(err)
+	}
+	for _, hub := range env.RegionalHubs {
+		if err := hub.CreateNamespace(ctx, "deletion"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := env.GlobalHub.CreatePolicy(ctx, "deletion", "deletion-policy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.WaitForPropagation(framework.PolicyGVR, "deletion", "deletion-policy"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.GlobalHub.Client.Resource(framework.PolicyGVR).Namespace("deletion").
+		Delete(ctx, "deletion-policy", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.WaitForDeletion(framework.PolicyGVR, "deletion", "deletion-policy"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHubControlPlane(t *testing.T) {
+	ctx := context.TODO()
+	hub := env.RegionalHubs[0]
+	cluster := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": framework.ManagedClusterGVR.GroupVersion().String(),
+		"kind":       "ManagedCluster",
+		"metadata": map[string]interface{}{
+			"name": "cluster1",
+		},
+		"spec": map[string]interface{}{
+			"hubAcceptsClient": true,
+		},
+	}}
+	if _, err := hub.Client.Resource(framework.ManagedClusterGVR).Create(ctx, cluster, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.GlobalHub.WaitFor(framework.HubControlPlaneGVR, "", hub.Name, nil); err != nil {
+		t.Fatal(fmt.Errorf("the regional hub is not reported: %v", err))
+	}
+}
+
+func TestSubscriptionStatusRollUp(t *testing.T) {
+	ctx := context.TODO()
+	if err := env.GlobalHub.CreateNamespace(ctx, "subscription"); err != nil {
+		t.Fatal(err)
+	}
+	for _, hub := range env.RegionalHubs {
+		if err := hub.CreateNamespace(ctx, "subscription"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	subscription := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": framework.SubscriptionGVR.GroupVersion().String(),
+		"kind":       "Subscription",
+		"metadata": map[string]interface{}{
+			"name": "nginx-subscription",
+		},
+		"spec": map[string]interface{}{
+			"channel": "subscription/nginx-channel",
+		},
+	}}
+	if _, err := env.GlobalHub.Client.Resource(framework.SubscriptionGVR).Namespace("subscription").
+		Create(ctx, subscription, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.WaitForPropagation(framework.SubscriptionGVR, "subscription", "nginx-subscription"); err != nil {
+		t.Fatal(err)
+	}
+
+	// every regional hub reports the subscription propagated to one cluster
+	for _, hub := range env.RegionalHubs {
+		hubSubscription, err := hub.Client.Resource(framework.SubscriptionGVR).Namespace("subscription").
+			Get(ctx, "nginx-subscription", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hubSubscription.Object["status"] = map[string]interface{}{
+			"phase": "Propagated",
+			"statuses": map[string]interface{}{
+				hub.Name + "-cluster1": map[string]interface{}{
+					"packages": map[string]interface{}{
+						"nginx": map[string]interface{}{"phase": "Deployed", "lastUpdateTime": "2022-01-01T00:00:00Z"},
+					},
+				},
+			},
+		}
+		if _, err := hub.Client.Resource(framework.SubscriptionGVR).Namespace("subscription").
+			UpdateStatus(ctx, hubSubscription, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	regionalHubs := int64(len(env.RegionalHubs))
+	if _, err := env.GlobalHub.WaitFor(framework.SubscriptionGVR, "subscription", "nginx-subscription",
+		func(obj *unstructured.Unstructured) bool {
+			deployed, _, _ := unstructured.NestedInt64(obj.Object, "status", "aggregations", "packages", "total", "Deployed")
+			return deployed == regionalHubs
+		}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlacementDecisionRollUp(t *testing.T) {
+	ctx := context.TODO()
+	if err := env.GlobalHub.CreateNamespace(ctx, "placement"); err != nil {
+		t.Fatal(err)
+	}
+	for _, hub := range env.RegionalHubs {
+		if err := hub.CreateNamespace(ctx, "placement"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	placement := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": framework.PlacementGVR.GroupVersion().String(),
+		"kind":       "Placement",
+		"metadata": map[string]interface{}{
+			"name": "prod-placement",
+		},
+		"spec": map[string]interface{}{},
+	}}
+	if _, err := env.GlobalHub.Client.Resource(framework.PlacementGVR).Namespace("placement").
+		Create(ctx, placement, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.WaitForPropagation(framework.PlacementGVR, "placement", "prod-placement"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the placement controller of every regional hub selects one cluster
+	for _, hub := range env.RegionalHubs {
+		decision := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": framework.PlacementDecisionGVR.GroupVersion().String(),
+			"kind":       "PlacementDecision",
+			"metadata": map[string]interface{}{
+				"name":   "prod-placement-decision-1",
+				"labels": map[string]interface{}{"cluster.open-cluster-management.io/placement": "prod-placement"},
+			},
+		}}
+		decision, err := hub.Client.Resource(framework.PlacementDecisionGVR).Namespace("placement").
+			Create(ctx, decision, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		decision.Object["status"] = map[string]interface{}{
+			"decisions": []interface{}{
+				map[string]interface{}{"clusterName": hub.Name + "-cluster1", "reason": ""},
+			},
+		}
+		if _, err := hub.Client.Resource(framework.PlacementDecisionGVR).Namespace("placement").
+			UpdateStatus(ctx, decision, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	regionalHubs := int64(len(env.RegionalHubs))
+	if _, err := env.GlobalHub.WaitFor(framework.PlacementGVR, "placement", "prod-placement",
+		func(obj *unstructured.Unstructured) bool {
+			selected, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberOfSelectedClusters")
+			return selected == regionalHubs
+		}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGlobalManagedClusterSet(t *testing.T) {
+	ctx := context.TODO()
+	// the managedclusters of every regional hub are reported to the global hub under the same name
+	for _, hub := range env.RegionalHubs {
+		for name, envLabel := range map[string]string{hub.Name + "-prod": "prod", hub.Name + "-dev": "dev"} {
+			cluster := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": framework.ManagedClusterGVR.GroupVersion().String(),
+				"kind":       "ManagedCluster",
+				"metadata": map[string]interface{}{
+					"name":   name,
+					"labels": map[string]interface{}{"env": envLabel},
+				},
+				"spec": map[string]interface{}{
+					"hubAcceptsClient": true,
+				},
+			}}
+			if _, err := hub.Client.Resource(framework.ManagedClusterGVR).Create(ctx, cluster, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	set := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": framework.GlobalManagedClusterSetGVR.GroupVersion().String(),
+		"kind":       "GlobalManagedClusterSet",
+		"metadata": map[string]interface{}{
+			"name": "prod",
+		},
+		"spec": map[string]interface{}{
+			"clusterSelector":   map[string]interface{}{"matchLabels": map[string]interface{}{"env": "prod"}},
+			"bindingNamespaces": []interface{}{"clusterset"},
+		},
+	}}
+	if _, err := env.GlobalHub.Client.Resource(framework.GlobalManagedClusterSetGVR).Create(ctx, set, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hub := range env.RegionalHubs {
+		if _, err := hub.WaitFor(framework.ManagedClusterSetGVR, "", "prod", nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := hub.WaitFor(framework.ManagedClusterGVR, "", hub.Name+"-prod", func(obj *unstructured.Unstructured) bool {
+			return obj.GetLabels()["cluster.open-cluster-management.io/clusterset"] == "prod"
+		}); err != nil {
+			t.Fatal(err)
+		}
+		cluster, err := hub.Client.Resource(framework.ManagedClusterGVR).Get(ctx, hub.Name+"-dev", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cluster.GetLabels()["cluster.open-cluster-management.io/clusterset"]; ok {
+			t.Errorf("the cluster %s is not expected in a clusterset", cluster.GetName())
+		}
+	}
+}
+
+func TestDriftRevert(t *testing.T) {
+	ctx := context.TODO()
+	if err := env.GlobalHub.CreateNamespace(ctx, "drift"); err != nil {
+		t.Fatal(err)
+	}
+	for _, hub := range env.RegionalHubs {
+		if err := hub.CreateNamespace(ctx, "drift"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := env.GlobalHub.CreatePolicy(ctx, "drift", "drift-policy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.WaitForPropagation(framework.PolicyGVR, "drift", "drift-policy"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the change on the regional hub is reverted
+	hub := env.RegionalHubs[0]
+	policy, err := hub.Client.Resource(framework.PolicyGVR).Namespace("drift").Get(ctx, "drift-policy", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unstructured.SetNestedField(policy.Object, "enforce", "spec", "remediationAction"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hub.Client.Resource(framework.PolicyGVR).Namespace("drift").Update(ctx, policy, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hub.WaitFor(framework.PolicyGVR, "drift", "drift-policy", func(obj *unstructured.Unstructured) bool {
+		action, _, _ := unstructured.NestedString(obj.Object, "spec", "remediationAction")
+		return action == "inform"
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the deletion on the regional hub is reverted
+	if err := hub.Client.Resource(framework.PolicyGVR).Namespace("drift").Delete(ctx, "drift-policy", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hub.WaitFor(framework.PolicyGVR, "drift", "drift-policy", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.GlobalHub.WaitFor(framework.HubControlPlaneGVR, "", hub.Name, func(obj *unstructured.Unstructured) bool {
+		detected, _, _ := unstructured.NestedInt64(obj.Object, "status", "drift", "detected")
+		return detected >= 2
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrphanCollection(t *testing.T) {
+	ctx := context.TODO()
+	if err := env.GlobalHub.CreateNamespace(ctx, "orphan"); err != nil {
+		t.Fatal(err)
+	}
+	for _, hub := range env.RegionalHubs {
+		if err := hub.CreateNamespace(ctx, "orphan"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := env.GlobalHub.CreatePolicy(ctx, "orphan", "orphan-policy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.WaitForPropagation(framework.PolicyGVR, "orphan", "orphan-policy"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the policy deleted while the syncer is down is deleted from the regional hub once the syncer is started
+	hub := env.RegionalHubs[0]
+	env.StopSyncer(hub)
+	if err := env.GlobalHub.Client.Resource(framework.PolicyGVR).Namespace("orphan").
+		Delete(ctx, "orphan-policy", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.StartSyncer(hub); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.WaitForDeletion(framework.PolicyGVR, "orphan", "orphan-policy"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPropagationEvents(t *testing.T) {
+	ctx := context.TODO()
+	if err := env.GlobalHub.CreateNamespace(ctx, "events"); err != nil {
+		t.Fatal(err)
+	}
+	for _, hub := range env.RegionalHubs {
+		if err := hub.CreateNamespace(ctx, "events"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := env.GlobalHub.CreatePolicy(ctx, "events", "events-policy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.WaitForPropagation(framework.PolicyGVR, "events", "events-policy"); err != nil {
+		t.Fatal(err)
+	}
+	hub := env.RegionalHubs[0]
+	if err := hub.SetPolicyCompliance(ctx, "events", "events-policy", map[string]string{
+		hub.Name + "-cluster1": "NonCompliant",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the syncers record the propagation to their regional hub and the global hub records the status aggregation
+	if err := wait.PollImmediate(framework.Interval, framework.Timeout, func() (bool, error) {
+		events, err := env.GlobalHub.Client.Resource(framework.EventGVR).Namespace("events").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+		reasons := map[string]bool{}
+		for _, event := range events.Items {
+			name, _, _ := unstructured.NestedString(event.Object, "involvedObject", "name")
+			reason, _, _ := unstructured.NestedString(event.Object, "reason")
+			host, _, _ := unstructured.NestedString(event.Object, "source", "host")
+			if name == "events-policy" {
+				reasons[reason+"/"+host] = true
+			}
+		}
+		for _, regionalHub := range env.RegionalHubs {
+			if !reasons["Propagated/"+regionalHub.Name] {
+				return false, nil
+			}
+		}
+		return reasons["StatusAggregated/"], nil
+	}); err != nil {
+		t.Fatal(fmt.Errorf("the propagation events are not recorded: %v", err))
+	}
+}
@@ -0,0 +1,296 @@
+// Package framework runs a global hub and regional hubs on envtest apiservers, with a syncer between the global hub
+// and every regional hub, and waits for the objects to be propagated to the regional hubs and their status to be
+// rolled up to the global hub.
+package framework
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	"github.com/clyang82/multicluster-global-hub-lite/server/controllers/globalhubcontroller"
+	"github.com/clyang82/multicluster-global-hub-lite/syncer"
+)
+
+const (
+	// Timeout bounds the waits for the propagation, the status roll-up and the deletion.
+	Timeout = 30 * time.Second
+	// Interval is the polling interval of the waits.
+	Interval = 200 * time.Millisecond
+
+	globalHubName    = "global-hub"
+	numSyncerThreads = 1
+)
+
+var (
+	PolicyGVR                  = schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "policies"}
+	PlacementBindingGVR        = schema.GroupVersionResource{Group: "policy.open-cluster-management.io", Version: "v1", Resource: "placementbindings"}
+	PlacementRuleGVR           = schema.GroupVersionResource{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "placementrules"}
+	ManagedClusterGVR          = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}
+	HubControlPlaneGVR         = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1alpha1", Resource: "hubcontrolplanes"}
+	SubscriptionGVR            = schema.GroupVersionResource{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "subscriptions"}
+	ChannelGVR                 = schema.GroupVersionResource{Group: "apps.open-cluster-management.io", Version: "v1", Resource: "channels"}
+	ApplicationGVR             = schema.GroupVersionResource{Group: "app.k8s.io", Version: "v1beta1", Resource: "applications"}
+	PlacementGVR               = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placements"}
+	PlacementDecisionGVR       = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placementdecisions"}
+	ManagedClusterSetGVR       = schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "managedclustersets"}
+	GlobalManagedClusterSetGVR = schema.GroupVersionResource{Group: "global-hub.open-cluster-management.io", Version: "v1alpha1", Resource: "globalmanagedclustersets"}
+	EventGVR                   = schema.GroupVersionResource{Version: "v1", Resource: "events"}
+)
+
+// Hub is a global or regional hub served by an envtest apiserver.
+type Hub struct {
+	Name   string
+	Config *rest.Config
+	Client dynamic.Interface
+
+	env        *envtest.Environment
+	stopSyncer context.CancelFunc
+}
+
+// Environment is a global hub running the global hub controllers and the regional hubs syncing with it.
+type Environment struct {
+	GlobalHub    *Hub
+	RegionalHubs []*Hub
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Start starts the global hub and one regional hub per name, then a syncer between the global hub and each regional
+// hub. The syncer of a regional hub reports its status to the global hub namespace named after the regional hub.
+func Start(regionalHubNames ...string) (*Environment, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &Environment{ctx: ctx, cancel: cancel}
+
+	crdPaths, err := crdPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	if e.GlobalHub, err = startHub(globalHubName, crdPaths); err != nil {
+		return nil, e.stop(err)
+	}
+	globalhubcontroller.AddControllers(e.GlobalHub.Client, kubernetes.NewForConfigOrDie(e.GlobalHub.Config), nil, ctx.Done())
+
+	for _, name := range regionalHubNames {
+		hub, err := startHub(name, crdPaths)
+		if err != nil {
+			return nil, e.stop(err)
+		}
+		e.RegionalHubs = append(e.RegionalHubs, hub)
+
+		if err := e.GlobalHub.CreateNamespace(ctx, name); err != nil {
+			return nil, e.stop(err)
+		}
+		if err := e.StartSyncer(hub); err != nil {
+			return nil, e.stop(err)
+		}
+	}
+
+	return e, nil
+}
+
+// StartSyncer starts the syncer between the global hub and the regional hub.
+func (e *Environment) StartSyncer(hub *Hub) error {
+	ctx, cancel := context.WithCancel(e.ctx)
+	if err := syncer.StartSyncer(ctx, &syncer.SyncerConfig{
+		UpstreamConfig:   e.GlobalHub.Config,
+		DownstreamConfig: hub.Config,
+		SyncerName:       hub.Name,
+	}, numSyncerThreads); err != nil {
+		cancel()
+		return err
+	}
+	hub.stopSyncer = cancel
+	return nil
+}
+
+// StopSyncer stops the syncer of the regional hub, the changes on the global hub are not synced until it is started
+// again.
+func (e *Environment) StopSyncer(hub *Hub) {
+	if hub.stopSyncer != nil {
+		hub.stopSyncer()
+		hub.stopSyncer = nil
+	}
+}
+
+// Stop stops the syncers, the controllers and the apiservers.
+func (e *Environment) Stop() error {
+	return e.stop(nil)
+}
+
+func (e *Environment) stop(err error) error {
+	errs := []error{err}
+	e.cancel()
+	for _, hub := range append(e.RegionalHubs, e.GlobalHub) {
+		if hub != nil {
+			errs = append(errs, hub.env.Stop())
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// WaitForPropagation waits for the object of the global hub to be created on every regional hub.
+func (e *Environment) WaitForPropagation(gvr schema.GroupVersionResource, namespace, name string) error {
+	for _, hub := range e.RegionalHubs {
+		if _, err := hub.WaitFor(gvr, namespace, name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WaitForDeletion waits for the object to be deleted from every regional hub.
+func (e *Environment) WaitForDeletion(gvr schema.GroupVersionResource, namespace, name string) error {
+	for _, hub := range e.RegionalHubs {
+		if err := hub.WaitForDeletion(gvr, namespace, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WaitForComplianceSummary waits for the compliance of the regional hubs to be rolled up to the policy of the
+// global hub.
+func (e *Environment) WaitForComplianceSummary(namespace, name string, compliant, nonCompliant int64) error {
+	_, err := e.GlobalHub.WaitFor(PolicyGVR, namespace, name, func(obj *unstructured.Unstructured) bool {
+		c, _, _ := unstructured.NestedInt64(obj.Object, "status", "complianceSummary", "compliant")
+		nc, _, _ := unstructured.NestedInt64(obj.Object, "status", "complianceSummary", "noncompliant")
+		return c == compliant && nc == nonCompliant
+	})
+	return err
+}
+
+// CreateNamespace creates the namespace if it doesn't exist.
+func (h *Hub) CreateNamespace(ctx context.Context, name string) error {
+	client, err := kubernetes.NewForConfig(h.Config)
+	if err != nil {
+		return err
+	}
+	_, err = client.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// CreatePolicy creates an inform policy without templates. The syncers report the policies of a regional hub by name
+// to the namespace of the regional hub on the global hub, so the names must be unique across the namespaces.
+func (h *Hub) CreatePolicy(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	policy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": PolicyGVR.GroupVersion().String(),
+		"kind":       "Policy",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"spec": map[string]interface{}{
+			"disabled":          false,
+			"remediationAction": "inform",
+			"policy-templates":  []interface{}{},
+		},
+	}}
+	return h.Client.Resource(PolicyGVR).Namespace(namespace).Create(ctx, policy, metav1.CreateOptions{})
+}
+
+// SetPolicyCompliance sets the compliance state of the clusters in the status of the policy, like the policy
+// framework of the regional hub does.
+func (h *Hub) SetPolicyCompliance(ctx context.Context, namespace, name string, clusters map[string]string) error {
+	policy, err := h.Client.Resource(PolicyGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	status := []interface{}{}
+	for cluster, compliance := range clusters {
+		status = append(status, map[string]interface{}{
+			"clustername":      cluster,
+			"clusternamespace": cluster,
+			"compliant":        compliance,
+		})
+	}
+	if err := unstructured.SetNestedSlice(policy.Object, status, "status", "status"); err != nil {
+		return err
+	}
+	_, err = h.Client.Resource(PolicyGVR).Namespace(namespace).UpdateStatus(ctx, policy, metav1.UpdateOptions{})
+	return err
+}
+
+// WaitFor waits for the object to exist and to satisfy the condition, a nil condition only waits for the object.
+func (h *Hub) WaitFor(gvr schema.GroupVersionResource, namespace, name string,
+	condition func(*unstructured.Unstructured) bool) (*unstructured.Unstructured, error) {
+	var obj *unstructured.Unstructured
+	err := wait.PollImmediate(Interval, Timeout, func() (bool, error) {
+		var err error
+		obj, err = h.Client.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return condition == nil || condition(obj), nil
+	})
+	if err != nil {
+		return obj, fmt.Errorf("%s %s/%s on %s: %v", gvr.Resource, namespace, name, h.Name, err)
+	}
+	return obj, nil
+}
+
+// WaitForDeletion waits for the object to be deleted.
+func (h *Hub) WaitForDeletion(gvr schema.GroupVersionResource, namespace, name string) error {
+	err := wait.PollImmediate(Interval, Timeout, func() (bool, error) {
+		_, err := h.Client.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return fmt.Errorf("%s %s/%s is still on %s: %v", gvr.Resource, namespace, name, h.Name, err)
+	}
+	return nil
+}
+
+func startHub(name string, crdPaths []string) (*Hub, error) {
+	env := &envtest.Environment{
+		CRDDirectoryPaths:     crdPaths,
+		ErrorIfCRDPathMissing: true,
+	}
+	config, err := env.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the %s apiserver: %v", name, err)
+	}
+	hub := &Hub{Name: name, Config: config, env: env}
+	if hub.Client, err = dynamic.NewForConfig(config); err != nil {
+		return nil, utilerrors.NewAggregate([]error{err, env.Stop()})
+	}
+	return hub, nil
+}
+
+// crdPaths returns the OCM CRDs installed by the global hub apiserver and the regional hub CRDs of the dev mode.
+func crdPaths() ([]string, error) {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return nil, fmt.Errorf("failed to locate the repository")
+	}
+	root := filepath.Join(filepath.Dir(file), "..", "..")
+	return []string{
+		filepath.Join(root, "server", "controllers", "globalhubcontroller", "manifests"),
+		filepath.Join(root, "cmd", "server", "cmd", "manifests"),
+	}, nil
+}